@@ -0,0 +1,6 @@
+package api
+
+// The gRPC code needs protoc with the protoc-gen-go and protoc-gen-go-grpc
+// plugins on PATH.
+//
+//go:generate protoc -I . --go_out=../pkg/eventpb --go_opt=paths=source_relative --go-grpc_out=../pkg/eventpb --go-grpc_opt=paths=source_relative EventService.proto
@@ -0,0 +1,9 @@
+// Package api holds the contracts of the calendar service.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the HTTP API.
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -2,27 +2,48 @@ package main
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// Time zones of calendars are validated on hosts without tzdata too.
+	_ "time/tzdata"
 
 	//nolint:depguard
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/blob"
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/digest"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/lifecycle"
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/outbox"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/queue"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/sender"
+	internalgrpc "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/server/http"
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	cachestorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/cache"
 	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/sql"
+	tracingstorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/tracing"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/webhook"
 )
 
-var configFile string
+var (
+	configFile   string
+	configLoader = config.NewLoader()
+)
 
 func init() {
-	flag.StringVar(&configFile, "config", "./config.yaml", "Path to configuration file")
+	flag.StringVar(&configFile, "config", config.DefaultFile, "Path to configuration file")
+	configLoader.RegisterFlags(flag.CommandLine)
 }
 
 func main() {
@@ -33,55 +54,228 @@ func main() {
 		return
 	}
 
-	config, err := config.LoadConfig(configFile)
+	conf, err := configLoader.Load(configFile)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 
-	logg, err := logger.NewLogger(config.Components.Logging.FilePath,
-		config.Components.Logging.Level,
-		logger.LogFormat(config.Components.Logging.Type))
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(conf.Components.Storage, flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	logg, err := logger.NewLogger(conf.Components.Logging.FilePath,
+		conf.Components.Logging.Level,
+		logger.LogFormat(conf.Components.Logging.Type))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't initialize logger: %s", err.Error())
 		os.Exit(1)
 	}
 
-	var storage storage.Storage
-	if config.Components.Storage.Type == "memory" {
-		storage = memorystorage.New()
-	} else if config.Components.Storage.Type == "postgres" {
-		storage, err = sqlstorage.New(config.Components.Storage)
-		if err != nil {
-			logg.Error(err.Error())
-			os.Exit(1)
+	if flag.Arg(0) == "export" || flag.Arg(0) == "import" {
+		if err = runSnapshot(conf.Components.Storage, logg, flag.Arg(0), flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
 		}
+	} else if err = serve(conf, logg); err != nil {
+		logg.Error("calendar stopped with errors: " + err.Error())
 	}
+	if closeErr := logg.Close(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't close logger: %s\n", closeErr.Error())
+		err = errors.Join(err, closeErr)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
 
-	calendar := app.New(*logg, storage)
-
-	server := internalhttp.NewServer(logg, *calendar, config.Components.Server)
-
+// serve runs the calendar until SIGINT or SIGTERM and returns the errors
+// of every component that failed to start, run or stop.
+func serve(conf *config.Config, logg *logger.Logger) error {
+	// A signal also stops waiting for the database to come up.
 	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	go func() {
-		<-ctx.Done()
+	stopTracing, err := tracing.Setup(context.Background(), conf.Components.Tracing)
+	if err != nil {
+		return err
+	}
+
+	blobs, err := blob.NewLocalStore(conf.Components.Attachments.Dir)
+	if err != nil {
+		return errors.Join(err, stopTracing(context.Background()))
+	}
+
+	store, err := newStorage(ctx, conf.Components.Storage, logg)
+	if err != nil {
+		return errors.Join(err, stopTracing(context.Background()))
+	}
+
+	if conf.Components.Storage.Cache.Enabled {
+		cached := cachestorage.New(store, conf.Components.Storage.Cache)
+		expvar.Publish("storage_cache", expvar.Func(func() interface{} {
+			return cached.Stats()
+		}))
+		store = cached
+	}
+	store = tracingstorage.New(store)
+
+	calendar := app.New(logg, store)
+	calendar.UseBlobStore(blobs, conf.Components.Attachments.MaxSize)
+
+	server := internalhttp.NewServer(logg, *calendar, conf.Components.Server)
+	grpcServer, err := internalgrpc.NewServer(logg, *calendar, conf.Components.GRPC)
+	if err != nil {
+		return errors.Join(err, store.Close(), stopTracing(context.Background()))
+	}
+
+	shutdown := conf.Components.Shutdown
+	timeout := func(name string) time.Duration {
+		return time.Duration(shutdown.TimeoutOf(name)) * time.Second
+	}
+
+	components := lifecycle.New(logg)
+	// Tracing stops last to export the spans of everything stopped
+	// before it.
+	components.Add(lifecycle.Component{
+		Name:    "tracing",
+		Stop:    stopTracing,
+		Timeout: timeout("tracing"),
+	})
+	components.Add(lifecycle.Component{
+		Name: "storage",
+		Stop: func(context.Context) error {
+			return errors.Join(saveSnapshotFile(conf.Components.Storage, store), store.Close())
+		},
+		Timeout: timeout("storage"),
+	})
+
+	var notifier *scheduler.Scheduler
+	if eventOutbox, ok := storage.As[storage.Outbox](store); ok {
+		notifications := queue.NewMemoryQueue(0)
+		notificationSender := sender.New(logg, conf.Components.Sender.DedupSize)
+		notificationSender.AddChannel(storage.ChannelPush, calendar.Push)
+		if notificationStore, ok := storage.As[storage.NotificationStore](store); ok {
+			notificationSender.UseStore(notificationStore)
+		}
+		relay := outbox.NewRelay(logg, eventOutbox, notifications, conf.Components.Scheduler.BatchSize,
+			time.Duration(conf.Components.Scheduler.Lease)*time.Second)
+		notifications.Subscribe(storage.TopicNotification, relay.Acknowledge(notificationSender.Handle))
+		notifier = scheduler.New(logg, eventOutbox, relay,
+			time.Duration(conf.Components.Scheduler.Interval)*time.Second)
+
+		components.Add(lifecycle.Component{
+			Name:    "queue",
+			Run:     notifications.Run,
+			Timeout: timeout("queue"),
+		})
+		components.Add(lifecycle.Component{
+			Name: "scheduler",
+			Run: func(ctx context.Context) error {
+				notifier.Run(ctx)
+				return nil
+			},
+			Timeout: timeout("scheduler"),
+		})
+
+		if digests, ok := storage.As[storage.DigestOutbox](store); ok {
+			digestJob := digest.New(logg, store, digests,
+				time.Duration(conf.Components.Digest.Interval)*time.Second)
+			components.Add(lifecycle.Component{
+				Name: "digest",
+				Run: func(ctx context.Context) error {
+					digestJob.Run(ctx)
+					return nil
+				},
+				Timeout: timeout("digest"),
+			})
+		}
+	}
 
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-		defer cancel()
+	if webhooks, ok := storage.As[storage.WebhookStore](store); ok {
+		dispatcher := webhook.NewDispatcher(logg, webhooks, conf.Components.Webhooks)
+		calendar.OnChange(dispatcher.HandleChange)
+		components.Add(lifecycle.Component{
+			Name: "webhooks",
+			Run: func(ctx context.Context) error {
+				dispatcher.Run(ctx)
+				return nil
+			},
+			Timeout: timeout("webhooks"),
+		})
+	}
 
-		if err := server.Stop(ctx); err != nil {
-			logg.Error("failed to stop http server: " + err.Error())
+	reloader := config.NewReloader(configLoader, configFile, conf)
+	reloader.OnReload(func(cfg *config.Config) {
+		logg.SetLevel(cfg.Components.Logging.Level)
+		logg.SetFormat(logger.LogFormat(cfg.Components.Logging.Type))
+		server.ApplyConfig(cfg.Components.Server)
+		if notifier != nil {
+			notifier.SetInterval(time.Duration(cfg.Components.Scheduler.Interval) * time.Second)
 		}
-	}()
+		logg.Info("configuration reloaded")
+	})
+
+	reloadSignal := make(chan os.Signal, 1)
+	signal.Notify(reloadSignal, syscall.SIGHUP)
+	defer signal.Stop(reloadSignal)
+	components.Add(lifecycle.Component{
+		Name: "reloader",
+		Run: func(ctx context.Context) error {
+			reloader.Watch(ctx, reloadSignal, func(err error) {
+				logg.Error("failed to reload configuration: " + err.Error())
+			})
+			return nil
+		},
+		Timeout: timeout("reloader"),
+	})
+
+	var listener net.Listener
+	components.Add(lifecycle.Component{
+		Name: "http",
+		Start: func(context.Context) (err error) {
+			listener, err = server.Listen()
+			return err
+		},
+		Run: func(ctx context.Context) error {
+			return server.Serve(ctx, listener)
+		},
+		Stop:    server.Stop,
+		Timeout: timeout("http"),
+	})
+
+	var grpcListener net.Listener
+	components.Add(lifecycle.Component{
+		Name: "grpc",
+		Start: func(context.Context) (err error) {
+			grpcListener, err = grpcServer.Listen()
+			return err
+		},
+		Run: func(ctx context.Context) error {
+			return grpcServer.Serve(ctx, grpcListener)
+		},
+		Stop:    grpcServer.Stop,
+		Timeout: timeout("grpc"),
+	})
 
 	logg.Info("calendar is running...")
+	return components.Run(ctx)
+}
+
+// newStorage opens the storage configured by conf. A memory storage is
+// loaded from its snapshot file if it has one.
+func newStorage(ctx context.Context, conf config.StorageConfig, logg *logger.Logger) (storage.Storage, error) {
+	if conf.Type == "postgres" {
+		return sqlstorage.New(ctx, conf, logg)
+	}
 
-	if err := server.Start(ctx); err != nil {
-		logg.Error("failed to start http server: " + err.Error())
-		cancel()
-		os.Exit(1) //nolint:gocritic
+	store := memorystorage.New().(*memorystorage.MemoryStorage)
+	if err := loadSnapshotFile(ctx, conf, store); err != nil {
+		return nil, fmt.Errorf("load snapshot file: %w", err)
 	}
+	return store, nil
 }
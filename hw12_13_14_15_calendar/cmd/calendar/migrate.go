@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	sqlstorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/sql"
+	migrator "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/migrations"
+)
+
+const migrateUsage = `usage: calendar [flags] migrate <command>
+
+commands:
+  up           apply all pending migrations
+  down [N]     roll back N migrations (default 1)
+  status       print the applied and available versions
+  goto N       migrate up or down to version N
+  force N      set version N without migrating and clear the dirty flag`
+
+var errMigrateUsage = errors.New(migrateUsage)
+
+func runMigrate(conf config.StorageConfig, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+	if conf.Type != "postgres" {
+		return fmt.Errorf("migrations require postgres storage, got %q", conf.Type)
+	}
+
+	mg, err := migrator.New(sqlstorage.DSN(conf))
+	if err != nil {
+		return err
+	}
+	defer mg.Close()
+
+	switch command, arg := args[0], args[1:]; command {
+	case "up":
+		err = mg.Up()
+	case "down":
+		steps := 1
+		if len(arg) > 0 {
+			if steps, err = strconv.Atoi(arg[0]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", arg[0])
+			}
+		}
+		err = mg.Steps(-steps)
+	case "goto":
+		if len(arg) == 0 {
+			return errMigrateUsage
+		}
+		version, parseErr := strconv.ParseUint(arg[0], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", arg[0])
+		}
+		err = mg.Goto(uint(version))
+	case "force":
+		if len(arg) == 0 {
+			return errMigrateUsage
+		}
+		version, parseErr := strconv.Atoi(arg[0])
+		if parseErr != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", arg[0])
+		}
+		err = mg.Force(version)
+	case "status":
+	default:
+		return errMigrateUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	return printMigrationStatus(mg)
+}
+
+func printMigrationStatus(mg *migrator.Migrator) error {
+	version, dirty, err := mg.Version()
+	if err != nil {
+		return err
+	}
+	available, err := mg.Available()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, v := range available {
+		if v > version {
+			pending++
+		}
+	}
+
+	fmt.Printf("version: %d\ndirty: %t\nlatest: %d\npending: %d\n",
+		version, dirty, available[len(available)-1], pending)
+	return nil
+}
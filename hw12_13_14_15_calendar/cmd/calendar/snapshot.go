@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/snapshot"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+)
+
+const snapshotUsage = `usage: calendar [flags] export <file>
+       calendar [flags] import [-remap] [-batch N] <file>
+
+export writes every calendar and event of the configured storage to a
+gzip-compressed JSON lines archive; "-" writes to stdout.
+import restores an archive into the configured storage, keeping the IDs
+of its records unless -remap is given; "-" reads from stdin. A memory
+storage keeps the restored records in its storage.snapshot_file.`
+
+var errSnapshotUsage = errors.New(snapshotUsage)
+
+// gzipMagic starts snapshot archives. Snapshot files of memory storages
+// were archives before they held the whole storage.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func runSnapshot(conf config.StorageConfig, logg *logger.Logger, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	remap := flags.Bool("remap", false, "give restored records new IDs")
+	batch := flags.Int("batch", 0, "records per batch")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || (command == "export" && *remap) {
+		return errSnapshotUsage
+	}
+	if command == "import" && conf.Type == "memory" && conf.SnapshotFile == "" {
+		// The records would be lost on exit.
+		return errors.New("import into a memory storage requires storage.snapshot_file")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	store, err := newStorage(ctx, conf, logg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	start := time.Now()
+	opts := snapshot.Options{
+		BatchSize: *batch,
+		RemapIDs:  *remap,
+		Progress: func(stats snapshot.Stats) {
+			fmt.Fprintf(os.Stderr, "%sed %d calendars, %d events in %s\n",
+				command, stats.Calendars, stats.Events, time.Since(start).Round(time.Millisecond))
+		},
+	}
+
+	path := flags.Arg(0)
+	if command == "export" {
+		if path == "-" {
+			_, err = snapshot.Export(ctx, store, os.Stdout, opts)
+			return err
+		}
+		return writeFile(path, func(w io.Writer) error {
+			_, err := snapshot.Export(ctx, store, w, opts)
+			return err
+		})
+	}
+
+	r := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if _, err := snapshot.Import(ctx, store, r, opts); err != nil {
+		return err
+	}
+	// A memory storage keeps the restored records in its snapshot file.
+	return saveSnapshotFile(conf, store)
+}
+
+// loadSnapshotFile restores everything a memory storage held from its
+// snapshot file if it exists. An archive written by export is imported
+// instead.
+func loadSnapshotFile(ctx context.Context, conf config.StorageConfig, store *memorystorage.MemoryStorage) error {
+	if conf.SnapshotFile == "" {
+		return nil
+	}
+
+	f, err := os.Open(conf.SnapshotFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if magic, err := r.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		_, err = snapshot.Import(ctx, store, r, snapshot.Options{})
+		return err
+	}
+	return store.Load(r)
+}
+
+// saveSnapshotFile writes everything a memory storage holds to its
+// snapshot file.
+func saveSnapshotFile(conf config.StorageConfig, store storage.Storage) error {
+	memory, ok := storage.As[*memorystorage.MemoryStorage](store)
+	if !ok || conf.SnapshotFile == "" {
+		return nil
+	}
+
+	return writeFile(conf.SnapshotFile, memory.Save)
+}
+
+// writeFile replaces the file at path with what write writes, so a failed
+// write leaves the previous file in place.
+func writeFile(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
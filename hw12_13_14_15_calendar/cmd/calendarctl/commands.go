@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/calendarclient"
+)
+
+// timeLayouts are accepted for times given on the command line, in the
+// local time zone unless the layout has one.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var errUsage = errors.New("usage")
+
+// eventClient is the API the commands call. *calendarclient.Client calls
+// it over HTTP and *grpcClient over gRPC.
+type eventClient interface {
+	CreateEvent(ctx context.Context, event calendarclient.Event) (*calendarclient.Event, error)
+	UpdateEvent(ctx context.Context, event calendarclient.Event) (*calendarclient.Event, error)
+	DeleteEvent(ctx context.Context, id int64) error
+	GetEvent(ctx context.Context, id int64) (*calendarclient.Event, error)
+	ListCalendarEvents(ctx context.Context,
+		from, to time.Time,
+		calendarIDs []int64,
+		tagIDs ...int64,
+	) ([]calendarclient.Event, error)
+}
+
+// command runs a subcommand with its arguments.
+type command struct {
+	client eventClient
+	out    io.Writer
+	format string
+	now    func() time.Time
+}
+
+func (c *command) run(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "create":
+		return c.create(ctx, args)
+	case "update":
+		return c.update(ctx, args)
+	case "delete":
+		return c.delete(ctx, args)
+	case "get":
+		return c.get(ctx, args)
+	case "list":
+		return c.list(ctx, args)
+	}
+	return fmt.Errorf("%w: unknown command %q", errUsage, name)
+}
+
+// eventFlags are the flags setting the fields of an event.
+type eventFlags struct {
+	fs          *flag.FlagSet
+	title       string
+	description string
+	start       string
+	end         string
+	notify      string
+	calendar    int64
+}
+
+func newEventFlags(name string) *eventFlags {
+	f := &eventFlags{fs: flag.NewFlagSet(name, flag.ContinueOnError)}
+	f.fs.StringVar(&f.title, "title", "", "title of the event")
+	f.fs.StringVar(&f.description, "description", "", "description of the event")
+	f.fs.StringVar(&f.start, "start", "", "start time, e.g. 2025-03-10T09:00")
+	f.fs.StringVar(&f.end, "end", "", "end time; one hour after the start by default")
+	f.fs.StringVar(&f.notify, "notify", "", `reminder time, or "none"`)
+	f.fs.Int64Var(&f.calendar, "calendar", 0, "calendar ID of the event, or 0 for none")
+	return f
+}
+
+// apply sets the fields of event whose flags were given.
+func (f *eventFlags) apply(event *calendarclient.Event) error {
+	var err error
+	f.fs.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch fl.Name {
+		case "title":
+			event.Title = f.title
+		case "description":
+			event.Description = f.description
+		case "start":
+			event.StartTime, err = parseTime(f.start)
+		case "end":
+			event.EndTime, err = parseTime(f.end)
+		case "notify":
+			if f.notify == "none" {
+				event.NotifyAt = time.Time{}
+				return
+			}
+			event.NotifyAt, err = parseTime(f.notify)
+		case "calendar":
+			event.CalendarID = f.calendar
+		}
+		if err != nil {
+			err = fmt.Errorf("-%s: %w", fl.Name, err)
+		}
+	})
+	return err
+}
+
+func (c *command) create(ctx context.Context, args []string) error {
+	f := newEventFlags("create")
+	if err := f.fs.Parse(args); err != nil {
+		return err
+	}
+	if f.title == "" || f.start == "" {
+		return fmt.Errorf("%w: create requires -title and -start", errUsage)
+	}
+
+	var event calendarclient.Event
+	if err := f.apply(&event); err != nil {
+		return err
+	}
+	if f.end == "" {
+		event.EndTime = event.StartTime.Add(time.Hour)
+	}
+
+	created, err := c.client.CreateEvent(ctx, event)
+	if err != nil {
+		return err
+	}
+	return c.print(*created)
+}
+
+func (c *command) update(ctx context.Context, args []string) error {
+	f := newEventFlags("update")
+	if err := f.fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := eventID(f.fs.Args())
+	if err != nil {
+		return err
+	}
+
+	event, err := c.client.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := f.apply(event); err != nil {
+		return err
+	}
+
+	updated, err := c.client.UpdateEvent(ctx, *event)
+	if err != nil {
+		return err
+	}
+	return c.print(*updated)
+}
+
+func (c *command) delete(ctx context.Context, args []string) error {
+	id, err := eventID(args)
+	if err != nil {
+		return err
+	}
+	if err := c.client.DeleteEvent(ctx, id); err != nil {
+		return err
+	}
+	if c.format == formatTable {
+		fmt.Fprintf(c.out, "deleted event %d\n", id)
+	}
+	return nil
+}
+
+func (c *command) get(ctx context.Context, args []string) error {
+	id, err := eventID(args)
+	if err != nil {
+		return err
+	}
+	event, err := c.client.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.print(*event)
+}
+
+// idsFlag is a flag of IDs that may be repeated.
+type idsFlag []int64
+
+func (f *idsFlag) String() string {
+	return fmt.Sprint([]int64(*f))
+}
+
+func (f *idsFlag) Set(value string) error {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, id)
+	return nil
+}
+
+func (c *command) list(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: list requires day, week or month", errUsage)
+	}
+	period := args[0]
+
+	fs := flag.NewFlagSet("list "+period, flag.ContinueOnError)
+	date := fs.String("date", "", "a date in the period; today by default")
+	var tags, calendars idsFlag
+	fs.Var(&tags, "tag", "only events with this tag ID; may be repeated")
+	fs.Var(&calendars, "calendar", "only events in this calendar or another given one; may be repeated")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	day := c.now()
+	if *date != "" {
+		var err error
+		if day, err = parseTime(*date); err != nil {
+			return fmt.Errorf("-date: %w", err)
+		}
+	}
+	from, to, err := periodRange(period, day)
+	if err != nil {
+		return err
+	}
+
+	// The range is inclusive, so stop just before the next period.
+	events, err := c.client.ListCalendarEvents(ctx, from, to.Add(-time.Second), calendars, tags...)
+	if err != nil {
+		return err
+	}
+	return c.print(events...)
+}
+
+func (c *command) print(events ...calendarclient.Event) error {
+	if events == nil {
+		events = []calendarclient.Event{}
+	}
+	return writeEvents(c.out, c.format, events, c.now())
+}
+
+// periodRange returns the day, the week starting on Monday, or the month
+// containing t, in the location of t.
+func periodRange(period string, t time.Time) (from, to time.Time, err error) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case "day":
+		return day, day.AddDate(0, 0, 1), nil
+	case "week":
+		from = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return from, from.AddDate(0, 0, 7), nil
+	case "month":
+		from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return from, from.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w: unknown period %q, want day, week or month", errUsage, period)
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, want e.g. 2025-03-10T09:00 or RFC 3339", value)
+}
+
+func eventID(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: expected one event ID", errUsage)
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(args[0]), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid event ID %q", args[0])
+	}
+	return id, nil
+}
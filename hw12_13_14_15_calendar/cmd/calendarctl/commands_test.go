@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/integration"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/calendarclient"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPeriodRange(t *testing.T) {
+	// Wednesday.
+	day := time.Date(2025, 3, 12, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		period   string
+		from, to time.Time
+	}{
+		{"day", time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"month", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			from, to, err := periodRange(tt.period, day)
+			require.NoError(t, err)
+			require.Equal(t, tt.from, from)
+			require.Equal(t, tt.to, to)
+		})
+	}
+
+	t.Run("week from sunday", func(t *testing.T) {
+		from, _, err := periodRange("week", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), from)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, _, err := periodRange("year", day)
+		require.ErrorIs(t, err, errUsage)
+	})
+}
+
+func TestWriteICS(t *testing.T) {
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	events := []calendarclient.Event{{
+		ID:          7,
+		Title:       "Review; part 1, draft",
+		Description: strings.Repeat("agenda ", 15) + "\nnotes",
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		NotifyAt:    start.Add(-15 * time.Minute),
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeICS(&buf, events, start))
+	out := buf.String()
+
+	require.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	require.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	require.Contains(t, out, "UID:7@calendar\r\n")
+	require.Contains(t, out, "DTSTART:20250310T090000Z\r\n")
+	require.Contains(t, out, "DTEND:20250310T100000Z\r\n")
+	require.Contains(t, out, `SUMMARY:Review\; part 1\, draft`+"\r\n")
+	require.Contains(t, out, "TRIGGER;VALUE=DATE-TIME:20250310T084500Z\r\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), icsLineLimit, line)
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	require.Contains(t, unfolded, "DESCRIPTION:"+strings.Repeat("agenda ", 15)+`\nnotes`+"\r\n")
+}
+
+func TestCommands(t *testing.T) {
+	h := integration.Start(t)
+
+	t.Run("http", func(t *testing.T) {
+		testCommands(t, calendarclient.New(h.URL, 1, calendarclient.WithHTTPClient(h.Client)), calendarclient.IsNotFound)
+	})
+	t.Run("grpc", func(t *testing.T) {
+		testCommands(t, newGRPCClient(h.GRPC, 1, 5*time.Second), func(err error) bool {
+			return status.Code(err) == codes.NotFound
+		})
+	})
+}
+
+// testCommands runs the commands against client. isNotFound reports
+// whether err is the client's not found error.
+func testCommands(t *testing.T, client eventClient, isNotFound func(error) bool) {
+	t.Helper()
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	now := time.Date(2025, 3, 12, 12, 0, 0, 0, time.Local)
+	cmd := &command{
+		client: client,
+		out:    &out,
+		format: formatJSON,
+		now:    func() time.Time { return now },
+	}
+	run := func(args ...string) []calendarclient.Event {
+		t.Helper()
+		out.Reset()
+		require.NoError(t, cmd.run(ctx, args[0], args[1:]))
+
+		var events []calendarclient.Event
+		require.NoError(t, json.Unmarshal(out.Bytes(), &events))
+		return events
+	}
+
+	created := run("create", "-title", "Standup", "-start", "2025-03-10T09:00", "-notify", "2025-03-10T08:55")
+	require.Len(t, created, 1)
+	id := strconv.FormatInt(created[0].ID, 10)
+	require.Equal(t, "Standup", created[0].Title)
+	require.Equal(t, time.Hour, created[0].EndTime.Sub(created[0].StartTime))
+
+	updated := run("update", "-title", "Daily standup", "-notify", "none", id)
+	require.Equal(t, "Daily standup", updated[0].Title)
+	require.True(t, updated[0].NotifyAt.IsZero())
+	require.True(t, created[0].StartTime.Equal(updated[0].StartTime))
+
+	require.Len(t, run("get", id), 1)
+	require.Len(t, run("list", "week"), 1)
+	require.Empty(t, run("list", "day"))
+	require.Len(t, run("list", "day", "-date", "2025-03-10"), 1)
+	require.Len(t, run("list", "month", "-date", "2025-03-31"), 1)
+
+	cmd.format = formatTable
+	out.Reset()
+	require.NoError(t, cmd.run(ctx, "delete", []string{id}))
+	require.Equal(t, "deleted event "+id+"\n", out.String())
+
+	err := cmd.run(ctx, "get", []string{id})
+	require.True(t, isNotFound(err), err)
+
+	require.ErrorIs(t, cmd.run(ctx, "create", []string{"-title", "x"}), errUsage)
+	require.ErrorIs(t, cmd.run(ctx, "frobnicate", nil), errUsage)
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	t.Setenv("CALENDARCTL_SERVER_URL", "http://calendar:8080")
+	t.Setenv("CALENDARCTL_USER_ID", "42")
+
+	conf := defaultSettings()
+	fs := flag.NewFlagSet("calendarctl", flag.ContinueOnError)
+	require.NoError(t, registerFlags(fs, &conf))
+	require.NoError(t, fs.Parse([]string{"-user.id", "7", "-output", "ics"}))
+
+	require.Equal(t, "http://calendar:8080", conf.ServerURL)
+	require.Equal(t, transportHTTP, conf.Transport)
+	require.Equal(t, int64(7), conf.UserID)
+	require.Equal(t, formatICS, conf.Output)
+	require.NoError(t, conf.validate())
+
+	conf.Transport = "smoke"
+	require.Error(t, conf.validate())
+
+	t.Setenv("CALENDARCTL_SERVER_TIMEOUT", "soon")
+	require.Error(t, registerFlags(flag.NewFlagSet("calendarctl", flag.ContinueOnError), &conf))
+}
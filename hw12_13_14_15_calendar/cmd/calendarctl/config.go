@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// envPrefix is prepended to the environment variable of every setting.
+const envPrefix = "CALENDARCTL_"
+
+// Transports of the API.
+const (
+	transportHTTP = "http"
+	transportGRPC = "grpc"
+)
+
+// settings are the connection settings. Like the service configuration,
+// every setting is named by a dotted path that is also its flag
+// (-server.url), and can be given in an environment variable
+// (CALENDARCTL_SERVER_URL). Flags override the environment, which
+// overrides the defaults.
+type settings struct {
+	Transport string
+	ServerURL string
+	// GRPCAddress is the host:port of the gRPC API.
+	GRPCAddress string
+	// GRPCCAFile enables TLS to the gRPC API, trusting the certificates
+	// in the file.
+	GRPCCAFile string
+	Timeout    time.Duration
+	UserID     int64
+	Output     string
+}
+
+func defaultSettings() settings {
+	return settings{
+		Transport:   transportHTTP,
+		ServerURL:   "http://localhost:8080",
+		GRPCAddress: "localhost:50051",
+		Timeout:     10 * time.Second,
+		Output:      formatTable,
+	}
+}
+
+// registerFlags defines the flags of s in fs, with defaults taken from the
+// environment. It returns the first environment variable that failed to
+// parse.
+func registerFlags(fs *flag.FlagSet, s *settings) error {
+	fs.StringVar(&s.Transport, "server.transport", s.Transport, usage("server.transport", "API to call: http or grpc"))
+	fs.StringVar(&s.ServerURL, "server.url", s.ServerURL, usage("server.url", "base URL of the calendar HTTP API"))
+	fs.StringVar(&s.GRPCAddress, "server.grpc_address", s.GRPCAddress,
+		usage("server.grpc_address", "host:port of the calendar gRPC API"))
+	fs.StringVar(&s.GRPCCAFile, "server.grpc_ca_file", s.GRPCCAFile,
+		usage("server.grpc_ca_file", "CA certificates to verify the gRPC API with; plaintext if empty"))
+	fs.DurationVar(&s.Timeout, "server.timeout", s.Timeout, usage("server.timeout", "request timeout"))
+	fs.Int64Var(&s.UserID, "user.id", s.UserID, usage("user.id", "ID of the user to act as"))
+	fs.StringVar(&s.Output, "output", s.Output, usage("output", "output format: table, json or ics"))
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		raw, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if setErr := f.Value.Set(raw); setErr != nil {
+			err = fmt.Errorf("env %s: %w", envName(f.Name), setErr)
+			return
+		}
+		f.DefValue = raw
+	})
+	return err
+}
+
+func (s settings) validate() error {
+	switch s.Transport {
+	case transportHTTP, transportGRPC:
+	default:
+		return fmt.Errorf("invalid transport %q", s.Transport)
+	}
+	switch s.Output {
+	case formatTable, formatJSON, formatICS:
+	default:
+		return fmt.Errorf("invalid output format %q", s.Output)
+	}
+	if s.UserID <= 0 {
+		return fmt.Errorf("user.id must be positive (flag -user.id or env %s)", envName("user.id"))
+	}
+	return nil
+}
+
+func envName(path string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+func usage(path, text string) string {
+	return fmt.Sprintf("%s (env %s)", text, envName(path))
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/calendarclient"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userIDMetadata names the calling user to the gRPC server.
+const userIDMetadata = "x-user-id"
+
+// grpcClient calls the gRPC API as one user.
+type grpcClient struct {
+	events  eventpb.EventServiceClient
+	userID  int64
+	timeout time.Duration
+}
+
+// dialGRPC connects to the gRPC API at address. With a caFile the
+// connection uses TLS and trusts the certificates it holds.
+func dialGRPC(address, caFile string) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots})
+	}
+	return grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+}
+
+func newGRPCClient(events eventpb.EventServiceClient, userID int64, timeout time.Duration) *grpcClient {
+	return &grpcClient{events: events, userID: userID, timeout: timeout}
+}
+
+// call returns the context of a call, naming the user and bounded by
+// the timeout.
+func (c *grpcClient) call(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = metadata.AppendToOutgoingContext(ctx, userIDMetadata, strconv.FormatInt(c.userID, 10))
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func (c *grpcClient) CreateEvent(ctx context.Context, event calendarclient.Event) (*calendarclient.Event, error) {
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	created, err := c.events.CreateEvent(ctx, &eventpb.CreateEventRequest{Event: toProto(event)})
+	if err != nil {
+		return nil, err
+	}
+	return fromProto(created), nil
+}
+
+func (c *grpcClient) UpdateEvent(ctx context.Context, event calendarclient.Event) (*calendarclient.Event, error) {
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	updated, err := c.events.UpdateEvent(ctx, &eventpb.UpdateEventRequest{Event: toProto(event)})
+	if err != nil {
+		return nil, err
+	}
+	return fromProto(updated), nil
+}
+
+func (c *grpcClient) DeleteEvent(ctx context.Context, id int64) error {
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	_, err := c.events.DeleteEvent(ctx, &eventpb.DeleteEventRequest{Id: id})
+	return err
+}
+
+func (c *grpcClient) GetEvent(ctx context.Context, id int64) (*calendarclient.Event, error) {
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	event, err := c.events.GetEvent(ctx, &eventpb.GetEventRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return fromProto(event), nil
+}
+
+func (c *grpcClient) ListCalendarEvents(ctx context.Context,
+	from, to time.Time,
+	calendarIDs []int64,
+	tagIDs ...int64,
+) ([]calendarclient.Event, error) {
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	resp, err := c.events.ListEvents(ctx, &eventpb.ListEventsRequest{
+		From:        timestamppb.New(from),
+		To:          timestamppb.New(to),
+		TagIds:      tagIDs,
+		CalendarIds: calendarIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]calendarclient.Event, len(resp.GetEvents()))
+	for i, event := range resp.GetEvents() {
+		events[i] = *fromProto(event)
+	}
+	return events, nil
+}
+
+func toProto(event calendarclient.Event) *eventpb.Event {
+	e := &eventpb.Event{
+		Id:          event.ID,
+		Title:       event.Title,
+		Description: event.Description,
+		StartTime:   toTimestamp(event.StartTime),
+		EndTime:     toTimestamp(event.EndTime),
+		NotifyAt:    toTimestamp(event.NotifyAt),
+		CalendarId:  event.CalendarID,
+	}
+	if c := event.Conference; c != nil {
+		e.Conference = &eventpb.Conference{
+			Url:       c.URL,
+			Provider:  c.Provider,
+			MeetingId: c.MeetingID,
+			Passcode:  c.Passcode,
+		}
+	}
+	return e
+}
+
+func fromProto(e *eventpb.Event) *calendarclient.Event {
+	event := &calendarclient.Event{
+		ID:          e.GetId(),
+		Title:       e.GetTitle(),
+		Description: e.GetDescription(),
+		StartTime:   fromTimestamp(e.GetStartTime()),
+		EndTime:     fromTimestamp(e.GetEndTime()),
+		UserID:      e.GetUserId(),
+		NotifyAt:    fromTimestamp(e.GetNotifyAt()),
+		CalendarID:  e.GetCalendarId(),
+	}
+	if c := e.GetConference(); c != nil {
+		event.Conference = &calendarclient.Conference{
+			URL:       c.GetUrl(),
+			Provider:  c.GetProvider(),
+			MeetingID: c.GetMeetingId(),
+			Passcode:  c.GetPasscode(),
+		}
+	}
+	return event
+}
+
+// toTimestamp leaves the zero time unset, which the server reads as no
+// reminder.
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp maps an unset timestamp to the zero time.
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,89 @@
+// Command calendarctl creates and inspects events of the calendar service
+// over its HTTP or gRPC API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/calendarclient"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+)
+
+const usageText = `Usage: calendarctl [flags] <command> [command flags] [args]
+
+Events are sent over HTTP, or over gRPC with -server.transport grpc.
+
+Commands:
+  create -title T -start S [-end E] [-description D] [-notify N] [-calendar ID]
+  update [-title T] [-start S] [-end E] [-description D] [-notify N|none] [-calendar ID] <id>
+  delete <id>
+  get <id>
+  list day|week|month [-date D] [-tag ID]... [-calendar ID]...
+
+Flags:
+`
+
+func main() {
+	fs := flag.NewFlagSet("calendarctl", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageText)
+		fs.PrintDefaults()
+	}
+
+	conf := defaultSettings()
+	if err := registerFlags(fs, &conf); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := conf.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cmd := &command{
+		client: calendarclient.New(conf.ServerURL, conf.UserID,
+			calendarclient.WithHTTPClient(&http.Client{Timeout: conf.Timeout})),
+		out:    os.Stdout,
+		format: conf.Output,
+		now:    time.Now,
+	}
+
+	if conf.Transport == transportGRPC {
+		conn, err := dialGRPC(conf.GRPCAddress, conf.GRPCCAFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer conn.Close()
+		cmd.client = newGRPCClient(eventpb.NewEventServiceClient(conn), conf.UserID, conf.Timeout)
+	}
+
+	if err := cmd.run(ctx, fs.Arg(0), fs.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, err.Error())
+		if errors.Is(err, errUsage) {
+			fs.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1) //nolint:gocritic
+	}
+}
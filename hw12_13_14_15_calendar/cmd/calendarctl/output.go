@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/calendarclient"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatICS   = "ics"
+)
+
+const (
+	tableTimeLayout = "2006-01-02 15:04"
+	icsTimeLayout   = "20060102T150405Z"
+	// icsLineLimit is the maximum length of an iCalendar line in octets.
+	icsLineLimit = 75
+)
+
+// writeEvents prints events in format.
+func writeEvents(w io.Writer, format string, events []calendarclient.Event, now time.Time) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	case formatICS:
+		return writeICS(w, events, now)
+	default:
+		return writeTable(w, events)
+	}
+}
+
+func writeTable(w io.Writer, events []calendarclient.Event) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTART\tEND\tNOTIFY\tTITLE")
+	for _, event := range events {
+		notify := "-"
+		if !event.NotifyAt.IsZero() {
+			notify = event.NotifyAt.Local().Format(tableTimeLayout)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			event.ID,
+			event.StartTime.Local().Format(tableTimeLayout),
+			event.EndTime.Local().Format(tableTimeLayout),
+			notify,
+			event.Title)
+	}
+	return tw.Flush()
+}
+
+// writeICS prints events as an iCalendar (RFC 5545) calendar. A reminder
+// becomes an alarm of the event.
+func writeICS(w io.Writer, events []calendarclient.Event, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//otus_hwgo//calendarctl//EN",
+		"CALSCALE:GREGORIAN",
+	}
+	stamp := now.UTC().Format(icsTimeLayout)
+	for _, event := range events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+strconv.FormatInt(event.ID, 10)+"@calendar",
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+event.StartTime.UTC().Format(icsTimeLayout),
+			"DTEND:"+event.EndTime.UTC().Format(icsTimeLayout),
+			"SUMMARY:"+icsEscape(event.Title),
+		)
+		if event.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+icsEscape(event.Description))
+		}
+		if !event.NotifyAt.IsZero() {
+			lines = append(lines,
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				"DESCRIPTION:"+icsEscape(event.Title),
+				"TRIGGER;VALUE=DATE-TIME:"+event.NotifyAt.UTC().Format(icsTimeLayout),
+				"END:VALARM",
+			)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, icsFold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icsEscape(text string) string {
+	return icsEscaper.Replace(text)
+}
+
+// icsFold splits a long line into continuation lines, which start with a
+// space, without breaking UTF-8 sequences.
+func icsFold(line string) string {
+	var b strings.Builder
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the limit.
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
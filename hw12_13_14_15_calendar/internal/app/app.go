@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"time"
 
 	//nolint:depguard
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
@@ -10,23 +11,142 @@ import (
 )
 
 type App struct {
-	logger  logger.Logger
-	storage storage.Storage
+	logger      *logger.Logger
+	storage     storage.Storage
+	changes     *changeHandlers
+	attachments *attachmentConfig
 }
 
-func New(logger logger.Logger, storage storage.Storage) *App {
+func New(logger *logger.Logger, storage storage.Storage) *App {
 	return &App{
-		logger:  logger,
-		storage: storage,
+		logger:      logger,
+		storage:     storage,
+		changes:     &changeHandlers{},
+		attachments: &attachmentConfig{},
 	}
 }
 
 func (a *App) CreateEvent(ctx context.Context, event *storage.Event) error {
+	if err := validateEvent(event); err != nil {
+		return err
+	}
+	if err := a.applyCalendar(ctx, event, true); err != nil {
+		return err
+	}
 	if err := a.storage.CreateEvent(ctx, event); err != nil {
 		a.logger.Error("Failed to create event: " + err.Error())
 		return err
 	}
 
 	a.logger.Info("Created event: " + event.Title)
+	a.emit(ctx, ChangeCreated, event)
+	return nil
+}
+
+func (a *App) UpdateEvent(ctx context.Context, event *storage.Event) error {
+	if err := validateEvent(event); err != nil {
+		return err
+	}
+	if err := a.applyCalendar(ctx, event, false); err != nil {
+		return err
+	}
+	if err := a.storage.UpdateEvent(ctx, event); err != nil {
+		a.logger.Error("Failed to update event: " + err.Error())
+		return err
+	}
+
+	a.logger.Info("Updated event: " + event.Title)
+	a.emit(ctx, ChangeUpdated, event)
+	return nil
+}
+
+// GetEvent returns an event of userID with its attachments. Events of
+// other users are not found.
+func (a *App) GetEvent(ctx context.Context, userID, id int64) (*storage.Event, error) {
+	event, err := a.storage.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event.UserID != userID {
+		return nil, storage.EventNotFound(id)
+	}
+	if err := a.fillAttachments(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (a *App) DeleteEvent(ctx context.Context, id int64) error {
+	// The deleted event is passed to change handlers.
+	event, err := a.storage.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := a.storage.DeleteEvent(ctx, id); err != nil {
+		a.logger.Error("Failed to delete event: " + err.Error())
+		return err
+	}
+
+	a.logger.Info("Deleted event: " + event.Title)
+	a.emit(ctx, ChangeDeleted, event)
+	return nil
+}
+
+// Remind tells change handlers that an event is about to start.
+func (a *App) Remind(ctx context.Context, notification storage.Notification) {
+	a.emit(ctx, ChangeReminder, &storage.Event{
+		ID:        notification.EventID,
+		Title:     notification.Title,
+		StartTime: notification.StartTime,
+		UserID:    notification.UserID,
+	})
+}
+
+// Push is the push channel of the notification sender. It tells change
+// handlers about reminders and digests.
+func (a *App) Push(ctx context.Context, notification storage.Notification) error {
+	if notification.Kind != storage.NotificationDigest {
+		a.Remind(ctx, notification)
+		return nil
+	}
+
+	a.emitChange(ctx, Change{
+		Type: ChangeDigest,
+		Event: storage.Event{
+			Title:     notification.Title,
+			StartTime: notification.StartTime,
+			UserID:    notification.UserID,
+		},
+		At:     time.Now(),
+		Digest: &Digest{Text: notification.Text, HTML: notification.HTML},
+	})
 	return nil
 }
+
+// Ping checks that the storage is reachable within the context deadline.
+func (a *App) Ping(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- a.storage.Ping()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// MigrationVersion reports the schema version of the storage.
+// The ok result is false for storages without migrations.
+func (a *App) MigrationVersion(ctx context.Context) (version uint, dirty bool, ok bool, err error) {
+	versioner, ok := storage.As[storage.MigrationVersioner](a.storage)
+	if !ok {
+		return 0, false, false, nil
+	}
+
+	version, dirty, err = versioner.MigrationVersion(ctx)
+	return version, dirty, true, err
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/blob"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultMimeType   = "application/octet-stream"
+	maxAttachmentName = 255
+	blobKeySize       = 16
+)
+
+var (
+	ErrAttachmentsUnsupported = errors.New("attachments are not configured")
+	ErrAttachmentTooLarge     = errors.New("attachment is too large")
+)
+
+// attachmentConfig is shared by copies of an App.
+type attachmentConfig struct {
+	blobs   blob.Store
+	maxSize int64
+}
+
+// UseBlobStore keeps the bytes of attachments in blobs, up to maxSize
+// bytes each. Attachments are unsupported until it is called.
+func (a *App) UseBlobStore(blobs blob.Store, maxSize int64) {
+	a.attachments.blobs = blobs
+	a.attachments.maxSize = maxSize
+	a.OnChange(a.deleteEventBlobs)
+}
+
+// MaxAttachmentSize is the size limit of an attachment in bytes.
+func (a *App) MaxAttachmentSize() int64 {
+	return a.attachments.maxSize
+}
+
+// UploadAttachment stores the bytes of r as an attachment of an event of
+// userID. An empty mimeType is stored as application/octet-stream.
+func (a *App) UploadAttachment(ctx context.Context,
+	userID, eventID int64,
+	name, mimeType string,
+	r io.Reader,
+) (*storage.Attachment, error) {
+	store, err := a.attachmentStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ownEvent(ctx, userID, eventID); err != nil {
+		return nil, err
+	}
+
+	attachment := &storage.Attachment{EventID: eventID}
+	if attachment.Name, err = attachmentName(name); err != nil {
+		return nil, err
+	}
+	if attachment.MimeType, err = attachmentMimeType(mimeType); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, blobKeySize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	attachment.Key = eventBlobPrefix(eventID) + hex.EncodeToString(buf)
+
+	// One byte past the limit tells an oversized upload apart.
+	blobs, maxSize := a.attachments.blobs, a.attachments.maxSize
+	attachment.Size, err = blobs.Put(ctx, attachment.Key, io.LimitReader(r, maxSize+1))
+	if err == nil && attachment.Size > maxSize {
+		err = fmt.Errorf("%w: the limit is %d bytes", ErrAttachmentTooLarge, maxSize)
+	}
+	if err == nil {
+		err = store.CreateAttachment(ctx, attachment)
+	}
+	if err != nil {
+		a.deleteBlob(ctx, attachment.Key)
+		return nil, err
+	}
+
+	a.logger.Info(fmt.Sprintf("Attached %s to event %d", attachment.Name, eventID))
+	return attachment, nil
+}
+
+// ListAttachments returns the attachments of an event of userID.
+func (a *App) ListAttachments(ctx context.Context, userID, eventID int64) ([]*storage.Attachment, error) {
+	store, err := a.attachmentStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ownEvent(ctx, userID, eventID); err != nil {
+		return nil, err
+	}
+
+	return store.ListAttachments(ctx, eventID)
+}
+
+// OpenAttachment returns an attachment of an event of userID with its
+// bytes. The caller closes the reader.
+func (a *App) OpenAttachment(ctx context.Context,
+	userID, eventID, id int64,
+) (*storage.Attachment, io.ReadCloser, error) {
+	attachment, err := a.ownAttachment(ctx, userID, eventID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := a.attachments.blobs.Open(ctx, attachment.Key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, storage.AttachmentNotFound(id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, r, nil
+}
+
+func (a *App) DeleteAttachment(ctx context.Context, userID, eventID, id int64) error {
+	attachment, err := a.ownAttachment(ctx, userID, eventID, id)
+	if err != nil {
+		return err
+	}
+
+	store, _ := a.attachmentStore()
+	if err := store.DeleteAttachment(ctx, id); err != nil {
+		return err
+	}
+	a.deleteBlob(ctx, attachment.Key)
+	return nil
+}
+
+func (a *App) ownAttachment(ctx context.Context, userID, eventID, id int64) (*storage.Attachment, error) {
+	store, err := a.attachmentStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ownEvent(ctx, userID, eventID); err != nil {
+		return nil, err
+	}
+
+	attachment, err := store.GetAttachment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.EventID != eventID {
+		return nil, storage.AttachmentNotFound(id)
+	}
+	return attachment, nil
+}
+
+// fillAttachments sets the attachments of event when the storage keeps
+// them.
+func (a *App) fillAttachments(ctx context.Context, event *storage.Event) error {
+	store, ok := storage.As[storage.AttachmentStore](a.storage)
+	if !ok {
+		return nil
+	}
+
+	attachments, err := store.ListAttachments(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	event.Attachments = attachments
+	return nil
+}
+
+func (a *App) attachmentStore() (storage.AttachmentStore, error) {
+	store, ok := storage.As[storage.AttachmentStore](a.storage)
+	if !ok || a.attachments.blobs == nil {
+		return nil, ErrAttachmentsUnsupported
+	}
+	return store, nil
+}
+
+// deleteEventBlobs removes the bytes of the attachments of a deleted
+// event. Their metadata is deleted with the event.
+func (a *App) deleteEventBlobs(ctx context.Context, change Change) {
+	if change.Type != ChangeDeleted {
+		return
+	}
+	if err := a.attachments.blobs.DeletePrefix(ctx, eventBlobPrefix(change.Event.ID)); err != nil {
+		a.logger.Error(fmt.Sprintf("Failed to delete attachments of event %d: %s", change.Event.ID, err))
+	}
+}
+
+func (a *App) deleteBlob(ctx context.Context, key string) {
+	if err := a.attachments.blobs.Delete(ctx, key); err != nil {
+		a.logger.Error("Failed to delete blob " + key + ": " + err.Error())
+	}
+}
+
+func eventBlobPrefix(eventID int64) string {
+	return fmt.Sprintf("events/%d/", eventID)
+}
+
+func attachmentName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", storage.ValidationError("attachment name is required")
+	case len(name) > maxAttachmentName:
+		return "", storage.ValidationError(fmt.Sprintf("attachment name must be at most %d bytes", maxAttachmentName))
+	case strings.ContainsAny(name, `/\`) || strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return "", storage.ValidationError("attachment name must not contain slashes or control characters")
+	}
+	return name, nil
+}
+
+func attachmentMimeType(mimeType string) (string, error) {
+	if mimeType == "" {
+		return defaultMimeType, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return "", storage.ValidationError("invalid mime type")
+	}
+	return mime.FormatMediaType(mediaType, params), nil
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrBatchUnsupported = errors.New("storage does not support atomic batches")
+
+var batchChanges = map[string]string{
+	storage.BatchCreate: ChangeCreated,
+	storage.BatchUpdate: ChangeUpdated,
+	storage.BatchDelete: ChangeDeleted,
+}
+
+// BatchResult is the outcome of a batch operation.
+type BatchResult struct {
+	Event *storage.Event
+	Err   error
+}
+
+// ApplyBatch applies ops to the events of userID. Atomic batches are
+// applied all or nothing and fail with a *storage.BatchError naming the
+// failed operation. Otherwise every operation is applied on its own and
+// its error is reported in its result.
+func (a *App) ApplyBatch(ctx context.Context, userID int64, ops []storage.BatchOp, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		op.Event.UserID = userID
+		results[i].Event = op.Event
+		results[i].Err = validateOp(op)
+		// Operations of other batches are checked by CreateEvent and
+		// UpdateEvent.
+		if results[i].Err == nil && atomic && op.Type != storage.BatchDelete {
+			results[i].Err = a.applyCalendar(ctx, op.Event, op.Type == storage.BatchCreate)
+		}
+		if results[i].Err != nil && atomic {
+			return nil, &storage.BatchError{Index: i, Err: results[i].Err}
+		}
+	}
+
+	if atomic {
+		return results, a.applyAtomic(ctx, ops)
+	}
+
+	for i, op := range ops {
+		if results[i].Err == nil {
+			results[i].Err = a.applyOp(ctx, op)
+		}
+	}
+	return results, nil
+}
+
+func (a *App) applyAtomic(ctx context.Context, ops []storage.BatchOp) error {
+	store, ok := storage.As[storage.BatchStore](a.storage)
+	if !ok {
+		return ErrBatchUnsupported
+	}
+
+	if err := store.ApplyBatch(ctx, ops); err != nil {
+		a.logger.Error("Failed to apply batch: " + err.Error())
+		return err
+	}
+
+	a.logger.Info(fmt.Sprintf("Applied batch of %d operations", len(ops)))
+	for _, op := range ops {
+		a.emit(ctx, batchChanges[op.Type], op.Event)
+	}
+	return nil
+}
+
+func (a *App) applyOp(ctx context.Context, op storage.BatchOp) error {
+	switch op.Type {
+	case storage.BatchCreate:
+		return a.CreateEvent(ctx, op.Event)
+	case storage.BatchUpdate:
+		return a.UpdateEvent(ctx, op.Event)
+	default:
+		// Fill the event as an atomic delete does.
+		existing, err := a.storage.GetEvent(ctx, op.Event.ID)
+		if err != nil {
+			return err
+		}
+		if existing.UserID != op.Event.UserID {
+			return storage.EventNotFound(op.Event.ID)
+		}
+		*op.Event = *existing
+		return a.DeleteEvent(ctx, op.Event.ID)
+	}
+}
+
+func validateOp(op storage.BatchOp) error {
+	switch op.Type {
+	case storage.BatchCreate, storage.BatchUpdate:
+		return validateEvent(op.Event)
+	case storage.BatchDelete:
+		return nil
+	}
+	return storage.ValidationError(fmt.Sprintf("unknown operation %q", op.Type))
+}
+
+func validateEvent(event *storage.Event) error {
+	if event.Title == "" {
+		return storage.ValidationError("title is required")
+	}
+	if event.EndTime.Before(event.StartTime) {
+		return storage.ValidationError("end time is before start time")
+	}
+	if event.Conference != nil {
+		u, ok := parseHTTPURL(strings.TrimSpace(event.Conference.URL))
+		if !ok {
+			return storage.ValidationError("conference url must be an absolute http or https URL")
+		}
+		event.Conference.URL = u.String()
+	}
+	return nil
+}
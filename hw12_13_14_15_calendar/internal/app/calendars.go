@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultCalendarColor    = "#1e90ff"
+	defaultCalendarTimeZone = "UTC"
+	// maxDefaultReminder is four weeks in minutes.
+	maxDefaultReminder = 4 * 7 * 24 * 60
+)
+
+var ErrCalendarsUnsupported = errors.New("storage does not support calendars")
+
+// CreateCalendar creates a calendar of calendar.UserID. Color defaults to
+// blue and the time zone to UTC.
+func (a *App) CreateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	store, err := a.calendarStore()
+	if err != nil {
+		return err
+	}
+	if err := validateCalendar(calendar); err != nil {
+		return err
+	}
+
+	return store.CreateCalendar(ctx, calendar)
+}
+
+func (a *App) ListCalendars(ctx context.Context, userID int64) ([]*storage.Calendar, error) {
+	store, err := a.calendarStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListCalendars(ctx, userID)
+}
+
+// UpdateCalendar replaces the settings of a calendar of
+// calendar.UserID. Its events keep their reminders.
+func (a *App) UpdateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	store, err := a.calendarStore()
+	if err != nil {
+		return err
+	}
+	if err := validateCalendar(calendar); err != nil {
+		return err
+	}
+
+	return store.UpdateCalendar(ctx, calendar)
+}
+
+// DeleteCalendar deletes a calendar of the user with all its events.
+func (a *App) DeleteCalendar(ctx context.Context, userID, id int64) error {
+	store, err := a.calendarStore()
+	if err != nil {
+		return err
+	}
+
+	calendar, err := store.GetCalendar(ctx, id)
+	if err != nil {
+		return err
+	}
+	if calendar.UserID != userID {
+		return storage.CalendarNotFound(id)
+	}
+
+	deleted, err := store.DeleteCalendar(ctx, id)
+	if err != nil {
+		a.logger.Error("Failed to delete calendar: " + err.Error())
+		return err
+	}
+
+	a.logger.Info(fmt.Sprintf("Deleted calendar %s with %d events", calendar.Name, len(deleted)))
+	for _, event := range deleted {
+		a.emit(ctx, ChangeDeleted, event)
+	}
+	return nil
+}
+
+// applyCalendar checks that the calendar of an event belongs to the
+// owner of the event. A created event without a reminder gets the
+// default reminder of its calendar.
+func (a *App) applyCalendar(ctx context.Context, event *storage.Event, created bool) error {
+	if event.CalendarID == 0 {
+		return nil
+	}
+	store, err := a.calendarStore()
+	if err != nil {
+		return err
+	}
+
+	calendar, err := store.GetCalendar(ctx, event.CalendarID)
+	if storage.IsNotFound(err) || err == nil && calendar.UserID != event.UserID {
+		return storage.ValidationError("unknown calendar")
+	}
+	if err != nil {
+		return err
+	}
+
+	if created && event.NotifyAt.IsZero() && calendar.DefaultReminder > 0 {
+		event.NotifyAt = event.StartTime.Add(-time.Duration(calendar.DefaultReminder) * time.Minute)
+	}
+	return nil
+}
+
+func (a *App) calendarStore() (storage.CalendarStore, error) {
+	store, ok := storage.As[storage.CalendarStore](a.storage)
+	if !ok {
+		return nil, ErrCalendarsUnsupported
+	}
+	return store, nil
+}
+
+func validateCalendar(calendar *storage.Calendar) error {
+	calendar.Name = strings.TrimSpace(calendar.Name)
+	if calendar.Name == "" {
+		return storage.ValidationError("name is required")
+	}
+
+	calendar.Color = strings.ToLower(calendar.Color)
+	if calendar.Color == "" {
+		calendar.Color = defaultCalendarColor
+	}
+	if !colorPattern.MatchString(calendar.Color) {
+		return storage.ValidationError("color must look like #rrggbb")
+	}
+
+	if calendar.DefaultReminder < 0 || calendar.DefaultReminder > maxDefaultReminder {
+		return storage.ValidationError(fmt.Sprintf("default reminder must be between 0 and %d minutes", maxDefaultReminder))
+	}
+
+	if calendar.TimeZone == "" {
+		calendar.TimeZone = defaultCalendarTimeZone
+	}
+	// Local depends on the server, so it is not a zone of a calendar.
+	if _, err := time.LoadLocation(calendar.TimeZone); err != nil || calendar.TimeZone == "Local" {
+		return storage.ValidationError("unknown time zone")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Change types.
+const (
+	ChangeCreated  = "event.created"
+	ChangeUpdated  = "event.updated"
+	ChangeDeleted  = "event.deleted"
+	ChangeReminder = "event.reminder"
+	ChangeDigest   = "agenda.digest"
+)
+
+// Change describes a change of an event, or a reminder that it is about
+// to start. A digest change carries the daily agenda of Event.UserID, with
+// the subject as Event.Title and the start of the day as Event.StartTime.
+type Change struct {
+	Type   string        `json:"type"`
+	Event  storage.Event `json:"event"`
+	At     time.Time     `json:"at"`
+	Digest *Digest       `json:"digest,omitempty"`
+}
+
+// Digest is a rendered daily agenda.
+type Digest struct {
+	Text string `json:"text"`
+	HTML string `json:"html"`
+}
+
+// ChangeHandler is called after a change is stored. It must not block.
+type ChangeHandler func(ctx context.Context, change Change)
+
+// changeHandlers is shared by copies of an App.
+type changeHandlers struct {
+	mu       sync.RWMutex
+	handlers []ChangeHandler
+}
+
+// OnChange registers fn to be called on every change.
+func (a *App) OnChange(fn ChangeHandler) {
+	a.changes.mu.Lock()
+	defer a.changes.mu.Unlock()
+	a.changes.handlers = append(a.changes.handlers, fn)
+}
+
+func (a *App) emit(ctx context.Context, changeType string, event *storage.Event) {
+	a.emitChange(ctx, Change{Type: changeType, Event: *event, At: time.Now()})
+}
+
+func (a *App) emitChange(ctx context.Context, change Change) {
+	a.changes.mu.RLock()
+	defer a.changes.mu.RUnlock()
+	for _, fn := range a.changes.handlers {
+		fn(ctx, change)
+	}
+}
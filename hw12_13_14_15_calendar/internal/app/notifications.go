@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrNotificationsUnsupported = errors.New("storage does not support notification preferences")
+
+var notificationChannels = map[string]bool{
+	storage.ChannelPush: true,
+	storage.ChannelLog:  true,
+}
+
+// NotificationPreferences returns the notification settings of the user.
+func (a *App) NotificationPreferences(ctx context.Context, userID int64) (*storage.NotificationPreferences, error) {
+	store, err := a.notificationStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.GetNotificationPreferences(ctx, userID)
+}
+
+// SetNotificationPreferences replaces the notification settings of
+// prefs.UserID. The channel defaults to push, the digest time to 08:00 and
+// the time zone to UTC.
+func (a *App) SetNotificationPreferences(ctx context.Context, prefs *storage.NotificationPreferences) error {
+	store, err := a.notificationStore()
+	if err != nil {
+		return err
+	}
+	if err := validatePreferences(prefs); err != nil {
+		return err
+	}
+
+	return store.SetNotificationPreferences(ctx, prefs)
+}
+
+// ListNotificationHistory returns up to limit notification deliveries of
+// the user, newest first. A non-zero eventID only lists those of the event.
+func (a *App) ListNotificationHistory(ctx context.Context,
+	userID, eventID int64,
+	limit int,
+) ([]*storage.NotificationDelivery, error) {
+	store, err := a.notificationStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListNotificationDeliveries(ctx, userID, eventID, limit)
+}
+
+func (a *App) notificationStore() (storage.NotificationStore, error) {
+	store, ok := storage.As[storage.NotificationStore](a.storage)
+	if !ok {
+		return nil, ErrNotificationsUnsupported
+	}
+	return store, nil
+}
+
+func validatePreferences(prefs *storage.NotificationPreferences) error {
+	defaults := storage.DefaultNotificationPreferences(prefs.UserID)
+	if prefs.Channel == "" {
+		prefs.Channel = defaults.Channel
+	}
+	if !notificationChannels[prefs.Channel] {
+		return storage.ValidationError("channel must be push or log")
+	}
+
+	if (prefs.QuietStart == "") != (prefs.QuietEnd == "") {
+		return storage.ValidationError("quiet hours need both a start and an end")
+	}
+	if prefs.QuietStart != "" {
+		if !isTimeOfDay(prefs.QuietStart) || !isTimeOfDay(prefs.QuietEnd) {
+			return storage.ValidationError("quiet hours must look like 22:00")
+		}
+		if prefs.QuietStart == prefs.QuietEnd {
+			return storage.ValidationError("quiet hours must not start and end at the same time")
+		}
+	}
+
+	if prefs.DigestTime == "" {
+		prefs.DigestTime = defaults.DigestTime
+	}
+	if !isTimeOfDay(prefs.DigestTime) {
+		return storage.ValidationError("digest time must look like 08:00")
+	}
+
+	if prefs.TimeZone == "" {
+		prefs.TimeZone = defaults.TimeZone
+	}
+	// Local depends on the server, so it is not a zone of a user.
+	if _, err := time.LoadLocation(prefs.TimeZone); err != nil || prefs.TimeZone == "Local" {
+		return storage.ValidationError("unknown time zone")
+	}
+	return nil
+}
+
+// isTimeOfDay reports whether s is a time of day like 08:00.
+func isTimeOfDay(s string) bool {
+	t, err := time.Parse(storage.TimeOfDayLayout, s)
+	return err == nil && t.Format(storage.TimeOfDayLayout) == s
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+var ErrSearchUnsupported = errors.New("storage does not support search")
+
+// searchEnd bounds searches without an end time.
+var searchEnd = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// SearchEvents finds events of userID whose title or description contain
+// words starting with every word of query. A zero from or to leaves the
+// range open on that side.
+func (a *App) SearchEvents(ctx context.Context,
+	userID int64,
+	query string,
+	from, to time.Time,
+	limit int,
+) ([]*storage.SearchResult, error) {
+	searcher, ok := storage.As[storage.Searcher](a.storage)
+	if !ok {
+		return nil, ErrSearchUnsupported
+	}
+
+	terms := storage.Tokenize(query)
+	if len(terms) == 0 {
+		return nil, storage.ValidationError("query has no words")
+	}
+	if to.IsZero() {
+		to = searchEnd
+	}
+	if to.Before(from) {
+		return nil, storage.ValidationError("end of range is before its start")
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	return searcher.SearchEvents(ctx, userID, terms, from, to, limit)
+}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const defaultTagColor = "#808080"
+
+var (
+	ErrTagsUnsupported = errors.New("storage does not support tags")
+
+	colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+)
+
+// CreateTag creates a tag or category of the user. Kind defaults to a tag
+// and color to grey.
+func (a *App) CreateTag(ctx context.Context, userID int64, name, kind, color string) (*storage.Tag, error) {
+	store, err := a.tagStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "" {
+		kind = storage.TagKindTag
+	}
+	if kind != storage.TagKindTag && kind != storage.TagKindCategory {
+		return nil, storage.ValidationError("kind must be tag or category")
+	}
+
+	tag := &storage.Tag{UserID: userID, Kind: kind}
+	if err := setTagFields(tag, name, color); err != nil {
+		return nil, err
+	}
+
+	if err := store.CreateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (a *App) ListTags(ctx context.Context, userID int64) ([]*storage.Tag, error) {
+	store, err := a.tagStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListTags(ctx, userID)
+}
+
+// UpdateTag renames and recolours a tag. Its kind cannot change.
+func (a *App) UpdateTag(ctx context.Context, userID, id int64, name, color string) (*storage.Tag, error) {
+	store, err := a.tagStore()
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &storage.Tag{ID: id, UserID: userID}
+	if err := setTagFields(tag, name, color); err != nil {
+		return nil, err
+	}
+
+	if err := store.UpdateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (a *App) DeleteTag(ctx context.Context, userID, id int64) error {
+	store, err := a.tagStore()
+	if err != nil {
+		return err
+	}
+
+	tag, err := store.GetTag(ctx, id)
+	if err != nil {
+		return err
+	}
+	if tag.UserID != userID {
+		return storage.TagNotFound(id)
+	}
+
+	return store.DeleteTag(ctx, id)
+}
+
+// SetEventTags replaces the tags of an event of the user. At most one of
+// them may be a category.
+func (a *App) SetEventTags(ctx context.Context, userID, eventID int64, tagIDs []int64) ([]*storage.Tag, error) {
+	store, err := a.tagStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ownEvent(ctx, userID, eventID); err != nil {
+		return nil, err
+	}
+
+	tagIDs = uniqueIDs(tagIDs)
+	tags := make([]*storage.Tag, 0, len(tagIDs))
+	categories := 0
+	for _, id := range tagIDs {
+		tag, err := store.GetTag(ctx, id)
+		if storage.IsNotFound(err) || err == nil && tag.UserID != userID {
+			return nil, storage.ValidationError("unknown tag")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tag.Kind == storage.TagKindCategory {
+			categories++
+		}
+		tags = append(tags, tag)
+	}
+	if categories > 1 {
+		return nil, storage.ValidationError("an event has at most one category")
+	}
+
+	if err := store.SetEventTags(ctx, eventID, tagIDs); err != nil {
+		return nil, err
+	}
+	return store.GetEventTags(ctx, eventID)
+}
+
+func (a *App) GetEventTags(ctx context.Context, userID, eventID int64) ([]*storage.Tag, error) {
+	store, err := a.tagStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ownEvent(ctx, userID, eventID); err != nil {
+		return nil, err
+	}
+
+	return store.GetEventTags(ctx, eventID)
+}
+
+// EventFilter narrows a listing of events. Empty fields keep all events.
+type EventFilter struct {
+	// TagIDs keeps events having all of the tags.
+	TagIDs []int64
+	// CalendarIDs keeps events in any of the calendars.
+	CalendarIDs []int64
+}
+
+// ListEvents returns events of the user overlapping [from, to] that pass
+// filter.
+func (a *App) ListEvents(ctx context.Context,
+	userID int64,
+	from, to time.Time,
+	filter EventFilter,
+) ([]*storage.Event, error) {
+	if to.Before(from) {
+		return nil, storage.ValidationError("end of range is before its start")
+	}
+
+	switch {
+	case len(filter.TagIDs) == 0 && len(filter.CalendarIDs) == 0:
+		return a.storage.ListEvents(ctx, userID, from, to)
+	case len(filter.TagIDs) == 0:
+		store, err := a.calendarStore()
+		if err != nil {
+			return nil, err
+		}
+		return store.ListEventsByCalendars(ctx, userID, uniqueIDs(filter.CalendarIDs), from, to)
+	}
+
+	store, err := a.tagStore()
+	if err != nil {
+		return nil, err
+	}
+	events, err := store.ListEventsByTags(ctx, userID, uniqueIDs(filter.TagIDs), from, to)
+	if err != nil || len(filter.CalendarIDs) == 0 {
+		return events, err
+	}
+
+	calendars := make(map[int64]bool, len(filter.CalendarIDs))
+	for _, id := range filter.CalendarIDs {
+		calendars[id] = true
+	}
+	kept := events[:0]
+	for _, event := range events {
+		if calendars[event.CalendarID] {
+			kept = append(kept, event)
+		}
+	}
+	return kept, nil
+}
+
+func (a *App) tagStore() (storage.TagStore, error) {
+	store, ok := storage.As[storage.TagStore](a.storage)
+	if !ok {
+		return nil, ErrTagsUnsupported
+	}
+	return store, nil
+}
+
+// ownEvent checks that event id belongs to the user. Events of other
+// users are reported as not found.
+func (a *App) ownEvent(ctx context.Context, userID, id int64) error {
+	event, err := a.storage.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if event.UserID != userID {
+		return storage.EventNotFound(id)
+	}
+	return nil
+}
+
+func setTagFields(tag *storage.Tag, name, color string) error {
+	tag.Name = strings.TrimSpace(name)
+	if tag.Name == "" {
+		return storage.ValidationError("name is required")
+	}
+
+	tag.Color = strings.ToLower(color)
+	if tag.Color == "" {
+		tag.Color = defaultTagColor
+	}
+	if !colorPattern.MatchString(tag.Color) {
+		return storage.ValidationError("color must look like #rrggbb")
+	}
+	return nil
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i == 0 || id != sorted[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const secretSize = 32
+
+var ErrWebhooksUnsupported = errors.New("storage does not support webhooks")
+
+// CreateWebhook subscribes url to changes of the user's events. A secret
+// is generated unless one is given.
+func (a *App) CreateWebhook(ctx context.Context, userID int64, target, secret string) (*storage.Webhook, error) {
+	store, err := a.webhookStore()
+	if err != nil {
+		return nil, err
+	}
+
+	u, ok := parseHTTPURL(target)
+	if !ok {
+		return nil, storage.ValidationError("webhook url must be an absolute http or https URL")
+	}
+
+	if secret == "" {
+		buf := make([]byte, secretSize)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &storage.Webhook{UserID: userID, URL: u.String(), Secret: secret}
+	if err := store.CreateWebhook(ctx, webhook); err != nil {
+		a.logger.Error("Failed to create webhook: " + err.Error())
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (a *App) ListWebhooks(ctx context.Context, userID int64) ([]*storage.Webhook, error) {
+	store, err := a.webhookStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListWebhooks(ctx, userID)
+}
+
+func (a *App) DeleteWebhook(ctx context.Context, userID, id int64) error {
+	store, err := a.ownWebhook(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return store.DeleteWebhook(ctx, id)
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook, newest first.
+func (a *App) ListWebhookDeliveries(ctx context.Context,
+	userID, id int64,
+	limit int,
+) ([]*storage.WebhookDelivery, error) {
+	store, err := a.ownWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListWebhookDeliveries(ctx, id, limit)
+}
+
+func (a *App) webhookStore() (storage.WebhookStore, error) {
+	store, ok := storage.As[storage.WebhookStore](a.storage)
+	if !ok {
+		return nil, ErrWebhooksUnsupported
+	}
+	return store, nil
+}
+
+// ownWebhook checks that webhook id belongs to the user. Webhooks of other
+// users are reported as not found.
+func (a *App) ownWebhook(ctx context.Context, userID, id int64) (storage.WebhookStore, error) {
+	store, err := a.webhookStore()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.UserID != userID {
+		return nil, storage.WebhookNotFound(id)
+	}
+
+	return store, nil
+}
+
+// parseHTTPURL parses an absolute http or https URL.
+func parseHTTPURL(raw string) (*url.URL, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
@@ -0,0 +1,24 @@
+// Package blob stores the bytes of files, such as event attachments,
+// apart from the database.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs under slash-separated keys such as "events/1/abc".
+type Store interface {
+	// Put stores the bytes of r under key and returns their number.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the bytes stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. A missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every blob whose key starts with prefix, which
+	// must end with a slash.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
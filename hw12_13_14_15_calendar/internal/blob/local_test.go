@@ -0,0 +1,85 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewLocalStore(dir)
+	require.NoError(t, err)
+
+	read := func(key string) string {
+		t.Helper()
+		r, err := store.Open(ctx, key)
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("put and open", func(t *testing.T) {
+		n, err := store.Put(ctx, "events/1/a", strings.NewReader("agenda"))
+		require.NoError(t, err)
+		require.Equal(t, int64(6), n)
+		require.Equal(t, "agenda", read("events/1/a"))
+
+		_, err = store.Put(ctx, "events/1/a", strings.NewReader("minutes"))
+		require.NoError(t, err)
+		require.Equal(t, "minutes", read("events/1/a"))
+	})
+
+	t.Run("failed put keeps blob", func(t *testing.T) {
+		failing := io.MultiReader(strings.NewReader("partial"), errReader{})
+		_, err := store.Put(ctx, "events/1/a", failing)
+		require.Error(t, err)
+		require.Equal(t, "minutes", read("events/1/a"))
+
+		entries, err := os.ReadDir(filepath.Join(dir, "events", "1"))
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "temporary file left behind")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := store.Open(ctx, "events/1/missing")
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, store.Delete(ctx, "events/1/missing"))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := store.Put(ctx, "events/2/b", strings.NewReader("b"))
+		require.NoError(t, err)
+
+		require.NoError(t, store.Delete(ctx, "events/1/a"))
+		_, err = store.Open(ctx, "events/1/a")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		require.Error(t, store.DeletePrefix(ctx, "events/2"))
+		require.NoError(t, store.DeletePrefix(ctx, "events/2/"))
+		_, err = store.Open(ctx, "events/2/b")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", ".", "../escape", "/abs", "events/../../escape"} {
+			_, err := store.Put(ctx, key, strings.NewReader("x"))
+			require.Error(t, err, key)
+		}
+	})
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -1,41 +1,81 @@
 package config
 
-import (
-	"io"
-	"os"
-
-	"gopkg.in/yaml.v3"
-)
-
+// LoadConfig reads the configuration from defaults, the YAML file and
+// environment variables. Use a Loader to also apply command-line flags.
 func LoadConfig(filePath string) (*Config, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, NewConfigError(err, "failed to open config file", "file_path")
-	}
-	defer file.Close()
+	return NewLoader().Load(filePath)
+}
 
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, NewConfigError(err, "failed to read config file", "file_path")
+func validateConfig(cfg *Config) error {
+	// Validate server listener port
+	if cfg.Components.Server.Listener.Port <= 0 || cfg.Components.Server.Listener.Port > 65535 {
+		return NewConfigError(nil, "invalid port number", "server.listener.port")
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, NewConfigError(err, "failed to unmarshal config", "yaml")
+	if err := validateTLS(cfg.Components.Server.Listener.TLS, "server.listener.tls"); err != nil {
+		return err
 	}
 
-	// Validate the configuration
-	if err := validateConfig(&cfg); err != nil {
-		return nil, err
+	if cfg.Components.GRPC.Listener.Port <= 0 || cfg.Components.GRPC.Listener.Port > 65535 {
+		return NewConfigError(nil, "invalid port number", "grpc.listener.port")
+	}
+	if err := validateTLS(cfg.Components.GRPC.Listener.TLS, "grpc.listener.tls"); err != nil {
+		return err
 	}
 
-	return &cfg, nil
-}
+	if cfg.Components.Server.Health.CheckTimeout < 0 {
+		return NewConfigError(nil, "check timeout must not be negative", "server.health.check_timeout")
+	}
+	if cfg.Components.Server.Health.ShutdownDelay < 0 {
+		return NewConfigError(nil, "shutdown delay must not be negative", "server.health.shutdown_delay")
+	}
 
-func validateConfig(cfg *Config) error {
-	// Validate server listener port
-	if cfg.Components.Server.Listener.Port <= 0 || cfg.Components.Server.Listener.Port > 65535 {
-		return NewConfigError(nil, "invalid port number", "server.listener.port")
+	if cfg.Components.Server.CORS.MaxAge < 0 {
+		return NewConfigError(nil, "max age must not be negative", "server.cors.max_age")
+	}
+	if err := validateServerLimits(&cfg.Components.Server); err != nil {
+		return err
+	}
+	if cfg.Components.Reload.WatchInterval < 0 {
+		return NewConfigError(nil, "watch interval must not be negative", "reload.watch_interval")
+	}
+	if cfg.Components.Scheduler.Interval <= 0 {
+		return NewConfigError(nil, "interval must be positive", "scheduler.interval")
+	}
+	if cfg.Components.Scheduler.BatchSize <= 0 {
+		return NewConfigError(nil, "batch size must be positive", "scheduler.batch_size")
+	}
+	if cfg.Components.Scheduler.Lease <= 0 {
+		return NewConfigError(nil, "lease must be positive", "scheduler.lease")
+	}
+	if cfg.Components.Digest.Interval <= 0 {
+		return NewConfigError(nil, "interval must be positive", "digest.interval")
+	}
+	if cfg.Components.Sender.DedupSize <= 0 {
+		return NewConfigError(nil, "dedup size must be positive", "sender.dedup_size")
+	}
+	if err := validateWebhooks(cfg.Components.Webhooks); err != nil {
+		return err
+	}
+	if cfg.Components.Attachments.Dir == "" {
+		return NewConfigError(nil, "directory is required", "attachments.dir")
+	}
+	if cfg.Components.Attachments.MaxSize <= 0 {
+		return NewConfigError(nil, "max size must be positive", "attachments.max_size")
+	}
+	if cfg.Components.Shutdown.Timeout <= 0 {
+		return NewConfigError(nil, "timeout must be positive", "shutdown.timeout")
+	}
+	for name, timeout := range cfg.Components.Shutdown.Components {
+		if timeout <= 0 {
+			return NewConfigError(nil, "timeout must be positive", "shutdown.components."+name)
+		}
+	}
+	if err := validateTracing(cfg.Components.Tracing); err != nil {
+		return err
+	}
+	if cfg.Components.Shutdown.TimeoutOf("http") <= cfg.Components.Server.Health.ShutdownDelay {
+		return NewConfigError(nil, "http timeout must exceed server.health.shutdown_delay", "shutdown.components.http")
 	}
 
 	// Validate logging level
@@ -46,14 +86,14 @@ func validateConfig(cfg *Config) error {
 		"error": true,
 	}
 	if !validLogLevels[cfg.Components.Logging.Level] {
-		return NewConfigError(nil, "invalid log level", "server.logging.level")
+		return NewConfigError(nil, "invalid log level", "logging.level")
 	}
 	validLogForms := map[string]bool{
 		"text": true,
 		"json": true,
 	}
 	if !validLogForms[cfg.Components.Logging.Type] {
-		return NewConfigError(nil, "invalid log type", "server.logging.type")
+		return NewConfigError(nil, "invalid log type", "logging.type")
 	}
 	// Validate storage type
 	validStorageTypes := map[string]bool{
@@ -61,19 +101,146 @@ func validateConfig(cfg *Config) error {
 		"postgres": true,
 	}
 	if !validStorageTypes[cfg.Components.Storage.Type] {
-		return NewConfigError(nil, "invalid storage type", "server.storage.type")
+		return NewConfigError(nil, "invalid storage type", "storage.type")
 	}
 
 	// Validate PostgreSQL credentials if storage type is postgres
+	if cfg.Components.Storage.Cache.Size < 0 {
+		return NewConfigError(nil, "cache size must not be negative", "storage.cache.size")
+	}
+	if cfg.Components.Storage.Cache.TTL < 0 {
+		return NewConfigError(nil, "cache ttl must not be negative", "storage.cache.ttl")
+	}
+
 	if cfg.Components.Storage.Type == "postgres" {
 		if cfg.Components.Storage.Address == "" {
-			return NewConfigError(nil, "address is required for postgres storage", "server.storage.address")
+			return NewConfigError(nil, "address is required for postgres storage", "storage.address")
 		}
 		if cfg.Components.Storage.Username == "" {
-			return NewConfigError(nil, "username is required for postgres storage", "server.storage.username")
+			return NewConfigError(nil, "username is required for postgres storage", "storage.username")
 		}
 		if cfg.Components.Storage.Password == "" {
-			return NewConfigError(nil, "password is required for postgres storage", "server.storage.password")
+			return NewConfigError(nil, "password is required for postgres storage", "storage.password")
+		}
+		if err := validatePostgres(&cfg.Components.Storage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validatePostgres(cfg *StorageConfig) error {
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return NewConfigError(nil, "invalid port number", "storage.port")
+	}
+
+	validSSLModes := map[string]bool{
+		"":            true,
+		"disable":     true,
+		"allow":       true,
+		"prefer":      true,
+		"require":     true,
+		"verify-ca":   true,
+		"verify-full": true,
+	}
+	if !validSSLModes[cfg.SSLMode] {
+		return NewConfigError(nil, "invalid ssl mode", "storage.ssl_mode")
+	}
+
+	if cfg.ConnectTimeout < 0 {
+		return NewConfigError(nil, "connect timeout must not be negative", "storage.connect_timeout")
+	}
+	if cfg.Replicas.CheckInterval < 0 {
+		return NewConfigError(nil, "check interval must not be negative", "storage.replicas.check_interval")
+	}
+	if cfg.Replicas.ReadYourWrites < 0 {
+		return NewConfigError(nil, "read your writes window must not be negative", "storage.replicas.read_your_writes")
+	}
+	if cfg.Retry.Attempts < 0 {
+		return NewConfigError(nil, "retry attempts must not be negative", "storage.retry.attempts")
+	}
+	if cfg.Retry.InitialBackoff < 0 {
+		return NewConfigError(nil, "backoff must not be negative", "storage.retry.initial_backoff")
+	}
+	if cfg.Retry.MaxBackoff < cfg.Retry.InitialBackoff {
+		return NewConfigError(nil, "max backoff must not be less than initial backoff", "storage.retry.max_backoff")
+	}
+
+	return nil
+}
+
+func validateTLS(cfg TLSConfig, location string) error {
+	if !cfg.Enabled() {
+		if cfg.ClientCAFile != "" {
+			return NewConfigError(nil, "client CA requires a certificate and key", location+".client_ca_file")
+		}
+		return nil
+	}
+
+	if cfg.CertFile == "" {
+		return NewConfigError(nil, "certificate file is required", location+".cert_file")
+	}
+	if cfg.KeyFile == "" {
+		return NewConfigError(nil, "key file is required", location+".key_file")
+	}
+
+	validClientAuth := map[string]bool{
+		"":                true,
+		"require":         true,
+		"verify_if_given": true,
+	}
+	if !validClientAuth[cfg.ClientAuth] {
+		return NewConfigError(nil, "invalid client auth mode", location+".client_auth")
+	}
+	if cfg.ClientAuth != "" && cfg.ClientCAFile == "" {
+		return NewConfigError(nil, "client auth requires a client CA file", location+".client_ca_file")
+	}
+	if cfg.ReloadInterval < 0 {
+		return NewConfigError(nil, "reload interval must not be negative", location+".reload_interval")
+	}
+
+	return nil
+}
+
+func validateServerLimits(cfg *ServerConfig) error {
+	timeouts := map[string]int{
+		"server.timeouts.read":        cfg.Timeouts.Read,
+		"server.timeouts.read_header": cfg.Timeouts.ReadHeader,
+		"server.timeouts.write":       cfg.Timeouts.Write,
+		"server.timeouts.idle":        cfg.Timeouts.Idle,
+	}
+	for location, timeout := range timeouts {
+		if timeout < 0 {
+			return NewConfigError(nil, "timeout must not be negative", location)
+		}
+	}
+
+	if cfg.MaxBatchSize <= 0 {
+		return NewConfigError(nil, "max batch size must be positive", "server.max_batch_size")
+	}
+
+	stream := map[string]int{
+		"server.stream.heartbeat":   cfg.Stream.Heartbeat,
+		"server.stream.log_size":    cfg.Stream.LogSize,
+		"server.stream.buffer_size": cfg.Stream.BufferSize,
+	}
+	for location, value := range stream {
+		if value <= 0 {
+			return NewConfigError(nil, "must be positive", location)
+		}
+	}
+
+	if cfg.MaxBodySize < 0 {
+		return NewConfigError(nil, "max body size must not be negative", "server.max_body_size")
+	}
+
+	for name, rate := range cfg.RateLimit.Groups {
+		if rate.RPS < 0 {
+			return NewConfigError(nil, "rate must not be negative", "server.rate_limit.groups."+name+".rps")
+		}
+		if rate.Burst < 0 {
+			return NewConfigError(nil, "burst must not be negative", "server.rate_limit.groups."+name+".burst")
 		}
 	}
 
@@ -87,19 +254,161 @@ func GetDefaultConfig() *Config {
 				Listener: ListenerConfig{
 					Port: 8080,
 					Host: "0.0.0.0",
+					TLS: TLSConfig{
+						ReloadInterval: 10,
+					},
+				},
+				Health: HealthConfig{
+					CheckTimeout:  2,
+					ShutdownDelay: 0,
+				},
+				CORS: CORSConfig{
+					AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+					AllowedHeaders: []string{"Content-Type"},
+					MaxAge:         600,
+				},
+				Timeouts: TimeoutsConfig{
+					Read:       15,
+					ReadHeader: 5,
+					Write:      15,
+					Idle:       60,
+				},
+				MaxBodySize:  1 << 20,
+				MaxBatchSize: 1000,
+				RateLimit: RateLimitConfig{
+					Enabled:    true,
+					UserHeader: "X-User-ID",
+					Groups: map[string]RateConfig{
+						"default": {RPS: 20, Burst: 40},
+					},
+				},
+				Stream: StreamConfig{
+					Heartbeat:  15,
+					LogSize:    1000,
+					BufferSize: 64,
+				},
+			},
+			GRPC: GRPCConfig{
+				Listener: ListenerConfig{
+					Port: 50051,
+					Host: "0.0.0.0",
+					TLS: TLSConfig{
+						ReloadInterval: 10,
+					},
 				},
 			},
 			Logging: LoggingConfig{
 				FilePath: "/tmp/calendar.log",
 				Level:    "info",
+				Type:     "text",
 			},
 			Storage: StorageConfig{
-				Type: "memory",
+				Type:            "memory",
+				SSLMode:         "disable",
+				ConnectTimeout:  5,
+				ApplicationName: "calendar",
+				Retry: StorageRetryConfig{
+					Attempts:       10,
+					InitialBackoff: 1,
+					MaxBackoff:     10,
+				},
+				Replicas: ReplicaConfig{
+					CheckInterval:  5,
+					ReadYourWrites: 5,
+				},
+				Cache: CacheConfig{
+					Enabled: false,
+					Size:    10000,
+					TTL:     60,
+				},
+				AutoMigrate: true,
 				Pool: StoragePoolConfig{
 					MaxOpenConns:    25,
 					ConnMaxLifetime: 300,
 				},
 			},
+			Reload: ReloadConfig{
+				WatchInterval: 5,
+			},
+			Scheduler: SchedulerConfig{
+				Interval:  10,
+				BatchSize: 100,
+				Lease:     60,
+			},
+			Sender: SenderConfig{
+				DedupSize: 10000,
+			},
+			Digest: DigestConfig{
+				Interval: 60,
+			},
+			Webhooks: WebhookConfig{
+				Workers:        4,
+				QueueSize:      1000,
+				Timeout:        10,
+				MaxAttempts:    5,
+				InitialBackoff: 1,
+				MaxBackoff:     60,
+			},
+			Attachments: AttachmentConfig{
+				Dir:     "/tmp/calendar/attachments",
+				MaxSize: 10 << 20,
+			},
+			Shutdown: ShutdownConfig{
+				Timeout: 10,
+			},
+			Tracing: TracingConfig{
+				Exporter:    "none",
+				Endpoint:    "localhost:4318",
+				ServiceName: "calendar",
+				SampleRatio: 1,
+			},
 		},
 	}
 }
+
+func validateWebhooks(cfg WebhookConfig) error {
+	positive := map[string]int{
+		"webhooks.workers":         cfg.Workers,
+		"webhooks.max_attempts":    cfg.MaxAttempts,
+		"webhooks.initial_backoff": cfg.InitialBackoff,
+	}
+	for location, value := range positive {
+		if value <= 0 {
+			return NewConfigError(nil, "must be positive", location)
+		}
+	}
+
+	if cfg.QueueSize < 0 {
+		return NewConfigError(nil, "queue size must not be negative", "webhooks.queue_size")
+	}
+	if cfg.Timeout < 0 {
+		return NewConfigError(nil, "timeout must not be negative", "webhooks.timeout")
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		return NewConfigError(nil, "max backoff must not be less than initial backoff", "webhooks.max_backoff")
+	}
+
+	return nil
+}
+
+func validateTracing(cfg TracingConfig) error {
+	validExporters := map[string]bool{
+		"none":   true,
+		"stdout": true,
+		"otlp":   true,
+	}
+	if !validExporters[cfg.Exporter] {
+		return NewConfigError(nil, "invalid exporter", "tracing.exporter")
+	}
+	if cfg.Exporter == "otlp" && cfg.Endpoint == "" {
+		return NewConfigError(nil, "endpoint is required for otlp exporter", "tracing.endpoint")
+	}
+	if cfg.ServiceName == "" {
+		return NewConfigError(nil, "service name is required", "tracing.service_name")
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return NewConfigError(nil, "sample ratio must be between 0 and 1", "tracing.sample_ratio")
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to every environment variable read by the Loader.
+const EnvPrefix = "CALENDAR_"
+
+// DefaultFile is the configuration file read when none is given. It is
+// the only file that may be missing.
+const DefaultFile = "./config.yaml"
+
+const sourceDefault = "default"
+
+// Loader builds a Config from layered sources, each overriding the previous:
+// defaults from GetDefaultConfig, the YAML file, CALENDAR_-prefixed
+// environment variables and finally command-line flags.
+//
+// Every setting is addressed by its dotted YAML path below "components",
+// e.g. "storage.password". The same path names the flag (-storage.password)
+// and the environment variable (CALENDAR_STORAGE_PASSWORD).
+type Loader struct {
+	flags   map[string]*flagValue
+	sources map[string]string
+}
+
+type flagValue struct {
+	value string
+	set   bool
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	f.set = true
+	return nil
+}
+
+// setting is a single leaf of the configuration tree.
+type setting struct {
+	path  string
+	value reflect.Value
+}
+
+func NewLoader() *Loader {
+	return &Loader{
+		flags:   make(map[string]*flagValue),
+		sources: make(map[string]string),
+	}
+}
+
+// RegisterFlags defines a flag for every configuration setting in fs.
+// It must be called before fs is parsed.
+func (l *Loader) RegisterFlags(fs *flag.FlagSet) {
+	for _, s := range settingsOf(GetDefaultConfig()) {
+		fv := &flagValue{}
+		l.flags[s.path] = fv
+		fs.Var(fv, s.path, fmt.Sprintf("overrides %s (env %s)", s.path, envName(s.path)))
+	}
+}
+
+// Load reads the configuration, applying every layer in order, and validates
+// the result. A missing file is an error unless it is DefaultFile, so a
+// mistyped path does not start the service on defaults.
+func (l *Loader) Load(filePath string) (*Config, error) {
+	cfg := GetDefaultConfig()
+	settings := settingsOf(cfg)
+
+	l.sources = make(map[string]string, len(settings))
+	for _, s := range settings {
+		l.sources[s.path] = sourceDefault
+	}
+
+	if err := l.applyFile(cfg, filePath); err != nil {
+		return nil, err
+	}
+
+	for _, s := range settings {
+		name := envName(s.path)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		source := "env " + name
+		if err := setValue(s.value, raw); err != nil {
+			return nil, NewConfigError(err, "failed to parse value", locate(s.path, source))
+		}
+		l.sources[s.path] = source
+	}
+
+	for _, s := range settings {
+		fv, ok := l.flags[s.path]
+		if !ok || !fv.set {
+			continue
+		}
+		source := "flag -" + s.path
+		if err := setValue(s.value, fv.value); err != nil {
+			return nil, NewConfigError(err, "failed to parse value", locate(s.path, source))
+		}
+		l.sources[s.path] = source
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		var cfgErr *Error
+		if errors.As(err, &cfgErr) {
+			cfgErr.Location = locate(cfgErr.Location, l.Source(cfgErr.Location))
+		}
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Source reports which layer supplied the setting at path.
+func (l *Loader) Source(path string) string {
+	if source, ok := l.sources[path]; ok {
+		return source
+	}
+	return sourceDefault
+}
+
+func (l *Loader) applyFile(cfg *Config, filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) && filepath.Clean(filePath) == filepath.Clean(DefaultFile) {
+		return nil
+	}
+	if err != nil {
+		return NewConfigError(err, "failed to read config file", "file_path")
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return NewConfigError(err, "failed to unmarshal config", "yaml")
+	}
+
+	// Decode once more into a generic tree to learn which keys the file set.
+	var raw struct {
+		Components map[string]interface{} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return NewConfigError(err, "failed to unmarshal config", "yaml")
+	}
+
+	source := "file " + filePath
+	markPaths(raw.Components, "", func(path string) {
+		l.sources[path] = source
+	})
+
+	return nil
+}
+
+func markPaths(node map[string]interface{}, prefix string, mark func(string)) {
+	for key, value := range node {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok {
+			markPaths(child, path, mark)
+			continue
+		}
+		mark(path)
+	}
+}
+
+// settingsOf lists the leaves of cfg.Components that can be set from
+// a string, with addressable values.
+func settingsOf(cfg *Config) []setting {
+	var settings []setting
+	walkSettings(reflect.ValueOf(&cfg.Components).Elem(), "", func(path string, value reflect.Value) {
+		// Maps can only be set from the file.
+		if value.Kind() != reflect.Map {
+			settings = append(settings, setting{path: path, value: value})
+		}
+	})
+	return settings
+}
+
+// walkSettings calls fn for every non-struct field below v, named by its
+// dotted YAML path.
+func walkSettings(v reflect.Value, prefix string, fn func(path string, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			walkSettings(value, path, fn)
+			continue
+		}
+		fn(path, value)
+	}
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func envName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+func locate(path, source string) string {
+	return fmt.Sprintf("%s (%s)", path, source)
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoader_Layers(t *testing.T) {
+	path := writeConfigFile(t, `
+components:
+  server:
+    listener:
+      port: 9000
+  logging:
+    level: debug
+  storage:
+    type: memory
+`)
+
+	t.Run("defaults when default file is missing", func(t *testing.T) {
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.NoError(t, os.Chdir(t.TempDir()))
+		t.Cleanup(func() { os.Chdir(wd) })
+
+		cfg, err := NewLoader().Load(DefaultFile)
+		require.NoError(t, err)
+		require.Equal(t, GetDefaultConfig(), cfg)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := NewLoader().Load(filepath.Join(t.TempDir(), "missing.yaml"))
+
+		var cfgErr *Error
+		require.ErrorAs(t, err, &cfgErr)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("file overrides defaults", func(t *testing.T) {
+		loader := NewLoader()
+		cfg, err := loader.Load(path)
+		require.NoError(t, err)
+		require.Equal(t, 9000, cfg.Components.Server.Listener.Port)
+		require.Equal(t, "0.0.0.0", cfg.Components.Server.Listener.Host)
+		require.Equal(t, "file "+path, loader.Source("server.listener.port"))
+		require.Equal(t, sourceDefault, loader.Source("server.listener.host"))
+	})
+
+	t.Run("env overrides file", func(t *testing.T) {
+		t.Setenv("CALENDAR_SERVER_LISTENER_PORT", "9100")
+		t.Setenv("CALENDAR_STORAGE_POOL_MAX_OPEN_CONNS", "7")
+
+		loader := NewLoader()
+		cfg, err := loader.Load(path)
+		require.NoError(t, err)
+		require.Equal(t, 9100, cfg.Components.Server.Listener.Port)
+		require.Equal(t, 7, cfg.Components.Storage.Pool.MaxOpenConns)
+		require.Equal(t, "env CALENDAR_SERVER_LISTENER_PORT", loader.Source("server.listener.port"))
+	})
+
+	t.Run("flags override env", func(t *testing.T) {
+		t.Setenv("CALENDAR_SERVER_LISTENER_PORT", "9100")
+
+		loader := NewLoader()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		loader.RegisterFlags(fs)
+		require.NoError(t, fs.Parse([]string{"-server.listener.port=9200", "-logging.level", "warn"}))
+
+		cfg, err := loader.Load(path)
+		require.NoError(t, err)
+		require.Equal(t, 9200, cfg.Components.Server.Listener.Port)
+		require.Equal(t, "warn", cfg.Components.Logging.Level)
+		require.Equal(t, "flag -server.listener.port", loader.Source("server.listener.port"))
+	})
+}
+
+func TestLoader_ErrorLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		location string
+	}{
+		{
+			name:     "invalid value from file",
+			file:     "components:\n  logging:\n    level: loud\n",
+			location: "logging.level (file {file})",
+		},
+		{
+			name:     "invalid map value from file",
+			file:     "components:\n  shutdown:\n    components:\n      http: 0\n",
+			location: "shutdown.components.http (file {file})",
+		},
+		{
+			name:     "invalid value from env",
+			env:      map[string]string{"CALENDAR_STORAGE_TYPE": "redis"},
+			location: "storage.type (env CALENDAR_STORAGE_TYPE)",
+		},
+		{
+			name:     "unparsable value from env",
+			env:      map[string]string{"CALENDAR_SERVER_LISTENER_PORT": "http"},
+			location: "server.listener.port (env CALENDAR_SERVER_LISTENER_PORT)",
+		},
+		{
+			name:     "missing value keeps default source",
+			env:      map[string]string{"CALENDAR_STORAGE_TYPE": "postgres"},
+			location: "storage.address (default)",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfigFile(t, tc.file)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := NewLoader().Load(path)
+
+			var cfgErr *Error
+			require.True(t, errors.As(err, &cfgErr))
+			require.Equal(t, strings.ReplaceAll(tc.location, "{file}", path), cfgErr.Location)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRestartRequired is returned when a reload changes settings that only
+// take effect after a restart.
+var ErrRestartRequired = errors.New("change requires restart")
+
+// reloadable lists the settings, or their parents, that are safe to change
+// while the service is running.
+var reloadable = []string{
+	"logging.level",
+	"logging.type",
+	"server.cors",
+	"server.rate_limit",
+	"reload.watch_interval",
+	"scheduler.interval",
+}
+
+// Reloader holds the current configuration and swaps it for a new one
+// when the config file changes or a reload is requested.
+type Reloader struct {
+	loader  *Loader
+	path    string
+	current atomic.Pointer[Config]
+
+	mu       sync.Mutex
+	handlers []func(cfg *Config)
+}
+
+func NewReloader(loader *Loader, path string, initial *Config) *Reloader {
+	r := &Reloader{
+		loader: loader,
+		path:   path,
+	}
+	r.current.Store(initial)
+	return r
+}
+
+// Current returns the configuration in effect.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn to be called with every configuration swapped in.
+func (r *Reloader) OnReload(fn func(cfg *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, fn)
+}
+
+// Reload reads and validates the configuration again. The new configuration
+// replaces the current one only if it differs in reloadable settings alone.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.loader.Load(r.path)
+	if err != nil {
+		return err
+	}
+
+	prev := r.current.Load()
+	changed := ChangedSettings(prev, next)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var restart []string
+	for _, path := range changed {
+		if !isReloadable(path) {
+			restart = append(restart, path)
+		}
+	}
+	if len(restart) > 0 {
+		return NewConfigError(ErrRestartRequired, "reload rejected", strings.Join(restart, ", "))
+	}
+
+	r.current.Store(next)
+	for _, fn := range r.handlers {
+		fn(next)
+	}
+
+	return nil
+}
+
+// Watch reloads the configuration whenever trigger fires or, if the
+// watch interval is positive, the config file modification time changes.
+// Reload errors are passed to onError. Watch blocks until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, trigger <-chan os.Signal, onError func(error)) {
+	lastMod := r.modTime()
+
+	for {
+		var tick <-chan time.Time
+		if interval := r.Current().Components.Reload.WatchInterval; interval > 0 {
+			tick = time.After(time.Duration(interval) * time.Second)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger:
+			lastMod = r.modTime()
+		case <-tick:
+			mod := r.modTime()
+			if mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+		}
+
+		if err := r.Reload(); err != nil {
+			onError(err)
+		}
+	}
+}
+
+func (r *Reloader) modTime() time.Time {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// ChangedSettings lists the dotted paths of settings that differ between
+// prev and next.
+func ChangedSettings(prev, next *Config) []string {
+	values := make(map[string]reflect.Value)
+	walkSettings(reflect.ValueOf(&prev.Components).Elem(), "", func(path string, value reflect.Value) {
+		values[path] = value
+	})
+
+	var changed []string
+	walkSettings(reflect.ValueOf(&next.Components).Elem(), "", func(path string, value reflect.Value) {
+		if !reflect.DeepEqual(values[path].Interface(), value.Interface()) {
+			changed = append(changed, path)
+		}
+	})
+
+	return changed
+}
+
+func isReloadable(path string) bool {
+	for _, p := range reloadable {
+		if path == p || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
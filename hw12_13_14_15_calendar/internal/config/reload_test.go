@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader_Reload(t *testing.T) {
+	path := writeConfigFile(t, "components:\n  logging:\n    level: info\n")
+	loader := NewLoader()
+	initial, err := loader.Load(path)
+	require.NoError(t, err)
+
+	reloader := NewReloader(loader, path, initial)
+	var applied *Config
+	reloader.OnReload(func(cfg *Config) {
+		applied = cfg
+	})
+
+	t.Run("applies reloadable change", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`
+components:
+  logging:
+    level: debug
+  server:
+    cors:
+      allowed_origins: ["https://example.com"]
+`), 0o600))
+
+		require.NoError(t, reloader.Reload())
+		require.NotNil(t, applied)
+		require.Equal(t, "debug", reloader.Current().Components.Logging.Level)
+		require.Equal(t, []string{"https://example.com"}, reloader.Current().Components.Server.CORS.AllowedOrigins)
+	})
+
+	t.Run("applies scheduler interval change", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`
+components:
+  logging:
+    level: debug
+  server:
+    cors:
+      allowed_origins: ["https://example.com"]
+  scheduler:
+    interval: 30
+`), 0o600))
+
+		require.NoError(t, reloader.Reload())
+		require.Equal(t, 30, applied.Components.Scheduler.Interval)
+		require.Equal(t, 30, reloader.Current().Components.Scheduler.Interval)
+	})
+
+	t.Run("rejects change requiring restart", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`
+components:
+  logging:
+    level: warn
+  server:
+    listener:
+      port: 9999
+`), 0o600))
+
+		err := reloader.Reload()
+		require.True(t, errors.Is(err, ErrRestartRequired))
+
+		var cfgErr *Error
+		require.True(t, errors.As(err, &cfgErr))
+		require.Contains(t, cfgErr.Location, "server.listener.port")
+		require.Equal(t, "debug", reloader.Current().Components.Logging.Level)
+	})
+
+	t.Run("rejects invalid config", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("components:\n  logging:\n    level: loud\n"), 0o600))
+
+		require.Error(t, reloader.Reload())
+		require.Equal(t, "debug", reloader.Current().Components.Logging.Level)
+	})
+}
@@ -13,6 +13,10 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("config error at %s: %s: %v", e.Location, e.Message, e.Err)
 }
 
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 func NewConfigError(err error, message, location string) *Error {
 	return &Error{
 		Err:      err,
@@ -28,19 +32,103 @@ type Config struct {
 
 // ComponentsConfig holds all component-specific configurations.
 type ComponentsConfig struct {
-	Server  ServerConfig  `yaml:"server"`
-	Logging LoggingConfig `yaml:"logging"`
-	Storage StorageConfig `yaml:"storage"`
+	Server      ServerConfig     `yaml:"server"`
+	GRPC        GRPCConfig       `yaml:"grpc"`
+	Logging     LoggingConfig    `yaml:"logging"`
+	Storage     StorageConfig    `yaml:"storage"`
+	Reload      ReloadConfig     `yaml:"reload"`
+	Scheduler   SchedulerConfig  `yaml:"scheduler"`
+	Sender      SenderConfig     `yaml:"sender"`
+	Digest      DigestConfig     `yaml:"digest"`
+	Webhooks    WebhookConfig    `yaml:"webhooks"`
+	Attachments AttachmentConfig `yaml:"attachments"`
+	Shutdown    ShutdownConfig   `yaml:"shutdown"`
+	Tracing     TracingConfig    `yaml:"tracing"`
 }
 
 type ServerConfig struct {
+	Listener     ListenerConfig  `yaml:"listener"`
+	Health       HealthConfig    `yaml:"health"`
+	CORS         CORSConfig      `yaml:"cors"`
+	Timeouts     TimeoutsConfig  `yaml:"timeouts"`
+	MaxBodySize  int64           `yaml:"max_body_size,omitempty"`  // in bytes
+	MaxBatchSize int             `yaml:"max_batch_size,omitempty"` // operations per batch request
+	RateLimit    RateLimitConfig `yaml:"rate_limit"`
+	Stream       StreamConfig    `yaml:"stream"`
+}
+
+// GRPCConfig holds gRPC server configurations.
+type GRPCConfig struct {
 	Listener ListenerConfig `yaml:"listener"`
 }
 
 // ListenerConfig holds server listener configurations.
 type ListenerConfig struct {
-	Port int    `yaml:"port"`
-	Host string `yaml:"host"`
+	Port int       `yaml:"port"`
+	Host string    `yaml:"host"`
+	TLS  TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig holds listener certificate configurations. TLS is enabled when
+// CertFile and KeyFile are set; setting ClientCAFile enables mutual TLS.
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file,omitempty"`
+	KeyFile      string `yaml:"key_file,omitempty"`
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+	// ClientAuth is "require" (default with a client CA) or "verify_if_given".
+	ClientAuth     string `yaml:"client_auth,omitempty"`
+	ReloadInterval int    `yaml:"reload_interval,omitempty"` // in seconds
+}
+
+// Enabled reports whether the listener serves TLS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// HealthConfig holds liveness and readiness probe configurations.
+type HealthConfig struct {
+	CheckTimeout  int `yaml:"check_timeout,omitempty"`  // in seconds
+	ShutdownDelay int `yaml:"shutdown_delay,omitempty"` // in seconds
+}
+
+// CORSConfig holds cross-origin resource sharing configurations.
+// CORS headers are not sent when AllowedOrigins is empty.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	AllowedMethods []string `yaml:"allowed_methods,omitempty"`
+	AllowedHeaders []string `yaml:"allowed_headers,omitempty"`
+	MaxAge         int      `yaml:"max_age,omitempty"` // in seconds
+}
+
+// TimeoutsConfig holds HTTP server timeouts, in seconds. Zero means no timeout.
+type TimeoutsConfig struct {
+	Read       int `yaml:"read,omitempty"`
+	ReadHeader int `yaml:"read_header,omitempty"`
+	Write      int `yaml:"write,omitempty"`
+	Idle       int `yaml:"idle,omitempty"`
+}
+
+// RateLimitConfig holds per-client rate limiting configurations.
+// Clients are identified by UserHeader or, without it, by IP address.
+type RateLimitConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	UserHeader string `yaml:"user_header,omitempty"`
+	// Groups maps a route group name to its rate. Routes of a group
+	// without an entry use the "default" group.
+	Groups map[string]RateConfig `yaml:"groups,omitempty"`
+}
+
+// StreamConfig holds server-sent events stream configurations.
+type StreamConfig struct {
+	Heartbeat  int `yaml:"heartbeat,omitempty"`   // in seconds
+	LogSize    int `yaml:"log_size,omitempty"`    // changes kept for resuming
+	BufferSize int `yaml:"buffer_size,omitempty"` // changes queued per client before it is dropped
+}
+
+// RateConfig holds token bucket parameters.
+type RateConfig struct {
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
 }
 
 // LoggingConfig holds logging specific configurations.
@@ -52,12 +140,26 @@ type LoggingConfig struct {
 
 // StorageConfig holds storage specific configurations.
 type StorageConfig struct {
-	Type     string            `yaml:"type"` // memory or postgres
-	Address  string            `yaml:"address,omitempty"`
-	Username string            `yaml:"username,omitempty"`
-	Password string            `yaml:"password,omitempty"`
-	Database string            `yaml:"database,omitempty"`
-	Pool     StoragePoolConfig `yaml:"pool,omitempty"`
+	Type            string             `yaml:"type"`              // memory or postgres
+	Address         string             `yaml:"address,omitempty"` // host, or host:port
+	Port            int                `yaml:"port,omitempty"`    // overrides the port in Address, 5432 if neither is set
+	Username        string             `yaml:"username,omitempty"`
+	Password        string             `yaml:"password,omitempty"`
+	Database        string             `yaml:"database,omitempty"`
+	SSLMode         string             `yaml:"ssl_mode,omitempty"`
+	SearchPath      string             `yaml:"search_path,omitempty"`
+	ConnectTimeout  int                `yaml:"connect_timeout,omitempty"` // in seconds
+	ApplicationName string             `yaml:"application_name,omitempty"`
+	Pool            StoragePoolConfig  `yaml:"pool,omitempty"`
+	Retry           StorageRetryConfig `yaml:"retry,omitempty"`
+	Replicas        ReplicaConfig      `yaml:"replicas,omitempty"`
+	Cache           CacheConfig        `yaml:"cache,omitempty"`
+	// SnapshotFile is where a memory storage keeps everything it holds. It
+	// is loaded on startup and saved on shutdown, and may also be an
+	// archive written by export. Empty keeps the records in memory only.
+	SnapshotFile string `yaml:"snapshot_file,omitempty"`
+	// AutoMigrate applies pending migrations on startup.
+	AutoMigrate bool `yaml:"auto_migrate"`
 }
 
 // StoragePoolConfig holds database connection pool configurations.
@@ -66,3 +168,102 @@ type StoragePoolConfig struct {
 	ConnMaxLifetime int `yaml:"conn_max_lifetime,omitempty"` // in seconds
 	MaxIdleConns    int `yaml:"max_idle_conns,omitempty"`
 }
+
+// StorageRetryConfig holds the initial connection retry configurations.
+// The backoff doubles after every failed attempt up to MaxBackoff.
+type StorageRetryConfig struct {
+	Attempts       int `yaml:"attempts,omitempty"`
+	InitialBackoff int `yaml:"initial_backoff,omitempty"` // in seconds
+	MaxBackoff     int `yaml:"max_backoff,omitempty"`     // in seconds
+}
+
+// ReplicaConfig holds read replica configurations for postgres storage.
+type ReplicaConfig struct {
+	DSNs          []string `yaml:"dsns,omitempty"`
+	CheckInterval int      `yaml:"check_interval,omitempty"` // in seconds
+	// ReadYourWrites sends reads of a user to the primary for this many
+	// seconds after their last write. Zero disables it.
+	ReadYourWrites int `yaml:"read_your_writes,omitempty"`
+}
+
+// CacheConfig holds storage read cache configurations.
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Size    int  `yaml:"size,omitempty"` // max cached entries
+	TTL     int  `yaml:"ttl,omitempty"`  // in seconds
+}
+
+// ReloadConfig holds configuration hot reload settings.
+type ReloadConfig struct {
+	WatchInterval int `yaml:"watch_interval,omitempty"` // in seconds, 0 disables file watching
+}
+
+// SchedulerConfig holds notification scheduling and outbox relay settings.
+type SchedulerConfig struct {
+	Interval  int `yaml:"interval,omitempty"`   // in seconds
+	BatchSize int `yaml:"batch_size,omitempty"` // outbox messages relayed per query
+	// Lease is how long a relayed outbox message waits for its consumer
+	// to acknowledge it before it is relayed again, in seconds.
+	Lease int `yaml:"lease,omitempty"`
+}
+
+// SenderConfig holds notification sender settings.
+type SenderConfig struct {
+	// DedupSize is the number of recent message keys remembered to drop
+	// redelivered notifications.
+	DedupSize int `yaml:"dedup_size,omitempty"`
+}
+
+// DigestConfig holds daily agenda digest settings.
+type DigestConfig struct {
+	// Interval is how often due digests are looked for, so it bounds how
+	// late after their digest time users get them.
+	Interval int `yaml:"interval,omitempty"` // in seconds
+}
+
+// WebhookConfig holds webhook delivery settings. The backoff between
+// attempts doubles up to MaxBackoff.
+type WebhookConfig struct {
+	Workers        int `yaml:"workers,omitempty"`
+	QueueSize      int `yaml:"queue_size,omitempty"`
+	Timeout        int `yaml:"timeout,omitempty"` // in seconds
+	MaxAttempts    int `yaml:"max_attempts,omitempty"`
+	InitialBackoff int `yaml:"initial_backoff,omitempty"` // in seconds
+	MaxBackoff     int `yaml:"max_backoff,omitempty"`     // in seconds
+	// AllowPrivateNetworks lets webhooks reach loopback, private and
+	// link-local addresses, which are refused by default.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// AttachmentConfig holds event attachment settings. The bytes of
+// attachments are kept in files under Dir.
+type AttachmentConfig struct {
+	Dir     string `yaml:"dir,omitempty"`
+	MaxSize int64  `yaml:"max_size,omitempty"` // in bytes
+}
+
+// ShutdownConfig holds graceful shutdown deadlines, in seconds.
+type ShutdownConfig struct {
+	// Timeout bounds the stop of each component.
+	Timeout int `yaml:"timeout,omitempty"`
+	// Components overrides Timeout by component name: http, grpc,
+	// reloader, webhooks, digest, scheduler, queue, storage or tracing.
+	Components map[string]int `yaml:"components,omitempty"`
+}
+
+// TracingConfig holds OpenTelemetry tracing settings.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`               // none, stdout or otlp
+	Endpoint    string  `yaml:"endpoint,omitempty"`     // OTLP/HTTP collector host:port
+	Insecure    bool    `yaml:"insecure"`               // send OTLP without TLS
+	ServiceName string  `yaml:"service_name,omitempty"` // reported as service.name
+	SampleRatio float64 `yaml:"sample_ratio"`           // share of new traces recorded, 0 to 1
+}
+
+// TimeoutOf returns the stop deadline of the named component in seconds.
+func (c ShutdownConfig) TimeoutOf(name string) int {
+	if timeout, ok := c.Components[name]; ok {
+		return timeout
+	}
+	return c.Timeout
+}
@@ -0,0 +1,167 @@
+// Package digest builds the daily agendas of users who chose a digest
+// over reminders.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultInterval = time.Minute
+
+var tracer = tracing.Tracer("digest")
+
+// Job enqueues the agenda of the day of each digest user at the first
+// tick after their digest time in their time zone. Claiming the day and
+// adding its digest to the outbox happen atomically, so a digest is
+// enqueued once per user and day even across restarts. The scheduler
+// relays it to the sender.
+type Job struct {
+	logger   *logger.Logger
+	events   storage.Storage
+	store    storage.DigestOutbox
+	interval time.Duration
+	now      func() time.Time
+
+	// done holds the last day enqueued for each user, so agendas already
+	// sent are not built again on every tick.
+	done map[int64]string
+}
+
+func New(logg *logger.Logger,
+	events storage.Storage,
+	store storage.DigestOutbox,
+	interval time.Duration,
+) *Job {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	return &Job{
+		logger:   logg,
+		events:   events,
+		store:    store,
+		interval: interval,
+		now:      time.Now,
+		done:     make(map[int64]string),
+	}
+}
+
+// Run ticks until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick enqueues the digests due now. It is not safe for concurrent use.
+func (j *Job) Tick(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "digest tick")
+	defer span.End()
+
+	users, err := j.store.ListDigestPreferences(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "failed to list digest preferences: "+err.Error())
+		return
+	}
+
+	now := j.now()
+	enqueued := 0
+	for _, prefs := range users {
+		added, err := j.enqueue(ctx, prefs, now)
+		if err != nil {
+			// Other users still get their digests.
+			j.logger.ErrorContext(ctx, fmt.Sprintf("failed to enqueue digest of user %d: %s", prefs.UserID, err))
+			continue
+		}
+		if added {
+			enqueued++
+		}
+	}
+
+	span.SetAttributes(attribute.Int("digests.enqueued", enqueued))
+	if enqueued > 0 {
+		j.logger.DebugContext(ctx, fmt.Sprintf("enqueued %d digests", enqueued))
+	}
+}
+
+// enqueue adds the digest of the day of prefs.UserID to the outbox if it
+// is due at now. It reports whether it was added.
+func (j *Job) enqueue(ctx context.Context,
+	prefs *storage.NotificationPreferences,
+	now time.Time,
+) (added bool, err error) {
+	loc, err := time.LoadLocation(prefs.TimeZone)
+	if err != nil {
+		return false, fmt.Errorf("load time zone: %w", err)
+	}
+	digestTime, err := time.Parse(storage.TimeOfDayLayout, prefs.DigestTime)
+	if err != nil {
+		return false, fmt.Errorf("parse digest time: %w", err)
+	}
+
+	now = now.In(loc)
+	day := now.Format(storage.DigestDayLayout)
+	if last, ok := j.done[prefs.UserID]; ok && day <= last {
+		return false, nil
+	}
+
+	year, month, date := now.Date()
+	if now.Before(time.Date(year, month, date, digestTime.Hour(), digestTime.Minute(), 0, 0, loc)) {
+		return false, nil
+	}
+
+	ctx, span := tracer.Start(ctx, "enqueue digest", trace.WithAttributes(
+		attribute.Int64("user.id", prefs.UserID),
+		attribute.String("digest.day", day),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Date(year, month, date, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+	events, err := j.events.ListEvents(ctx, prefs.UserID, start, end)
+	if err != nil {
+		return false, fmt.Errorf("list events: %w", err)
+	}
+
+	agenda := newAgenda(start, end, events)
+	text, html, err := render(agenda)
+	if err != nil {
+		return false, fmt.Errorf("render digest: %w", err)
+	}
+
+	added, err = j.store.EnqueueDigest(ctx, day, storage.Notification{
+		Kind:      storage.NotificationDigest,
+		Title:     agenda.Subject,
+		StartTime: start,
+		UserID:    prefs.UserID,
+		Text:      text,
+		HTML:      html,
+		EventIDs:  agenda.eventIDs,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	j.done[prefs.UserID] = day
+	return added, nil
+}
@@ -0,0 +1,128 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJob_EnqueuesOncePerDay(t *testing.T) {
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	store := memorystorage.New().(*memorystorage.MemoryStorage)
+	ctx := context.Background()
+
+	for _, prefs := range []storage.NotificationPreferences{
+		{UserID: 1, Digest: true, DigestTime: "08:00", TimeZone: "Europe/Berlin"},
+		{UserID: 2, Digest: true, DigestTime: "08:00", TimeZone: "America/New_York"},
+		{UserID: 3, DigestTime: "08:00", TimeZone: "UTC"},
+	} {
+		prefs.Channel = storage.ChannelPush
+		require.NoError(t, store.SetNotificationPreferences(ctx, &prefs))
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, berlin)
+	for _, event := range []*storage.Event{
+		{Title: "Standup", UserID: 1, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(9*time.Hour + 15*time.Minute)},
+		{Title: "Yesterday", UserID: 1, StartTime: day.Add(-2 * time.Hour), EndTime: day},
+		{Title: "Tomorrow", UserID: 1, StartTime: day.AddDate(0, 0, 1), EndTime: day.AddDate(0, 0, 1).Add(time.Hour)},
+	} {
+		require.NoError(t, store.CreateEvent(ctx, event))
+	}
+
+	digests := func() []storage.Notification {
+		// A zero lease leaves the messages pending for the next call.
+		messages, err := store.FetchOutbox(ctx, 100, 0)
+		require.NoError(t, err)
+
+		var notifications []storage.Notification
+		for _, msg := range messages {
+			if msg.Topic != storage.TopicNotification {
+				continue
+			}
+			var notification storage.Notification
+			require.NoError(t, json.Unmarshal(msg.Payload, &notification))
+			require.Equal(t, storage.DigestKey(notification.UserID, "2025-03-10"), msg.IdempotencyKey)
+			notifications = append(notifications, notification)
+		}
+		return notifications
+	}
+	tickAt := func(job *Job, t time.Time) {
+		job.now = func() time.Time { return t }
+		job.Tick(ctx)
+	}
+
+	job := New(logg, store, store, time.Minute)
+
+	// 07:59 in Berlin.
+	tickAt(job, time.Date(2025, 3, 10, 6, 59, 0, 0, time.UTC))
+	require.Empty(t, digests())
+
+	// 08:00 in Berlin, 03:00 in New York.
+	tickAt(job, time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC))
+	sent := digests()
+	require.Len(t, sent, 1)
+	require.Equal(t, int64(1), sent[0].UserID)
+	require.Equal(t, storage.NotificationDigest, sent[0].Kind)
+	require.Equal(t, "Agenda for Monday, 10 March 2025", sent[0].Title)
+	require.True(t, day.Equal(sent[0].StartTime))
+	require.Contains(t, sent[0].Text, "09:00–09:15  Standup")
+	require.NotContains(t, sent[0].Text, "Yesterday")
+	require.NotContains(t, sent[0].Text, "Tomorrow")
+	require.Equal(t, []int64{1}, sent[0].EventIDs)
+
+	tickAt(job, time.Date(2025, 3, 10, 7, 1, 0, 0, time.UTC))
+	require.Len(t, digests(), 1)
+
+	// A restarted job finds the day claimed.
+	job = New(logg, store, store, time.Minute)
+	// 08:00 in New York.
+	tickAt(job, time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	sent = digests()
+	require.Len(t, sent, 2)
+	require.Equal(t, int64(2), sent[1].UserID)
+	require.Contains(t, sent[1].Text, "Nothing is scheduled.")
+}
+
+func TestRender(t *testing.T) {
+	start := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	at := func(hour int) time.Time { return start.Add(time.Duration(hour) * time.Hour) }
+
+	a := newAgenda(start, end, []*storage.Event{
+		{Title: "Night shift", StartTime: at(22), EndTime: at(30)},
+		{Title: "Offsite", StartTime: at(-24), EndTime: at(48)},
+		{
+			Title: "Review <draft>", StartTime: at(10), EndTime: at(11),
+			Conference: &storage.Conference{URL: "https://meet.example.com/abc"},
+		},
+		{Title: "Flight", StartTime: at(-3), EndTime: at(2)},
+	})
+	require.Equal(t, []agendaEvent{
+		{Time: "all day", Title: "Offsite"},
+		{Time: "until 02:00", Title: "Flight"},
+		{Time: "10:00–11:00", Title: "Review <draft>", ConferenceURL: "https://meet.example.com/abc"},
+		{Time: "from 22:00", Title: "Night shift"},
+	}, a.Events)
+
+	text, html, err := render(a)
+	require.NoError(t, err)
+	require.Equal(t, `Agenda for Monday, 10 March 2025
+
+all day  Offsite
+until 02:00  Flight
+10:00–11:00  Review <draft>
+       https://meet.example.com/abc
+from 22:00  Night shift
+`, text)
+	require.Contains(t, html, "<td>Review &lt;draft&gt; <a href=\"https://meet.example.com/abc\">Join</a></td>")
+}
@@ -0,0 +1,89 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"sort"
+	texttemplate "text/template"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templates, "templates/digest.txt.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/digest.html.tmpl"))
+)
+
+// agenda is the data of the digest templates.
+type agenda struct {
+	Subject string
+	Events  []agendaEvent
+	// eventIDs are the IDs of Events.
+	eventIDs []int64
+}
+
+type agendaEvent struct {
+	// Time is the part of the day the event takes, like 09:00–10:00.
+	Time          string
+	Title         string
+	ConferenceURL string
+}
+
+// newAgenda lists the events of the day from start to end, which overlap
+// it, by start time.
+func newAgenda(start, end time.Time, events []*storage.Event) agenda {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+
+	a := agenda{Subject: "Agenda for " + start.Format("Monday, 2 January 2006")}
+	for _, event := range events {
+		// The storage also returns events touching the ends of the day.
+		if !event.StartTime.Before(end) || (event.StartTime.Before(start) && !event.EndTime.After(start)) {
+			continue
+		}
+
+		item := agendaEvent{
+			Time:  eventTime(event, start, end),
+			Title: event.Title,
+		}
+		if event.Conference != nil {
+			item.ConferenceURL = event.Conference.URL
+		}
+		a.Events = append(a.Events, item)
+		a.eventIDs = append(a.eventIDs, event.ID)
+	}
+	return a
+}
+
+func eventTime(event *storage.Event, start, end time.Time) string {
+	from := event.StartTime.In(start.Location()).Format(storage.TimeOfDayLayout)
+	to := event.EndTime.In(start.Location()).Format(storage.TimeOfDayLayout)
+	switch {
+	case !event.StartTime.After(start) && !event.EndTime.Before(end):
+		return "all day"
+	case event.StartTime.Before(start):
+		return "until " + to
+	case event.EndTime.After(end):
+		return "from " + from
+	}
+	return from + "–" + to
+}
+
+// render returns the agenda as plain text and as HTML.
+func render(a agenda) (string, string, error) {
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, a); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplate.Execute(&html, a); err != nil {
+		return "", "", err
+	}
+	return text.String(), html.String(), nil
+}
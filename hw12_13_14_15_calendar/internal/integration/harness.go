@@ -0,0 +1,250 @@
+// Package integration runs the calendar services in-process so that they
+// can be driven end to end through their public APIs from tests.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/blob"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/digest"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/lifecycle"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/outbox"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/queue"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/sender"
+	internalgrpc "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/server/grpc"
+	internalhttp "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/webhook"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// SchedulerInterval is how often the harness scheduler looks for due
+// notifications.
+const SchedulerInterval = 20 * time.Millisecond
+
+// Harness is a running calendar: the HTTP and gRPC servers, the
+// scheduler, the sender, the digest job and the webhook dispatcher over
+// memory storage, an in-memory queue and a blob store in a temporary
+// directory.
+type Harness struct {
+	// URL is the base URL of the HTTP server, e.g. http://127.0.0.1:41234.
+	URL    string
+	Client *http.Client
+	// GRPC is a client of the gRPC server. Calls name their user with
+	// AsUser.
+	GRPC eventpb.EventServiceClient
+
+	App       *app.App
+	Storage   *memorystorage.MemoryStorage
+	Queue     *queue.MemoryQueue
+	Scheduler *scheduler.Scheduler
+	Digest    *digest.Job
+
+	mu   sync.Mutex
+	sent []storage.Notification
+}
+
+// Start starts the services on ephemeral ports. configure may adjust
+// the default configuration first. Everything is stopped when the test
+// finishes.
+func Start(t testing.TB, configure ...func(*config.Config)) *Harness {
+	t.Helper()
+
+	conf := config.GetDefaultConfig()
+	conf.Components.Server.Listener.Host = "127.0.0.1"
+	conf.Components.Server.RateLimit.Enabled = false
+	conf.Components.GRPC.Listener.Host = "127.0.0.1"
+	conf.Components.Webhooks.MaxBackoff = conf.Components.Webhooks.InitialBackoff
+	// Webhook receivers of tests listen on loopback.
+	conf.Components.Webhooks.AllowPrivateNetworks = true
+	conf.Components.Attachments.Dir = t.TempDir()
+	for _, fn := range configure {
+		fn(conf)
+	}
+
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+
+	store := memorystorage.New().(*memorystorage.MemoryStorage)
+	calendar := app.New(logg, store)
+
+	blobs, err := blob.NewLocalStore(conf.Components.Attachments.Dir)
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+	calendar.UseBlobStore(blobs, conf.Components.Attachments.MaxSize)
+
+	h := &Harness{
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		App:     calendar,
+		Storage: store,
+		Queue:   queue.NewMemoryQueue(0),
+	}
+
+	notificationSender := sender.New(logg, conf.Components.Sender.DedupSize)
+	notificationSender.OnSend(func(_ context.Context, notification storage.Notification) {
+		h.mu.Lock()
+		h.sent = append(h.sent, notification)
+		h.mu.Unlock()
+	})
+	notificationSender.AddChannel(storage.ChannelPush, calendar.Push)
+	notificationSender.UseStore(store)
+
+	relay := outbox.NewRelay(logg, store, h.Queue, conf.Components.Scheduler.BatchSize,
+		time.Duration(conf.Components.Scheduler.Lease)*time.Second)
+	h.Queue.Subscribe(storage.TopicNotification, relay.Acknowledge(notificationSender.Handle))
+	h.Scheduler = scheduler.New(logg, store, relay, SchedulerInterval)
+	h.Digest = digest.New(logg, store, store, SchedulerInterval)
+
+	dispatcher := webhook.NewDispatcher(logg, store, conf.Components.Webhooks)
+	calendar.OnChange(dispatcher.HandleChange)
+
+	server := internalhttp.NewServer(logg, *calendar, conf.Components.Server)
+
+	l, err := net.Listen("tcp", net.JoinHostPort(conf.Components.Server.Listener.Host, "0"))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	h.URL = "http://" + l.Addr().String()
+
+	grpcServer, err := internalgrpc.NewServer(logg, *calendar, conf.Components.GRPC)
+	if err != nil {
+		t.Fatalf("create gRPC server: %v", err)
+	}
+	grpcListener, err := net.Listen("tcp", net.JoinHostPort(conf.Components.GRPC.Listener.Host, "0"))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	conn, err := grpc.NewClient(grpcListener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial gRPC server: %v", err)
+	}
+	h.GRPC = eventpb.NewEventServiceClient(conn)
+
+	components := lifecycle.New(logg)
+	components.Add(lifecycle.Component{Name: "storage", Stop: func(context.Context) error { return store.Close() }})
+	components.Add(lifecycle.Component{Name: "queue", Run: h.Queue.Run})
+	components.Add(lifecycle.Component{Name: "scheduler", Run: func(ctx context.Context) error {
+		h.Scheduler.Run(ctx)
+		return nil
+	}})
+	components.Add(lifecycle.Component{Name: "digest", Run: func(ctx context.Context) error {
+		h.Digest.Run(ctx)
+		return nil
+	}})
+	components.Add(lifecycle.Component{Name: "webhooks", Run: func(ctx context.Context) error {
+		dispatcher.Run(ctx)
+		return nil
+	}})
+	components.Add(lifecycle.Component{
+		Name: "http",
+		Run:  func(ctx context.Context) error { return server.Serve(ctx, l) },
+		Stop: server.Stop,
+	})
+	components.Add(lifecycle.Component{
+		Name: "grpc",
+		Run:  func(ctx context.Context) error { return grpcServer.Serve(ctx, grpcListener) },
+		Stop: grpcServer.Stop,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- components.Run(ctx) }()
+
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("stop: %v", err)
+		}
+	})
+
+	return h
+}
+
+// Notifications returns the notifications sent so far.
+func (h *Harness) Notifications() []storage.Notification {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]storage.Notification(nil), h.sent...)
+}
+
+// AsUser returns ctx naming userID to the gRPC server.
+func AsUser(ctx context.Context, userID int64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, internalgrpc.UserIDMetadata, strconv.FormatInt(userID, 10))
+}
+
+// Do sends a request as userID. A non-nil body is encoded as JSON and a
+// non-nil out is decoded from the response. It returns the status code.
+func (h *Harness) Do(ctx context.Context, method, path string, userID int64, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := h.NewRequest(ctx, method, path, userID, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < http.StatusBadRequest {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// NewRequest builds a request to the server as userID. A zero userID
+// sends no user header.
+func (h *Harness) NewRequest(ctx context.Context,
+	method, path string,
+	userID int64,
+	body io.Reader,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.URL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if userID != 0 {
+		req.Header.Set(internalhttp.UserIDHeader, strconv.FormatInt(userID, 10))
+	}
+
+	return req, nil
+}
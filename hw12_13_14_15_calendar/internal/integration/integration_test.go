@@ -0,0 +1,332 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/webhook"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type streamEvent struct {
+	Type   string
+	Change app.Change
+}
+
+// readStream sends the events of an SSE stream to the returned channel
+// until the body is closed.
+func readStream(body io.Reader) <-chan streamEvent {
+	events := make(chan streamEvent, 16)
+	go func() {
+		defer close(events)
+
+		var event streamEvent
+		var data string
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "" && event.Type != "":
+				if json.Unmarshal([]byte(data), &event.Change) == nil {
+					events <- event
+				}
+				event, data = streamEvent{}, ""
+			case strings.HasPrefix(line, "event: "):
+				event.Type = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+type delivery struct {
+	Event string
+	Body  []byte
+	Valid bool
+}
+
+func TestNotificationFlow(t *testing.T) {
+	h := Start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const (
+		userID = int64(1)
+		secret = "integration-secret"
+	)
+
+	deliveries := make(chan delivery, 16)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{
+			Event: r.Header.Get(webhook.EventHeader),
+			Body:  body,
+			Valid: webhook.Verify(secret, body, r.Header.Get(webhook.TimestampHeader),
+				r.Header.Get(webhook.SignatureHeader), webhook.DefaultTolerance),
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	var hook storage.Webhook
+	code, err := h.Do(ctx, http.MethodPost, "/webhooks", userID,
+		map[string]string{"url": receiver.URL, "secret": secret}, &hook)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, code)
+
+	req, err := h.NewRequest(ctx, http.MethodGet, "/events/stream", userID, nil)
+	require.NoError(t, err)
+	resp, err := h.Client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	stream := readStream(resp.Body)
+
+	start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	batch := map[string]interface{}{
+		"atomic": true,
+		"operations": []map[string]interface{}{{
+			"op": storage.BatchCreate,
+			"event": storage.Event{
+				Title:     "Standup",
+				StartTime: start,
+				EndTime:   start.Add(15 * time.Minute),
+				NotifyAt:  time.Now().Add(-time.Minute),
+			},
+		}},
+	}
+	var result struct {
+		Results []struct {
+			Event *storage.Event `json:"event"`
+		} `json:"results"`
+		Failed int `json:"failed"`
+	}
+	code, err = h.Do(ctx, http.MethodPost, "/events/batch", userID, batch, &result)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Zero(t, result.Failed)
+	require.Len(t, result.Results, 1)
+	eventID := result.Results[0].Event.ID
+
+	t.Run("stream", func(t *testing.T) {
+		var types []string
+		for len(types) < 2 {
+			select {
+			case event, ok := <-stream:
+				require.True(t, ok, "stream closed")
+				require.Equal(t, eventID, event.Change.Event.ID)
+				types = append(types, event.Type)
+			case <-ctx.Done():
+				t.Fatalf("timed out, got %v", types)
+			}
+		}
+		require.Equal(t, []string{app.ChangeCreated, app.ChangeReminder}, types)
+	})
+
+	t.Run("sender", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return len(h.Notifications()) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		sent := h.Notifications()
+		require.Len(t, sent, 1)
+		require.Equal(t, eventID, sent[0].EventID)
+		require.Equal(t, "Standup", sent[0].Title)
+		require.True(t, start.Equal(sent[0].StartTime))
+
+		var history []storage.NotificationDelivery
+		code, err := h.Do(ctx, http.MethodGet,
+			"/notifications/history?event="+strconv.FormatInt(eventID, 10), userID, nil, &history)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, history, 1)
+		require.Equal(t, storage.ChannelPush, history[0].Channel)
+		require.Equal(t, storage.DeliverySent, history[0].Status)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		received := map[string]bool{}
+		for !received[app.ChangeReminder] {
+			select {
+			case d := <-deliveries:
+				require.True(t, d.Valid, "bad signature on %s", d.Event)
+				received[d.Event] = true
+			case <-ctx.Done():
+				t.Fatalf("timed out, got %v", received)
+			}
+		}
+		require.True(t, received[app.ChangeCreated])
+
+		require.Eventually(t, func() bool {
+			var log []storage.WebhookDelivery
+			code, err := h.Do(ctx, http.MethodGet,
+				"/webhooks/"+strconv.FormatInt(hook.ID, 10)+"/deliveries", userID, nil, &log)
+			return err == nil && code == http.StatusOK && len(log) == 2 && log[0].Success && log[1].Success
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("reminded once", func(t *testing.T) {
+		time.Sleep(5 * SchedulerInterval)
+		require.Len(t, h.Notifications(), 1)
+	})
+}
+
+// TestGRPCNotificationFlow creates an event over gRPC and follows it to
+// the sender and the HTTP API.
+func TestGRPCNotificationFlow(t *testing.T) {
+	h := Start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const userID = int64(1)
+
+	start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	created, err := h.GRPC.CreateEvent(AsUser(ctx, userID), &eventpb.CreateEventRequest{Event: &eventpb.Event{
+		Title:     "Retro",
+		StartTime: timestamppb.New(start),
+		EndTime:   timestamppb.New(start.Add(time.Hour)),
+		NotifyAt:  timestamppb.New(time.Now().Add(-time.Minute)),
+	}})
+	require.NoError(t, err)
+	require.Equal(t, userID, created.GetUserId())
+
+	t.Run("sender", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return len(h.Notifications()) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		sent := h.Notifications()
+		require.Len(t, sent, 1)
+		require.Equal(t, created.GetId(), sent[0].EventID)
+		require.Equal(t, "Retro", sent[0].Title)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		var event storage.Event
+		code, err := h.Do(ctx, http.MethodGet, "/events/"+strconv.FormatInt(created.GetId(), 10), userID, nil, &event)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "Retro", event.Title)
+		require.True(t, start.Equal(event.StartTime))
+	})
+
+	t.Run("search", func(t *testing.T) {
+		resp, err := h.GRPC.SearchEvents(AsUser(ctx, userID), &eventpb.SearchEventsRequest{Query: "retro"})
+		require.NoError(t, err)
+		require.Len(t, resp.GetResults(), 1)
+		require.Equal(t, created.GetId(), resp.GetResults()[0].GetEvent().GetId())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := h.GRPC.DeleteEvent(AsUser(ctx, userID), &eventpb.DeleteEventRequest{Id: created.GetId()})
+		require.NoError(t, err)
+
+		resp, err := h.GRPC.ListEvents(AsUser(ctx, userID), &eventpb.ListEventsRequest{
+			From: timestamppb.New(start.Add(-time.Hour)),
+			To:   timestamppb.New(start.Add(2 * time.Hour)),
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.GetEvents())
+	})
+}
+
+func TestUnauthenticated(t *testing.T) {
+	h := Start(t)
+
+	code, err := h.Do(context.Background(), http.MethodGet, "/events/search?q=x", 0, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	_, err = h.GRPC.SearchEvents(context.Background(), &eventpb.SearchEventsRequest{Query: "x"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestDailyDigest(t *testing.T) {
+	h := Start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const userID = int64(1)
+
+	req, err := h.NewRequest(ctx, http.MethodGet, "/events/stream", userID, nil)
+	require.NoError(t, err)
+	resp, err := h.Client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	stream := readStream(resp.Body)
+
+	// The event exists before the digest is due.
+	start := time.Now().UTC().Truncate(time.Second)
+	batch := map[string]interface{}{
+		"atomic": true,
+		"operations": []map[string]interface{}{{
+			"op":    storage.BatchCreate,
+			"event": storage.Event{Title: "Retro", StartTime: start, EndTime: start.Add(time.Minute)},
+		}},
+	}
+	code, err := h.Do(ctx, http.MethodPost, "/events/batch", userID, batch, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+
+	code, err = h.Do(ctx, http.MethodPut, "/notifications/preferences", userID,
+		map[string]interface{}{"digest": true, "digest_time": "00:00"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+
+	t.Run("pushed", func(t *testing.T) {
+		for {
+			select {
+			case event := <-stream:
+				if event.Type != app.ChangeDigest {
+					continue
+				}
+				require.NotNil(t, event.Change.Digest)
+				require.Contains(t, event.Change.Digest.Text, "Retro")
+				require.Contains(t, event.Change.Digest.HTML, "<td>Retro</td>")
+				require.Equal(t, userID, event.Change.Event.UserID)
+				return
+			case <-ctx.Done():
+				t.Fatal("timed out waiting for the digest")
+			}
+		}
+	})
+
+	t.Run("once a day", func(t *testing.T) {
+		time.Sleep(5 * SchedulerInterval)
+
+		digests := 0
+		for _, notification := range h.Notifications() {
+			if notification.Kind == storage.NotificationDigest {
+				digests++
+			}
+		}
+		require.Equal(t, 1, digests)
+
+		var history []storage.NotificationDelivery
+		code, err := h.Do(ctx, http.MethodGet, "/notifications/history", userID, nil, &history)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, history, 1)
+		require.Equal(t, storage.DigestKey(userID, start.Format(storage.DigestDayLayout)), history[0].Key)
+		require.Equal(t, storage.DeliverySent, history[0].Status)
+	})
+}
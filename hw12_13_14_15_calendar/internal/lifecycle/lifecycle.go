@@ -0,0 +1,147 @@
+// Package lifecycle starts the components of the service in dependency
+// order and stops them in reverse order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Component is a part of the service. Every function is optional.
+type Component struct {
+	Name string
+	// Start prepares the component, e.g. binds a listener. Components
+	// are not run until every Start has returned.
+	Start func(ctx context.Context) error
+	// Run runs the component until its context is canceled or Stop is
+	// called, and then returns nil. An error shuts the service down.
+	Run func(ctx context.Context) error
+	// Stop releases the component. The context of Run is canceled after
+	// it returns.
+	Stop func(ctx context.Context) error
+	// Timeout bounds Stop and the return of Run. Zero means ten seconds.
+	Timeout time.Duration
+}
+
+// Manager runs components.
+type Manager struct {
+	logger     *logger.Logger
+	components []Component
+}
+
+func New(logg *logger.Logger) *Manager {
+	return &Manager{logger: logg}
+}
+
+// Add adds a component that depends on the components added before it.
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// running is a component whose Run was called.
+type running struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Run starts the components, runs them until ctx is done or one of them
+// fails, and stops the started ones in reverse order. The returned error
+// joins the errors of every component.
+func (m *Manager) Run(ctx context.Context) error {
+	var errs []error
+	started := 0
+	for _, c := range m.components {
+		if c.Start == nil {
+			started++
+			continue
+		}
+		if err := c.Start(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("start %s: %w", c.Name, err))
+			break
+		}
+		started++
+	}
+
+	runs := make([]*running, started)
+	failed := make(chan string, started)
+	if len(errs) == 0 {
+		for i, c := range m.components {
+			if c.Run != nil {
+				runs[i] = m.run(c, failed)
+			}
+		}
+		m.logger.Info("all components started")
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("shutting down")
+		case name := <-failed:
+			m.logger.Error(fmt.Sprintf("%s failed, shutting down", name))
+		}
+	}
+
+	for i := started - 1; i >= 0; i-- {
+		if err := m.stop(m.components[i], runs[i]); err != nil {
+			m.logger.Error(err.Error())
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) run(c Component, failed chan<- string) *running {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &running{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(r.done)
+		r.err = c.Run(ctx)
+		if r.err != nil && ctx.Err() == nil {
+			failed <- c.Name
+		}
+	}()
+	return r
+}
+
+// stop stops c within its timeout. r is nil if c has no Run.
+func (m *Manager) stop(c Component, r *running) error {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	m.logger.Info("stopping " + c.Name)
+	var errs []error
+	if c.Stop != nil {
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if r != nil {
+		r.cancel()
+		select {
+		case <-r.done:
+			if r.err != nil {
+				errs = append(errs, r.err)
+			}
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("did not stop within %s", timeout))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the calls made to components.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) component(name string) Component {
+	return Component{
+		Name: name,
+		Start: func(context.Context) error {
+			r.record("start " + name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			r.record("stop " + name)
+			return nil
+		},
+	}
+}
+
+func newManager(t *testing.T) *Manager {
+	t.Helper()
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+	return New(logg)
+}
+
+func TestManager(t *testing.T) {
+	t.Run("stops in reverse order", func(t *testing.T) {
+		r := &recorder{}
+		m := newManager(t)
+		m.Add(r.component("storage"))
+		server := r.component("http")
+		server.Run = func(ctx context.Context) error {
+			<-ctx.Done()
+			r.record("http returned")
+			return nil
+		}
+		m.Add(server)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.NoError(t, m.Run(ctx))
+		require.Equal(t, []string{"start storage", "start http", "stop http", "http returned", "stop storage"}, r.calls)
+	})
+
+	t.Run("failed run shuts down", func(t *testing.T) {
+		r := &recorder{}
+		m := newManager(t)
+		m.Add(r.component("storage"))
+		failing := r.component("queue")
+		failing.Run = func(context.Context) error { return errors.New("broken") }
+		m.Add(failing)
+
+		err := m.Run(context.Background())
+		require.EqualError(t, err, "queue: broken")
+		require.Equal(t, []string{"start storage", "start queue", "stop queue", "stop storage"}, r.calls)
+	})
+
+	t.Run("failed start stops started components", func(t *testing.T) {
+		r := &recorder{}
+		m := newManager(t)
+		m.Add(r.component("storage"))
+		failing := r.component("http")
+		failing.Start = func(context.Context) error { return errors.New("address in use") }
+		m.Add(failing)
+		m.Add(r.component("never"))
+
+		err := m.Run(context.Background())
+		require.EqualError(t, err, "start http: address in use")
+		require.Equal(t, []string{"start storage", "stop storage"}, r.calls)
+	})
+
+	t.Run("reports every error and deadline", func(t *testing.T) {
+		stuck := make(chan struct{})
+		t.Cleanup(func() { close(stuck) })
+
+		m := newManager(t)
+		m.Add(Component{Name: "storage", Stop: func(context.Context) error { return errors.New("close failed") }})
+		m.Add(Component{
+			Name: "webhooks",
+			Run: func(context.Context) error {
+				<-stuck
+				return nil
+			},
+			Timeout: 10 * time.Millisecond,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := m.Run(ctx)
+		require.EqualError(t, err, "webhooks: did not stop within 10ms\nstorage: close failed")
+	})
+}
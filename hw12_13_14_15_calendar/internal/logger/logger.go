@@ -1,12 +1,16 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -33,6 +37,7 @@ var levels = map[LogLevel]int{
 }
 
 type Logger struct {
+	mu     sync.RWMutex
 	format LogFormat
 	level  LogLevel
 	writer io.Writer
@@ -50,6 +55,16 @@ type LogRecord struct {
 	Latency     float64   `json:"latency_ms,omitempty"`
 	UserAgent   string    `json:"user_agent,omitempty"`
 	Message     string    `json:"message,omitempty"`
+	TraceID     string    `json:"trace_id,omitempty"`
+	SpanID      string    `json:"span_id,omitempty"`
+}
+
+// WithTrace fills TraceID and SpanID from the span in ctx, if any.
+func (r *LogRecord) WithTrace(ctx context.Context) {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.TraceID = sc.TraceID().String()
+		r.SpanID = sc.SpanID().String()
+	}
 }
 
 func NewLogger(outputPath string, level string, format LogFormat) (*Logger, error) {
@@ -79,6 +94,20 @@ func NewLogger(outputPath string, level string, format LogFormat) (*Logger, erro
 	}, nil
 }
 
+// SetLevel changes the minimal level of logged messages.
+func (l *Logger) SetLevel(level string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = LogLevel(strings.ToLower(level))
+}
+
+// SetFormat changes the format of subsequent records.
+func (l *Logger) SetFormat(format LogFormat) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = format
+}
+
 func (l *Logger) Close() error {
 	if l.file != nil {
 		return l.file.Close()
@@ -87,7 +116,11 @@ func (l *Logger) Close() error {
 }
 
 func (l *Logger) write(record LogRecord) error {
-	if l.format == JSONFormat {
+	l.mu.RLock()
+	format := l.format
+	l.mu.RUnlock()
+
+	if format == JSONFormat {
 		encoder := json.NewEncoder(l.writer)
 		return encoder.Encode(record)
 	}
@@ -96,7 +129,7 @@ func (l *Logger) write(record LogRecord) error {
 	var msg string
 	if record.ClientIP != "" {
 		// HTTP request log.
-		msg = fmt.Sprintf("%s | %s | %s | %s %s %s | %d | %.2fms | %s | %s\n",
+		msg = fmt.Sprintf("%s | %s | %s | %s %s %s | %d | %.2fms | %s | %s",
 			record.Timestamp.Format(time.RFC3339),
 			record.Level,
 			record.ClientIP,
@@ -108,13 +141,21 @@ func (l *Logger) write(record LogRecord) error {
 			record.UserAgent,
 			record.Message,
 		)
+		if record.TraceID != "" {
+			msg += fmt.Sprintf(" | trace=%s span=%s", record.TraceID, record.SpanID)
+		}
+		msg += "\n"
 	} else {
 		// Simple application log.
-		msg = fmt.Sprintf("%s | %s | %s\n",
+		msg = fmt.Sprintf("%s | %s | %s",
 			record.Timestamp.Format(time.RFC3339),
 			record.Level,
 			record.Message,
 		)
+		if record.TraceID != "" {
+			msg += fmt.Sprintf(" | trace=%s span=%s", record.TraceID, record.SpanID)
+		}
+		msg += "\n"
 	}
 
 	_, err := fmt.Fprint(l.writer, msg)
@@ -123,10 +164,12 @@ func (l *Logger) write(record LogRecord) error {
 
 // shouldLog determines if the message should be logged based on level.
 func (l *Logger) shouldLog(msgLevel LogLevel) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return levels[msgLevel] >= levels[l.level]
 }
 
-func (l *Logger) log(level LogLevel, msg string) {
+func (l *Logger) log(ctx context.Context, level LogLevel, msg string) {
 	if !l.shouldLog(level) {
 		return
 	}
@@ -136,6 +179,7 @@ func (l *Logger) log(level LogLevel, msg string) {
 		Level:     string(level),
 		Message:   msg,
 	}
+	record.WithTrace(ctx)
 
 	if err := l.write(record); err != nil {
 		// If we can't log to our configured writer, fall back to stderr.
@@ -145,22 +189,42 @@ func (l *Logger) log(level LogLevel, msg string) {
 
 // Debug logs a debug message.
 func (l *Logger) Debug(msg string) {
-	l.log(DebugLevel, msg)
+	l.log(context.Background(), DebugLevel, msg)
 }
 
 // Info logs an info message.
 func (l *Logger) Info(msg string) {
-	l.log(InfoLevel, msg)
+	l.log(context.Background(), InfoLevel, msg)
 }
 
 // Warn logs a warning message.
 func (l *Logger) Warn(msg string) {
-	l.log(WarnLevel, msg)
+	l.log(context.Background(), WarnLevel, msg)
 }
 
 // Error logs an error message.
 func (l *Logger) Error(msg string) {
-	l.log(ErrorLevel, msg)
+	l.log(context.Background(), ErrorLevel, msg)
+}
+
+// DebugContext logs a debug message with the trace of the span in ctx.
+func (l *Logger) DebugContext(ctx context.Context, msg string) {
+	l.log(ctx, DebugLevel, msg)
+}
+
+// InfoContext logs an info message with the trace of the span in ctx.
+func (l *Logger) InfoContext(ctx context.Context, msg string) {
+	l.log(ctx, InfoLevel, msg)
+}
+
+// WarnContext logs a warning message with the trace of the span in ctx.
+func (l *Logger) WarnContext(ctx context.Context, msg string) {
+	l.log(ctx, WarnLevel, msg)
+}
+
+// ErrorContext logs an error message with the trace of the span in ctx.
+func (l *Logger) ErrorContext(ctx context.Context, msg string) {
+	l.log(ctx, ErrorLevel, msg)
 }
 
 // LogRequest logs an HTTP request.
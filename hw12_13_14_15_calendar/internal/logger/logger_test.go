@@ -1,7 +1,62 @@
 package logger
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
 
-func TestLogger(t *testing.T) {
-	// TODO
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogger_Context(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	t.Run("text", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "calendar.log")
+		logg, err := NewLogger(path, "debug", TextFormat)
+		require.NoError(t, err)
+		defer logg.Close()
+
+		logg.InfoContext(ctx, "sent")
+		logg.Info("started")
+		logg.DebugContext(context.Background(), "idle")
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		require.Len(t, lines, 3)
+		require.True(t, strings.HasSuffix(lines[0], "| info | sent | trace="+traceID.String()+" span="+spanID.String()))
+		require.True(t, strings.HasSuffix(lines[1], "| info | started"))
+		require.True(t, strings.HasSuffix(lines[2], "| debug | idle"))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "calendar.log")
+		logg, err := NewLogger(path, "warn", JSONFormat)
+		require.NoError(t, err)
+		defer logg.Close()
+
+		logg.InfoContext(ctx, "filtered")
+		logg.ErrorContext(ctx, "failed")
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var record LogRecord
+		require.NoError(t, json.Unmarshal(data, &record))
+		require.Equal(t, "failed", record.Message)
+		require.Equal(t, traceID.String(), record.TraceID)
+		require.Equal(t, spanID.String(), record.SpanID)
+	})
 }
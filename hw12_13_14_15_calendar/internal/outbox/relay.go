@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/queue"
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultLease     = time.Minute
+)
+
+// OutboxIDHeader names the outbox message a queue message was relayed
+// from.
+const OutboxIDHeader = "outbox-id"
+
+// Relay publishes outbox messages to the queue. A message stays in the
+// outbox until its consumer acknowledges it through a handler wrapped by
+// Acknowledge; until then it is claimed for a lease and relayed again
+// once the lease has passed. Messages lost by the queue or by a crash are
+// thus relayed again, so delivery is at least once and consumers
+// deduplicate on the message key.
+type Relay struct {
+	logger    *logger.Logger
+	store     storage.Outbox
+	publisher queue.Publisher
+	batchSize int
+	lease     time.Duration
+}
+
+func NewRelay(logg *logger.Logger,
+	store storage.Outbox,
+	publisher queue.Publisher,
+	batchSize int,
+	lease time.Duration,
+) *Relay {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if lease <= 0 {
+		lease = defaultLease
+	}
+
+	return &Relay{
+		logger:    logg,
+		store:     store,
+		publisher: publisher,
+		batchSize: batchSize,
+		lease:     lease,
+	}
+}
+
+// Flush publishes pending messages until none is left unclaimed and
+// returns the number published.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := r.relayBatch(ctx)
+		total += n
+		if err != nil || n < r.batchSize {
+			return total, err
+		}
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	messages, err := r.store.FetchOutbox(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range messages {
+		// Stop at the first failure; the rest of the batch is relayed
+		// when its lease has passed.
+		if err := r.publisher.Publish(ctx, queue.Message{
+			Topic:   msg.Topic,
+			Key:     msg.IdempotencyKey,
+			Headers: map[string]string{OutboxIDHeader: strconv.FormatInt(msg.ID, 10)},
+			Body:    msg.Payload,
+		}); err != nil {
+			return i, fmt.Errorf("publish outbox message %d: %w", msg.ID, err)
+		}
+	}
+
+	return len(messages), nil
+}
+
+// Acknowledge wraps handler to delete the outbox message of every queue
+// message it handled. A failed deletion is returned, so the message is
+// delivered again and acknowledged by the duplicate. A message the
+// handler deferred stays in the outbox and is relayed again at the time
+// it asked for.
+func (r *Relay) Acknowledge(handler queue.Handler) queue.Handler {
+	return func(ctx context.Context, msg queue.Message) error {
+		handleErr := handler(ctx, msg)
+		var deferErr *queue.DeferError
+		if handleErr != nil && !errors.As(handleErr, &deferErr) {
+			r.logger.WarnContext(ctx, fmt.Sprintf("failed to handle message %s: %s", msg.Key, handleErr))
+			return handleErr
+		}
+
+		id, err := strconv.ParseInt(msg.Headers[OutboxIDHeader], 10, 64)
+		if err != nil {
+			// The message was not relayed from the outbox.
+			return handleErr
+		}
+		if deferErr != nil {
+			if err := r.store.DeferOutbox(ctx, id, deferErr.Until); err != nil {
+				r.logger.ErrorContext(ctx, fmt.Sprintf("failed to defer outbox message %d: %s", id, err))
+				return fmt.Errorf("defer outbox message %d: %w", id, err)
+			}
+			r.logger.DebugContext(ctx, fmt.Sprintf("deferred outbox message %d until %s", id, deferErr.Until))
+			return nil
+		}
+		if err := r.store.DeleteOutbox(ctx, []int64{id}); err != nil {
+			r.logger.ErrorContext(ctx, fmt.Sprintf("failed to acknowledge outbox message %d: %s", id, err))
+			return fmt.Errorf("acknowledge outbox message %d: %w", id, err)
+		}
+		return nil
+	}
+}
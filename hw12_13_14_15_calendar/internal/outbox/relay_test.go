@@ -0,0 +1,145 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/queue"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher struct {
+	messages []queue.Message
+	failAt   int
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, msg queue.Message) error {
+	if p.failAt > 0 && len(p.messages)+1 == p.failAt {
+		p.failAt = 0
+		return errors.New("queue unavailable")
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+	return logg
+}
+
+func TestRelay_Flush(t *testing.T) {
+	ctx := context.Background()
+	store := memorystorage.New()
+	eventOutbox, ok := storage.As[storage.Outbox](store)
+	require.True(t, ok)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.CreateEvent(ctx, &storage.Event{
+			Title:     "Event",
+			UserID:    1,
+			StartTime: now.Add(time.Hour),
+			EndTime:   now.Add(2 * time.Hour),
+			NotifyAt:  now.Add(time.Duration(i-10) * time.Minute),
+		}))
+	}
+	n, err := eventOutbox.EnqueueDueNotifications(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	const lease = 50 * time.Millisecond
+	publisher := &recordingPublisher{failAt: 4}
+	relay := NewRelay(newTestLogger(t), eventOutbox, publisher, 2, lease)
+	acknowledge := relay.Acknowledge(func(context.Context, queue.Message) error { return nil })
+	fail := relay.Acknowledge(func(context.Context, queue.Message) error { return errors.New("not delivered") })
+
+	t.Run("stops at a failed publish", func(t *testing.T) {
+		n, err := relay.Flush(ctx)
+		require.Error(t, err)
+		require.Equal(t, 3, n)
+	})
+
+	t.Run("skips claimed messages", func(t *testing.T) {
+		n, err := relay.Flush(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		require.Len(t, publisher.messages, 4)
+
+		keys := make(map[string]bool)
+		for _, msg := range publisher.messages {
+			require.Equal(t, storage.TopicNotification, msg.Topic)
+			require.NotEmpty(t, msg.Headers[OutboxIDHeader])
+			require.False(t, keys[msg.Key])
+			keys[msg.Key] = true
+		}
+	})
+
+	t.Run("relays unacknowledged messages after the lease", func(t *testing.T) {
+		for _, msg := range publisher.messages[:3] {
+			require.NoError(t, acknowledge(ctx, msg))
+		}
+		require.Error(t, fail(ctx, publisher.messages[3]))
+
+		time.Sleep(lease)
+		publisher.messages = nil
+		n, err := relay.Flush(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		for _, msg := range publisher.messages {
+			require.NoError(t, acknowledge(ctx, msg))
+		}
+
+		pending, err := eventOutbox.FetchOutbox(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Empty(t, pending)
+	})
+}
+
+func TestRelay_Defer(t *testing.T) {
+	ctx := context.Background()
+	store := memorystorage.New()
+	eventOutbox, ok := storage.As[storage.Outbox](store)
+	require.True(t, ok)
+
+	now := time.Now()
+	require.NoError(t, store.CreateEvent(ctx, &storage.Event{
+		Title:     "Event",
+		UserID:    1,
+		StartTime: now.Add(time.Hour),
+		EndTime:   now.Add(2 * time.Hour),
+		NotifyAt:  now.Add(-time.Minute),
+	}))
+	_, err := eventOutbox.EnqueueDueNotifications(ctx, now)
+	require.NoError(t, err)
+
+	publisher := &recordingPublisher{}
+	relay := NewRelay(newTestLogger(t), eventOutbox, publisher, 10, time.Millisecond)
+	until := time.Now().Add(100 * time.Millisecond)
+	deferUntil := relay.Acknowledge(func(context.Context, queue.Message) error {
+		return &queue.DeferError{Until: until}
+	})
+
+	_, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	require.Len(t, publisher.messages, 1)
+	require.NoError(t, deferUntil(ctx, publisher.messages[0]))
+
+	// The lease has passed, but the message is held until it is due.
+	time.Sleep(10 * time.Millisecond)
+	n, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	time.Sleep(time.Until(until))
+	n, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, publisher.messages[0].Key, publisher.messages[1].Key)
+}
@@ -0,0 +1,160 @@
+package pubsub
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+)
+
+const (
+	defaultLogSize    = 1000
+	defaultBufferSize = 64
+
+	// epochMask masks the position of an entry within its epoch.
+	epochMask = 1<<32 - 1
+)
+
+// Entry is a change with its position in the change log. The upper 32
+// bits of its ID are the epoch of the broker that published it.
+type Entry struct {
+	ID     uint64
+	Change app.Change
+}
+
+// Broker fans changes out to the subscriptions of their owners and keeps
+// the latest changes so a reconnecting subscriber can resume.
+type Broker struct {
+	mu sync.Mutex
+	// epoch is random for every broker, so IDs published before a restart
+	// are not mistaken for IDs in its log.
+	epoch  uint64
+	lastID uint64
+	// log is a ring of the latest entries; dropped is the ID of the last
+	// entry that fell out of it.
+	log     []Entry
+	start   int
+	dropped uint64
+
+	bufferSize int
+	subs       map[int64]map[*Subscription]struct{}
+}
+
+// Subscription receives the changes of a user. C is closed when the
+// subscriber falls too far behind; it can resume from the last entry seen.
+type Subscription struct {
+	C <-chan Entry
+
+	c      chan Entry
+	userID int64
+	broker *Broker
+	closed bool
+}
+
+func NewBroker(logSize, bufferSize int) *Broker {
+	if logSize <= 0 {
+		logSize = defaultLogSize
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	epoch := newEpoch()
+	return &Broker{
+		epoch:      epoch,
+		lastID:     epoch,
+		dropped:    epoch,
+		log:        make([]Entry, 0, logSize),
+		bufferSize: bufferSize,
+		subs:       make(map[int64]map[*Subscription]struct{}),
+	}
+}
+
+// newEpoch returns a nonzero epoch in the upper 32 bits.
+func newEpoch() uint64 {
+	var epoch uint32
+	for epoch == 0 {
+		epoch = rand.Uint32()
+	}
+	return uint64(epoch) << 32
+}
+
+// Publish adds change to the log and sends it to the subscribers of the
+// event owner. It is an app.ChangeHandler and never blocks.
+func (b *Broker) Publish(_ context.Context, change app.Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	entry := Entry{ID: b.lastID, Change: change}
+	if len(b.log) < cap(b.log) {
+		b.log = append(b.log, entry)
+	} else {
+		b.dropped = b.log[b.start].ID
+		b.log[b.start] = entry
+		b.start = (b.start + 1) % len(b.log)
+	}
+
+	for sub := range b.subs[change.Event.UserID] {
+		select {
+		case sub.c <- entry:
+		default:
+			b.unsubscribe(sub)
+		}
+	}
+}
+
+// Subscribe subscribes to the changes of userID. The changes after lastID
+// still in the log are returned as backlog; complete is false if some of
+// them were already dropped, or lastID is unknown to the broker, as IDs
+// from another epoch are.
+func (b *Broker) Subscribe(userID int64, lastID uint64) (sub *Subscription, backlog []Entry, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = lastID&^epochMask == b.epoch && lastID >= b.dropped && lastID <= b.lastID
+	for i := 0; i < len(b.log); i++ {
+		entry := b.log[(b.start+i)%len(b.log)]
+		if entry.ID > lastID && entry.Change.Event.UserID == userID {
+			backlog = append(backlog, entry)
+		}
+	}
+
+	c := make(chan Entry, b.bufferSize)
+	sub = &Subscription{C: c, c: c, userID: userID, broker: b}
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*Subscription]struct{})
+	}
+	b.subs[userID][sub] = struct{}{}
+
+	return sub, backlog, complete
+}
+
+// LastID returns the ID of the latest entry.
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.unsubscribe(s)
+}
+
+func (b *Broker) unsubscribe(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.c)
+
+	delete(b.subs[sub.userID], sub)
+	if len(b.subs[sub.userID]) == 0 {
+		delete(b.subs, sub.userID)
+	}
+}
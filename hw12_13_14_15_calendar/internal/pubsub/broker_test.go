@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func change(userID, eventID int64) app.Change {
+	return app.Change{Type: app.ChangeCreated, Event: storage.Event{ID: eventID, UserID: userID}}
+}
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("delivers to the owner only", func(t *testing.T) {
+		b := NewBroker(10, 10)
+		sub, backlog, complete := b.Subscribe(1, b.LastID())
+		defer sub.Close()
+		require.Empty(t, backlog)
+		require.True(t, complete)
+
+		b.Publish(ctx, change(2, 1))
+		b.Publish(ctx, change(1, 2))
+
+		entry := <-sub.C
+		require.Equal(t, b.LastID(), entry.ID)
+		require.Equal(t, int64(2), entry.Change.Event.ID)
+		require.Empty(t, sub.C)
+	})
+
+	t.Run("resumes from the log", func(t *testing.T) {
+		b := NewBroker(3, 10)
+		first := b.LastID()
+		for i := int64(1); i <= 4; i++ {
+			b.Publish(ctx, change(1, i))
+		}
+
+		sub, backlog, complete := b.Subscribe(1, first+2)
+		sub.Close()
+		require.True(t, complete)
+		require.Len(t, backlog, 2)
+		require.Equal(t, first+3, backlog[0].ID)
+		require.Equal(t, first+4, backlog[1].ID)
+
+		// Entry 1 fell out of the log, so resuming from the start misses it.
+		sub, backlog, complete = b.Subscribe(1, first)
+		sub.Close()
+		require.False(t, complete)
+		require.Len(t, backlog, 3)
+
+		// An ID past the latest entry is unknown.
+		sub, _, complete = b.Subscribe(1, first+100)
+		sub.Close()
+		require.False(t, complete)
+	})
+
+	t.Run("resets after a restart", func(t *testing.T) {
+		before := NewBroker(10, 10)
+		before.Publish(ctx, change(1, 1))
+		before.Publish(ctx, change(1, 2))
+		lastSeen := before.LastID()
+
+		// The restarted broker publishes as many entries, so only the
+		// epoch tells their IDs apart.
+		after := NewBroker(10, 10)
+		for after.epoch == before.epoch {
+			after = NewBroker(10, 10)
+		}
+		after.Publish(ctx, change(1, 3))
+		after.Publish(ctx, change(1, 4))
+		after.Publish(ctx, change(1, 5))
+
+		sub, _, complete := after.Subscribe(1, lastSeen)
+		sub.Close()
+		require.False(t, complete)
+
+		// Neither are IDs without an epoch.
+		sub, _, complete = after.Subscribe(1, 2)
+		sub.Close()
+		require.False(t, complete)
+	})
+
+	t.Run("drops slow subscribers", func(t *testing.T) {
+		b := NewBroker(10, 1)
+		sub, _, _ := b.Subscribe(1, 0)
+
+		b.Publish(ctx, change(1, 1))
+		b.Publish(ctx, change(1, 2))
+
+		<-sub.C
+		_, ok := <-sub.C
+		require.False(t, ok)
+		sub.Close()
+	})
+}
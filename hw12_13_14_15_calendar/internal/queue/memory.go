@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	defaultBufferSize = 1024
+	redeliveryDelay   = time.Second
+)
+
+// MemoryQueue is an in-process queue. Messages of topics without
+// subscribers are dropped; messages whose handler fails are redelivered,
+// deferred ones once the latest requested time has passed.
+type MemoryQueue struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	messages chan Message
+}
+
+func NewMemoryQueue(bufferSize int) *MemoryQueue {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	return &MemoryQueue{
+		handlers: make(map[string][]Handler),
+		messages: make(chan Message, bufferSize),
+	}
+}
+
+func (q *MemoryQueue) Subscribe(topic string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[topic] = append(q.handlers[topic], handler)
+}
+
+// Publish blocks while the buffer is full. The trace context of ctx is
+// passed to consumers in the message headers.
+func (q *MemoryQueue) Publish(ctx context.Context, msg Message) (err error) {
+	ctx, span := startPublish(ctx, &msg)
+	defer func() { tracing.End(span, err) }()
+
+	select {
+	case q.messages <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *MemoryQueue) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-q.messages:
+			q.deliver(ctx, msg)
+		}
+	}
+}
+
+func (q *MemoryQueue) deliver(ctx context.Context, msg Message) {
+	q.mu.RLock()
+	handlers := q.handlers[msg.Topic]
+	q.mu.RUnlock()
+
+	consumeCtx, span := startConsume(ctx, msg)
+	failed := false
+	delay := redeliveryDelay
+	for _, handle := range handlers {
+		err := handle(consumeCtx, msg)
+		var deferErr *DeferError
+		if errors.As(err, &deferErr) {
+			delay = max(delay, time.Until(deferErr.Until))
+		} else if err != nil {
+			span.RecordError(err)
+		}
+		failed = failed || err != nil
+	}
+	span.SetAttributes(attribute.Bool("messaging.redelivery_scheduled", failed))
+	span.End()
+	if !failed {
+		return
+	}
+
+	// Every handler sees the message again, so they must be idempotent.
+	time.AfterFunc(delay, func() {
+		_ = q.Publish(ctx, msg)
+	})
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrClosed = errors.New("queue is closed")
+
+// DeferError is returned by a handler to have a message delivered again
+// no earlier than Until, e.g. when its recipient does not want it now.
+type DeferError struct {
+	Until time.Time
+}
+
+func (e *DeferError) Error() string {
+	return "deferred until " + e.Until.Format(time.RFC3339)
+}
+
+// Message is a unit of delivery. Delivery is at least once, so consumers
+// deduplicate on Key.
+type Message struct {
+	Topic   string
+	Key     string
+	Headers map[string]string
+	Body    []byte
+}
+
+// Handler processes a message. A returned error requests redelivery; a
+// *DeferError requests it at a later time.
+type Handler func(ctx context.Context, msg Message) error
+
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+type Queue interface {
+	Publisher
+	// Subscribe registers handler for messages of topic. It must be called
+	// before Run.
+	Subscribe(topic string, handler Handler)
+	// Run delivers messages until ctx is done.
+	Run(ctx context.Context) error
+}
@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("queue")
+
+// startPublish starts a producer span for msg and writes its context to a
+// copy of the message headers. A message published again without a span
+// in ctx, e.g. for redelivery, stays in the trace it carries.
+func startPublish(ctx context.Context, msg *Message) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = tracing.Extract(ctx, msg.Headers)
+	}
+
+	ctx, span := tracer.Start(ctx, "publish "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messageAttributes(*msg)...))
+
+	headers := make(map[string]string, len(msg.Headers)+1)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	tracing.Inject(ctx, headers)
+	msg.Headers = headers
+	return ctx, span
+}
+
+// startConsume starts a consumer span in the trace carried by msg.
+func startConsume(ctx context.Context, msg Message) (context.Context, trace.Span) {
+	return tracer.Start(tracing.Extract(ctx, msg.Headers), "process "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(messageAttributes(msg)...))
+}
+
+func messageAttributes(msg Message) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("memory"),
+		semconv.MessagingDestinationName(msg.Topic),
+		semconv.MessagingMessageID(msg.Key),
+	}
+}
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMemoryQueue_PropagatesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	q := NewMemoryQueue(1)
+	consumed := make(chan trace.SpanContext, 1)
+	q.Subscribe("notification", func(ctx context.Context, msg Message) error {
+		require.Equal(t, "value", msg.Headers["custom"])
+		consumed <- trace.SpanContextFromContext(ctx)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	parent, span := otel.Tracer("test").Start(context.Background(), "scheduler tick")
+	headers := map[string]string{"custom": "value"}
+	require.NoError(t, q.Publish(parent, Message{Topic: "notification", Key: "n:1", Headers: headers}))
+	span.End()
+	require.Len(t, headers, 1, "caller headers were modified")
+
+	var consumer trace.SpanContext
+	select {
+	case consumer = <-consumed:
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+	}
+	require.Equal(t, span.SpanContext().TraceID(), consumer.TraceID())
+
+	require.Eventually(t, func() bool { return len(recorder.Ended()) == 3 }, time.Second, 10*time.Millisecond)
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	publish, process := spans["publish notification"], spans["process notification"]
+	require.Equal(t, trace.SpanKindProducer, publish.SpanKind())
+	require.Equal(t, span.SpanContext().SpanID(), publish.Parent().SpanID())
+	require.Equal(t, trace.SpanKindConsumer, process.SpanKind())
+	require.Equal(t, publish.SpanContext().SpanID(), process.Parent().SpanID())
+	require.Equal(t, consumer.SpanID(), process.SpanContext().SpanID())
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/outbox"
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const defaultInterval = 10 * time.Second
+
+var tracer = tracing.Tracer("scheduler")
+
+// Scheduler periodically moves due notifications to the outbox and relays
+// the outbox to the queue. Marking an event as notified and recording its
+// notification happen in one transaction, so a crash loses neither.
+type Scheduler struct {
+	logger *logger.Logger
+	store  storage.Outbox
+	relay  *outbox.Relay
+
+	mu       sync.Mutex
+	interval time.Duration
+	reset    chan struct{}
+}
+
+func New(logg *logger.Logger, store storage.Outbox, relay *outbox.Relay, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	return &Scheduler{
+		logger:   logg,
+		store:    store,
+		relay:    relay,
+		interval: interval,
+		reset:    make(chan struct{}, 1),
+	}
+}
+
+// SetInterval changes how often Run ticks. A running scheduler resets its
+// ticker to the new interval.
+func (s *Scheduler) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	s.mu.Lock()
+	changed := s.interval != interval
+	s.interval = interval
+	s.mu.Unlock()
+
+	if changed {
+		select {
+		case s.reset <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Scheduler) currentInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interval
+}
+
+// Run ticks until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.currentInterval())
+	defer ticker.Stop()
+
+	for {
+		s.Tick(ctx)
+		if !s.wait(ctx, ticker) {
+			return
+		}
+	}
+}
+
+// wait blocks until the next tick, resetting ticker whenever the interval
+// changes. It returns false once ctx is done.
+func (s *Scheduler) wait(ctx context.Context, ticker *time.Ticker) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.reset:
+			ticker.Reset(s.currentInterval())
+		case <-ticker.C:
+			return true
+		}
+	}
+}
+
+// Tick enqueues the notifications due now and relays the outbox.
+func (s *Scheduler) Tick(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "scheduler tick")
+	defer span.End()
+
+	enqueued, err := s.enqueue(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to enqueue notifications: "+err.Error())
+	} else if enqueued > 0 {
+		s.logger.DebugContext(ctx, fmt.Sprintf("enqueued %d notifications", enqueued))
+	}
+
+	// Relay even after a failure above to drain earlier messages.
+	relayed, err := s.flush(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to relay outbox: "+err.Error())
+	}
+	span.SetAttributes(
+		attribute.Int("notifications.enqueued", enqueued),
+		attribute.Int("outbox.relayed", relayed),
+	)
+}
+
+func (s *Scheduler) enqueue(ctx context.Context) (n int, err error) {
+	ctx, span := tracer.Start(ctx, "enqueue due notifications")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.EnqueueDueNotifications(ctx, time.Now())
+}
+
+func (s *Scheduler) flush(ctx context.Context) (n int, err error) {
+	ctx, span := tracer.Start(ctx, "relay outbox")
+	defer func() { tracing.End(span, err) }()
+
+	return s.relay.Flush(ctx)
+}
@@ -0,0 +1,291 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/queue"
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const defaultDedupSize = 10000
+
+var tracer = tracing.Tracer("sender")
+
+// Sender delivers notifications from the queue. Messages are delivered at
+// least once, so it remembers the keys of recent messages and drops copies.
+// With a store, copies of messages it no longer remembers are found in the
+// delivery history. Reminders due in the quiet hours of their user are
+// deferred until the quiet hours end.
+type Sender struct {
+	logger   *logger.Logger
+	channels map[string]Channel
+	onSend   []func(ctx context.Context, notification storage.Notification)
+	// store keeps preferences and delivery history; nil sends every
+	// notification to the default channel and records nothing.
+	store storage.NotificationStore
+	now   func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+	// order holds seen keys oldest first; the oldest is forgotten when full.
+	order []string
+	next  int
+}
+
+// Channel delivers a notification to its user. A returned error requests
+// redelivery.
+type Channel func(ctx context.Context, notification storage.Notification) error
+
+func New(logg *logger.Logger, dedupSize int) *Sender {
+	if dedupSize <= 0 {
+		dedupSize = defaultDedupSize
+	}
+
+	return &Sender{
+		logger: logg,
+		// Sent notifications are always logged, so the log channel has
+		// nothing more to do.
+		channels: map[string]Channel{
+			storage.ChannelLog: func(context.Context, storage.Notification) error { return nil },
+		},
+		now:   time.Now,
+		seen:  make(map[string]struct{}, dedupSize),
+		order: make([]string, dedupSize),
+	}
+}
+
+// AddChannel registers a channel users can choose in their preferences.
+// It must be called before the sender is subscribed.
+func (s *Sender) AddChannel(name string, channel Channel) {
+	s.channels[name] = channel
+}
+
+// UseStore makes the sender follow the notification preferences kept in
+// store and record every delivery there. It must be called before the
+// sender is subscribed.
+func (s *Sender) UseStore(store storage.NotificationStore) {
+	s.store = store
+}
+
+// OnSend registers fn to be called with every notification sent. It must
+// be called before the sender is subscribed.
+func (s *Sender) OnSend(fn func(ctx context.Context, notification storage.Notification)) {
+	s.onSend = append(s.onSend, fn)
+}
+
+func (s *Sender) Handle(ctx context.Context, msg queue.Message) (err error) {
+	ctx, span := tracer.Start(ctx, "send notification")
+	defer func() {
+		// Deferring a notification is not a failure.
+		var deferErr *queue.DeferError
+		if errors.As(err, &deferErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	if s.isDuplicate(msg.Key) {
+		span.SetAttributes(attribute.Bool("notification.duplicate", true))
+		s.logger.DebugContext(ctx, "dropped duplicate notification "+msg.Key)
+		return nil
+	}
+
+	var notification storage.Notification
+	if err := json.Unmarshal(msg.Body, &notification); err != nil {
+		// A malformed message would fail forever, so it is not retried.
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid notification")
+		s.logger.ErrorContext(ctx, fmt.Sprintf("invalid notification %s: %s", msg.Key, err.Error()))
+		return nil
+	}
+
+	span.SetAttributes(
+		attribute.Int64("user.id", notification.UserID),
+		attribute.Int64("event.id", notification.EventID),
+	)
+
+	if s.store != nil && msg.Key != "" {
+		handled, err := s.store.NotificationHandled(ctx, notification.UserID, msg.Key)
+		if err != nil {
+			s.forget(msg.Key)
+			return fmt.Errorf("check notification delivery: %w", err)
+		}
+		if handled {
+			span.SetAttributes(attribute.Bool("notification.duplicate", true))
+			s.logger.DebugContext(ctx, "dropped duplicate notification "+msg.Key)
+			return nil
+		}
+	}
+
+	delivery, err := s.deliver(ctx, msg.Key, notification)
+	if delivery != nil {
+		span.SetAttributes(
+			attribute.String("notification.channel", delivery.Channel),
+			attribute.String("notification.status", delivery.Status),
+		)
+		s.record(ctx, delivery)
+	}
+	if err != nil {
+		// Let the redelivered message through.
+		s.forget(msg.Key)
+		return err
+	}
+	if delivery.Status != storage.DeliverySent {
+		s.logger.DebugContext(ctx, fmt.Sprintf("notification %s for user %d %s",
+			msg.Key, notification.UserID, delivery.Status))
+		return nil
+	}
+
+	if notification.Kind == storage.NotificationDigest {
+		s.logger.InfoContext(ctx, fmt.Sprintf("digest for user %d: %q", notification.UserID, notification.Title))
+	} else {
+		s.logger.InfoContext(ctx, fmt.Sprintf("notification for user %d: event %d %q starts at %s",
+			notification.UserID, notification.EventID, notification.Title, notification.StartTime))
+	}
+	for _, fn := range s.onSend {
+		fn(ctx, notification)
+	}
+	return nil
+}
+
+// deliver sends notification as its user prefers. It returns the delivery
+// to record, which is nil if the preferences could not be read.
+func (s *Sender) deliver(ctx context.Context,
+	key string,
+	notification storage.Notification,
+) (*storage.NotificationDelivery, error) {
+	prefs := storage.DefaultNotificationPreferences(notification.UserID)
+	if s.store != nil {
+		var err error
+		if prefs, err = s.store.GetNotificationPreferences(ctx, notification.UserID); err != nil {
+			return nil, fmt.Errorf("get notification preferences: %w", err)
+		}
+	}
+
+	channel, ok := s.channels[prefs.Channel]
+	if !ok {
+		prefs.Channel = storage.ChannelLog
+		channel = s.channels[storage.ChannelLog]
+	}
+
+	delivery := &storage.NotificationDelivery{
+		UserID:  notification.UserID,
+		EventID: notification.EventID,
+		Key:     key,
+		Channel: prefs.Channel,
+		Status:  storage.DeliverySent,
+	}
+	// The digest is what the user asked for, at the time they chose.
+	isDigest := notification.Kind == storage.NotificationDigest
+	// Reminders of events missing from the enqueued agenda, such as those
+	// added to the day after it, are delivered like any other.
+	digested := false
+	if prefs.Digest && !isDigest && s.store != nil {
+		var err error
+		if digested, err = s.store.InDigest(ctx, notification.UserID, notification.EventID); err != nil {
+			return nil, fmt.Errorf("check digest: %w", err)
+		}
+	}
+	quietEnd, quiet := quietUntil(prefs, s.now())
+	switch {
+	case digested:
+		delivery.Status = storage.DeliveryDigested
+	case quiet && !isDigest:
+		delivery.Status = storage.DeliveryDeferred
+		return delivery, &queue.DeferError{Until: quietEnd}
+	default:
+		if err := channel(ctx, notification); err != nil {
+			delivery.Status = storage.DeliveryFailed
+			delivery.Error = err.Error()
+			return delivery, fmt.Errorf("send to %s: %w", prefs.Channel, err)
+		}
+	}
+	return delivery, nil
+}
+
+func (s *Sender) record(ctx context.Context, delivery *storage.NotificationDelivery) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.AddNotificationDelivery(ctx, delivery); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record notification delivery: "+err.Error())
+	}
+}
+
+// quietUntil reports whether t falls in the quiet hours of prefs and
+// when they end.
+func quietUntil(prefs *storage.NotificationPreferences, t time.Time) (time.Time, bool) {
+	if prefs.QuietStart == "" || prefs.QuietEnd == "" {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(prefs.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, startErr := time.Parse(storage.TimeOfDayLayout, prefs.QuietStart)
+	end, endErr := time.Parse(storage.TimeOfDayLayout, prefs.QuietEnd)
+	if startErr != nil || endErr != nil {
+		return time.Time{}, false
+	}
+
+	t = t.In(loc)
+	now := t.Hour()*60 + t.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	quiet := from <= now && now < to
+	if from > to {
+		// The quiet hours span midnight.
+		quiet = now >= from || now < to
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(t.Year(), t.Month(), t.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !until.After(t) {
+		until = time.Date(t.Year(), t.Month(), t.Day()+1, end.Hour(), end.Minute(), 0, 0, loc)
+	}
+	return until, true
+}
+
+// isDuplicate reports whether key was seen and remembers it otherwise.
+func (s *Sender) isDuplicate(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[key]; ok {
+		return true
+	}
+
+	if oldest := s.order[s.next]; oldest != "" {
+		delete(s.seen, oldest)
+	}
+	s.order[s.next] = key
+	s.next = (s.next + 1) % len(s.order)
+	s.seen[key] = struct{}{}
+	return false
+}
+
+// forget lets a seen key through again.
+func (s *Sender) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.seen, key)
+}
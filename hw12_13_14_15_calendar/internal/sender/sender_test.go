@@ -0,0 +1,255 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/queue"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSender_Deduplicates(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "sender.log")
+	logg, err := logger.NewLogger(logPath, "info", logger.TextFormat)
+	require.NoError(t, err)
+	defer logg.Close()
+
+	sent := func() int {
+		data, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		return strings.Count(string(data), "notification for user")
+	}
+
+	s := New(logg, 2)
+	ctx := context.Background()
+
+	message := func(eventID int64) queue.Message {
+		body, err := json.Marshal(storage.Notification{EventID: eventID, Title: "Meeting", UserID: 1})
+		require.NoError(t, err)
+		return queue.Message{
+			Topic: storage.TopicNotification,
+			Key:   storage.NotificationKey(eventID, time.Unix(0, 0)),
+			Body:  body,
+		}
+	}
+
+	require.NoError(t, s.Handle(ctx, message(1)))
+	require.NoError(t, s.Handle(ctx, message(1)))
+	require.Equal(t, 1, sent())
+
+	// The oldest key is forgotten once more keys than the limit are seen.
+	require.NoError(t, s.Handle(ctx, message(2)))
+	require.NoError(t, s.Handle(ctx, message(3)))
+	require.NoError(t, s.Handle(ctx, message(1)))
+	require.Equal(t, 4, sent())
+
+	t.Run("store remembers across senders", func(t *testing.T) {
+		store := memorystorage.New().(*memorystorage.MemoryStorage)
+		first := New(logg, 2)
+		first.UseStore(store)
+		require.NoError(t, first.Handle(ctx, message(4)))
+		require.Equal(t, 5, sent())
+
+		// A restarted sender remembers no keys.
+		restarted := New(logg, 2)
+		restarted.UseStore(store)
+		require.NoError(t, restarted.Handle(ctx, message(4)))
+		require.Equal(t, 5, sent())
+
+		history, err := store.ListNotificationDeliveries(ctx, 1, 4, 0)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+	})
+}
+
+func TestSender_FollowsPreferences(t *testing.T) {
+	logg, err := logger.NewLogger(filepath.Join(t.TempDir(), "sender.log"), "info", logger.TextFormat)
+	require.NoError(t, err)
+	defer logg.Close()
+
+	store := memorystorage.New().(*memorystorage.MemoryStorage)
+	ctx := context.Background()
+
+	var pushed []int64
+	var pushErr error
+	s := New(logg, 16)
+	s.AddChannel(storage.ChannelPush, func(_ context.Context, notification storage.Notification) error {
+		if pushErr != nil {
+			return pushErr
+		}
+		pushed = append(pushed, notification.EventID)
+		return nil
+	})
+	s.UseStore(store)
+
+	// 23:30 in Berlin.
+	s.now = func() time.Time { return time.Date(2025, 3, 10, 22, 30, 0, 0, time.UTC) }
+
+	send := func(eventID int64) error {
+		body, err := json.Marshal(storage.Notification{EventID: eventID, Title: "Meeting", UserID: 1})
+		require.NoError(t, err)
+		return s.Handle(ctx, queue.Message{
+			Topic: storage.TopicNotification,
+			Key:   storage.NotificationKey(eventID, time.Unix(0, 0)),
+			Body:  body,
+		})
+	}
+	setPreferences := func(prefs storage.NotificationPreferences) {
+		prefs.UserID = 1
+		require.NoError(t, store.SetNotificationPreferences(ctx, &prefs))
+	}
+	lastStatus := func() string {
+		deliveries, err := store.ListNotificationDeliveries(ctx, 1, 0, 1)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		return deliveries[0].Status
+	}
+
+	require.NoError(t, send(1))
+	require.Equal(t, storage.DeliverySent, lastStatus())
+
+	setPreferences(storage.NotificationPreferences{
+		Channel: storage.ChannelPush, QuietStart: "22:00", QuietEnd: "07:00", DigestTime: "08:00", TimeZone: "Europe/Berlin",
+	})
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	var deferErr *queue.DeferError
+	require.ErrorAs(t, send(2), &deferErr)
+	require.True(t, time.Date(2025, 3, 11, 7, 0, 0, 0, berlin).Equal(deferErr.Until), deferErr.Until)
+	require.Equal(t, storage.DeliveryDeferred, lastStatus())
+
+	// The deferred reminder is sent when it comes back after the quiet
+	// hours.
+	s.now = func() time.Time { return deferErr.Until }
+	require.NoError(t, send(2))
+	require.Equal(t, storage.DeliverySent, lastStatus())
+	s.now = func() time.Time { return time.Date(2025, 3, 10, 22, 30, 0, 0, time.UTC) }
+
+	setPreferences(storage.NotificationPreferences{
+		Channel: storage.ChannelPush, Digest: true, DigestTime: "08:00", TimeZone: "UTC",
+	})
+	added, err := store.EnqueueDigest(ctx, "2025-03-10", storage.Notification{
+		Kind: storage.NotificationDigest, UserID: 1, EventIDs: []int64{3},
+	})
+	require.NoError(t, err)
+	require.True(t, added)
+	require.NoError(t, send(3))
+	require.Equal(t, storage.DeliveryDigested, lastStatus())
+
+	// The digest itself is sent, even in quiet hours.
+	setPreferences(storage.NotificationPreferences{
+		Channel: storage.ChannelPush, Digest: true, DigestTime: "08:00",
+		QuietStart: "22:00", QuietEnd: "07:00", TimeZone: "UTC",
+	})
+	body, err := json.Marshal(storage.Notification{Kind: storage.NotificationDigest, Title: "Agenda", UserID: 1})
+	require.NoError(t, err)
+	require.NoError(t, s.Handle(ctx, queue.Message{
+		Topic: storage.TopicNotification,
+		Key:   storage.DigestKey(1, "2025-03-10"),
+		Body:  body,
+	}))
+	require.Equal(t, storage.DeliverySent, lastStatus())
+	require.Equal(t, []int64{1, 2, 0}, pushed)
+	pushed = pushed[:2]
+
+	setPreferences(storage.NotificationPreferences{Channel: storage.ChannelLog, DigestTime: "08:00", TimeZone: "UTC"})
+	require.NoError(t, send(4))
+	require.Equal(t, storage.DeliverySent, lastStatus())
+	require.Equal(t, []int64{1, 2}, pushed)
+
+	// A failed delivery is recorded and retried.
+	setPreferences(storage.NotificationPreferences{Channel: storage.ChannelPush, DigestTime: "08:00", TimeZone: "UTC"})
+	pushErr = errors.New("stream closed")
+	require.Error(t, send(5))
+	require.Equal(t, storage.DeliveryFailed, lastStatus())
+	pushErr = nil
+	require.NoError(t, send(5))
+	require.Equal(t, storage.DeliverySent, lastStatus())
+	require.Equal(t, []int64{1, 2, 5}, pushed)
+
+	deliveries, err := store.ListNotificationDeliveries(ctx, 1, 5, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+}
+
+func TestSender_RemindsEventsMissingFromDigest(t *testing.T) {
+	logg, err := logger.NewLogger(filepath.Join(t.TempDir(), "sender.log"), "info", logger.TextFormat)
+	require.NoError(t, err)
+	defer logg.Close()
+
+	store := memorystorage.New().(*memorystorage.MemoryStorage)
+	ctx := context.Background()
+
+	var pushed []int64
+	s := New(logg, 16)
+	s.AddChannel(storage.ChannelPush, func(_ context.Context, notification storage.Notification) error {
+		pushed = append(pushed, notification.EventID)
+		return nil
+	})
+	s.UseStore(store)
+	s.now = func() time.Time { return time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, store.SetNotificationPreferences(ctx, &storage.NotificationPreferences{
+		UserID: 1, Channel: storage.ChannelPush, Digest: true, DigestTime: "08:00", TimeZone: "UTC",
+	}))
+	send := func(eventID int64) string {
+		body, err := json.Marshal(storage.Notification{EventID: eventID, Title: "Meeting", UserID: 1})
+		require.NoError(t, err)
+		require.NoError(t, s.Handle(ctx, queue.Message{
+			Topic: storage.TopicNotification,
+			Key:   storage.NotificationKey(eventID, time.Unix(0, 0)),
+			Body:  body,
+		}))
+
+		deliveries, err := store.ListNotificationDeliveries(ctx, 1, eventID, 1)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		return deliveries[0].Status
+	}
+
+	// Before the digest of the day is enqueued, nothing is in it.
+	require.Equal(t, storage.DeliverySent, send(1))
+
+	_, err = store.EnqueueDigest(ctx, "2025-03-10", storage.Notification{
+		Kind: storage.NotificationDigest, UserID: 1, EventIDs: []int64{2},
+	})
+	require.NoError(t, err)
+	require.Equal(t, storage.DeliveryDigested, send(2))
+
+	// Event 3 was created after the digest was enqueued.
+	require.Equal(t, storage.DeliverySent, send(3))
+	require.Equal(t, []int64{1, 3}, pushed)
+}
+
+func TestQuietUntil(t *testing.T) {
+	prefs := &storage.NotificationPreferences{QuietStart: "22:00", QuietEnd: "07:00", TimeZone: "UTC"}
+	at := func(hour, minute int) bool {
+		_, quiet := quietUntil(prefs, time.Date(2025, 3, 10, hour, minute, 0, 0, time.UTC))
+		return quiet
+	}
+	require.True(t, at(22, 0))
+	require.True(t, at(3, 0))
+	require.False(t, at(7, 0))
+	require.False(t, at(21, 59))
+
+	until, _ := quietUntil(prefs, time.Date(2025, 3, 10, 23, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2025, 3, 11, 7, 0, 0, 0, time.UTC), until)
+	until, _ = quietUntil(prefs, time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC), until)
+
+	prefs.QuietStart, prefs.QuietEnd = "12:00", "13:00"
+	require.True(t, at(12, 30))
+	require.False(t, at(13, 30))
+
+	prefs.QuietStart, prefs.QuietEnd = "", ""
+	require.False(t, at(12, 30))
+}
@@ -0,0 +1,74 @@
+package internalgrpc
+
+import (
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fromProto converts an event of a request. Its user is set by the server.
+func fromProto(e *eventpb.Event) *storage.Event {
+	if e == nil {
+		return &storage.Event{}
+	}
+
+	event := &storage.Event{
+		ID:          e.GetId(),
+		Title:       e.GetTitle(),
+		Description: e.GetDescription(),
+		StartTime:   fromTimestamp(e.GetStartTime()),
+		EndTime:     fromTimestamp(e.GetEndTime()),
+		NotifyAt:    fromTimestamp(e.GetNotifyAt()),
+		CalendarID:  e.GetCalendarId(),
+	}
+	if c := e.GetConference(); c != nil {
+		event.Conference = &storage.Conference{
+			URL:       c.GetUrl(),
+			Provider:  c.GetProvider(),
+			MeetingID: c.GetMeetingId(),
+			Passcode:  c.GetPasscode(),
+		}
+	}
+	return event
+}
+
+func toProto(event *storage.Event) *eventpb.Event {
+	e := &eventpb.Event{
+		Id:          event.ID,
+		Title:       event.Title,
+		Description: event.Description,
+		StartTime:   toTimestamp(event.StartTime),
+		EndTime:     toTimestamp(event.EndTime),
+		UserId:      event.UserID,
+		NotifyAt:    toTimestamp(event.NotifyAt),
+		CalendarId:  event.CalendarID,
+	}
+	if c := event.Conference; c != nil {
+		e.Conference = &eventpb.Conference{
+			Url:       c.URL,
+			Provider:  c.Provider,
+			MeetingId: c.MeetingID,
+			Passcode:  c.Passcode,
+		}
+	}
+	return e
+}
+
+// fromTimestamp maps an unset timestamp to the zero time.
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// toTimestamp leaves the zero time unset.
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
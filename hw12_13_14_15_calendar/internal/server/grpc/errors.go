@@ -0,0 +1,42 @@
+package internalgrpc
+
+import (
+	"errors"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError converts err to the status matching it. Internal errors
+// are logged and not shown to the caller.
+func (s *Server) statusError(err error) error {
+	code := errorCode(err)
+	message := err.Error()
+	if code == codes.Internal {
+		s.logger.Error("call failed: " + message)
+		message = "internal error"
+	}
+	return status.Error(code, message)
+}
+
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, errNoUser):
+		return codes.Unauthenticated
+	case storage.IsValidationError(err):
+		return codes.InvalidArgument
+	case storage.IsNotFound(err):
+		return codes.NotFound
+	case storage.IsAlreadyExists(err):
+		return codes.AlreadyExists
+	case errors.Is(err, app.ErrBatchUnsupported), errors.Is(err, app.ErrTagsUnsupported),
+		errors.Is(err, app.ErrCalendarsUnsupported), errors.Is(err, app.ErrAttachmentsUnsupported),
+		errors.Is(err, app.ErrSearchUnsupported):
+		return codes.Unimplemented
+	}
+	return codes.Internal
+}
@@ -0,0 +1,90 @@
+package internalgrpc
+
+import (
+	"context"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *Server) CreateEvent(ctx context.Context, req *eventpb.CreateEventRequest) (*eventpb.Event, error) {
+	event, err := s.apply(ctx, storage.BatchCreate, fromProto(req.GetEvent()))
+	if err != nil {
+		return nil, err
+	}
+	return toProto(event), nil
+}
+
+func (s *Server) UpdateEvent(ctx context.Context, req *eventpb.UpdateEventRequest) (*eventpb.Event, error) {
+	event, err := s.apply(ctx, storage.BatchUpdate, fromProto(req.GetEvent()))
+	if err != nil {
+		return nil, err
+	}
+	return toProto(event), nil
+}
+
+func (s *Server) DeleteEvent(ctx context.Context, req *eventpb.DeleteEventRequest) (*emptypb.Empty, error) {
+	if _, err := s.apply(ctx, storage.BatchDelete, &storage.Event{ID: req.GetId()}); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// apply runs a single operation as the HTTP API does, so events of other
+// users are neither changed nor revealed.
+func (s *Server) apply(ctx context.Context, op string, event *storage.Event) (*storage.Event, error) {
+	user, err := userID(ctx)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	results, err := s.app.ApplyBatch(ctx, user, []storage.BatchOp{{Type: op, Event: event}}, false)
+	if err == nil {
+		err = results[0].Err
+	}
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return results[0].Event, nil
+}
+
+func (s *Server) GetEvent(ctx context.Context, req *eventpb.GetEventRequest) (*eventpb.Event, error) {
+	user, err := userID(ctx)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	event, err := s.app.GetEvent(ctx, user, req.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return toProto(event), nil
+}
+
+// ListEvents lists the user's events overlapping [from, to]. Events must
+// have all of the tags and be in any of the calendars of the request.
+func (s *Server) ListEvents(ctx context.Context, req *eventpb.ListEventsRequest) (*eventpb.ListEventsResponse, error) {
+	user, err := userID(ctx)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, s.statusError(storage.ValidationError("from and to are required"))
+	}
+
+	filter := app.EventFilter{TagIDs: req.GetTagIds(), CalendarIDs: req.GetCalendarIds()}
+	events, err := s.app.ListEvents(ctx, user, req.GetFrom().AsTime(), req.GetTo().AsTime(), filter)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	resp := &eventpb.ListEventsResponse{Events: make([]*eventpb.Event, len(events))}
+	for i, event := range events {
+		resp.Events[i] = toProto(event)
+	}
+	return resp, nil
+}
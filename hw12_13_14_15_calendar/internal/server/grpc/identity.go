@@ -0,0 +1,60 @@
+package internalgrpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UserIDMetadata carries the ID of the calling user, as the X-User-ID
+// header does for HTTP.
+const UserIDMetadata = "x-user-id"
+
+type userKey struct{}
+
+var errNoUser = errors.New("missing or invalid " + UserIDMetadata + " metadata")
+
+// identify names the calling user by the subject common name of a
+// verified client certificate or, without one, by the user ID metadata.
+// A call whose metadata names another user than its certificate is
+// rejected.
+func identify(ctx context.Context, req interface{},
+	_ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	var user string
+	if values := metadata.ValueFromIncomingContext(ctx, UserIDMetadata); len(values) > 0 {
+		user = values[0]
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			subject := info.State.VerifiedChains[0][0].Subject.CommonName
+			if subject == "" {
+				return nil, status.Error(codes.PermissionDenied, "client certificate has no subject common name")
+			}
+			if user != "" && user != subject {
+				return nil, status.Error(codes.PermissionDenied, "user ID does not match client certificate")
+			}
+			user = subject
+		}
+	}
+
+	return handler(context.WithValue(ctx, userKey{}, user), req)
+}
+
+// userID returns the ID of the calling user.
+func userID(ctx context.Context) (int64, error) {
+	value, _ := ctx.Value(userKey{}).(string)
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errNoUser
+	}
+	return id, nil
+}
@@ -0,0 +1,44 @@
+package internalgrpc
+
+import (
+	"context"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// logRequests logs every call as the HTTP server logs requests, with the
+// full method name as path and the status code name as message.
+func (s *Server) logRequests(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	startTime := time.Now()
+	resp, err := handler(ctx, req)
+	code := status.Code(err)
+
+	record := logger.LogRecord{
+		Timestamp:   startTime,
+		Level:       string(logger.InfoLevel),
+		Method:      "gRPC",
+		Path:        info.FullMethod,
+		HTTPVersion: "HTTP/2.0",
+		StatusCode:  int(code),
+		Latency:     float64(time.Since(startTime).Milliseconds()),
+		Message:     code.String(),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		record.ClientIP = p.Addr.String()
+	}
+	if agents := metadata.ValueFromIncomingContext(ctx, "user-agent"); len(agents) > 0 {
+		record.UserAgent = agents[0]
+	}
+	record.WithTrace(ctx)
+
+	s.logger.LogRequest(record)
+	return resp, err
+}
@@ -0,0 +1,34 @@
+package internalgrpc
+
+import (
+	"context"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+)
+
+// SearchEvents searches the user's events as GET /events/search does.
+func (s *Server) SearchEvents(ctx context.Context,
+	req *eventpb.SearchEventsRequest,
+) (*eventpb.SearchEventsResponse, error) {
+	user, err := userID(ctx)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	results, err := s.app.SearchEvents(ctx, user, req.GetQuery(),
+		fromTimestamp(req.GetFrom()), fromTimestamp(req.GetTo()), int(req.GetLimit()))
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	resp := &eventpb.SearchEventsResponse{Results: make([]*eventpb.SearchResult, len(results))}
+	for i, result := range results {
+		resp.Results[i] = &eventpb.SearchResult{
+			Event:                toProto(result.Event),
+			Rank:                 result.Rank,
+			TitleHighlight:       result.TitleHighlight,
+			DescriptionHighlight: result.DescriptionHighlight,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,118 @@
+package internalgrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/server/tlsconfig"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Server serves the EventService of api/EventService.proto.
+type Server struct {
+	eventpb.UnimplementedEventServiceServer
+
+	logger *logger.Logger
+	app    app.App
+	conf   config.GRPCConfig
+	server *grpc.Server
+	certs  *tlsconfig.Reloader
+}
+
+// NewServer loads the listener certificates, if TLS is enabled, and
+// registers the service.
+func NewServer(logger *logger.Logger, app app.App, conf config.GRPCConfig) (*Server, error) {
+	s := &Server{
+		logger: logger,
+		app:    app,
+		conf:   conf,
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(tracingHandler()),
+		grpc.ChainUnaryInterceptor(s.logRequests, identify),
+	}
+	if conf.Listener.TLS.Enabled() {
+		certs, err := tlsconfig.New(conf.Listener.TLS)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		opts = append(opts, grpc.Creds(credentials.NewTLS(http2Config(certs.Config()))))
+	}
+
+	s.server = grpc.NewServer(opts...)
+	eventpb.RegisterEventServiceServer(s.server, s)
+	return s, nil
+}
+
+// http2Config makes the configurations chosen per client offer HTTP/2,
+// which credentials.NewTLS only sets on the outer configuration.
+func http2Config(conf *tls.Config) *tls.Config {
+	getConfig := conf.GetConfigForClient
+	conf.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg, err := getConfig(hello)
+		if err != nil {
+			return nil, err
+		}
+		cfg.NextProtos = []string{"h2"}
+		return cfg, nil
+	}
+	return conf
+}
+
+// Listen binds the configured address for Serve.
+func (s *Server) Listen() (net.Listener, error) {
+	addr := net.JoinHostPort(s.conf.Listener.Host, strconv.Itoa(s.conf.Listener.Port))
+	return net.Listen("tcp", addr)
+}
+
+// Serve serves on l until Stop is called. ctx bounds reloading the
+// certificates; canceling it does not stop the server.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	scheme := "gRPC"
+	if s.certs != nil {
+		go s.certs.Watch(ctx, func(err error) {
+			s.logger.Error("failed to reload gRPC server certificates: " + err.Error())
+		})
+		scheme = "gRPC over TLS"
+	}
+
+	s.logger.Info(fmt.Sprintf("Starting %s server on %s", scheme, l.Addr()))
+	if err := s.server.Serve(l); !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+
+	return nil
+}
+
+// Stop waits for in-flight calls to finish. When ctx is done first, the
+// remaining calls are canceled.
+func (s *Server) Stop(ctx context.Context) error {
+	s.logger.Info("Stopping gRPC server")
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,244 @@
+package internalgrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/eventpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// startServer serves conf on a local port and returns its address.
+func startServer(t *testing.T, conf config.GRPCConfig) string {
+	t.Helper()
+
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	s, err := NewServer(logg, *app.New(logg, memorystorage.New()), conf)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, l) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, s.Stop(context.Background()))
+		require.NoError(t, <-served)
+	})
+
+	return l.Addr().String()
+}
+
+func dial(t *testing.T, addr string, creds credentials.TransportCredentials) eventpb.EventServiceClient {
+	t.Helper()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return eventpb.NewEventServiceClient(conn)
+}
+
+func asUser(user string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), UserIDMetadata, user)
+}
+
+func TestServer_Events(t *testing.T) {
+	client := dial(t, startServer(t, config.GRPCConfig{}), insecure.NewCredentials())
+	ctx := asUser("1")
+
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	created, err := client.CreateEvent(ctx, &eventpb.CreateEventRequest{Event: &eventpb.Event{
+		Title:     "Sync",
+		StartTime: timestamppb.New(start),
+		EndTime:   timestamppb.New(start.Add(time.Hour)),
+		Conference: &eventpb.Conference{
+			Url: "https://meet.example.com/abc",
+		},
+	}})
+	require.NoError(t, err)
+	require.NotZero(t, created.GetId())
+	require.Equal(t, int64(1), created.GetUserId())
+	require.Nil(t, created.GetNotifyAt())
+
+	t.Run("get", func(t *testing.T) {
+		event, err := client.GetEvent(ctx, &eventpb.GetEventRequest{Id: created.GetId()})
+		require.NoError(t, err)
+		require.Equal(t, "Sync", event.GetTitle())
+		require.Equal(t, "https://meet.example.com/abc", event.GetConference().GetUrl())
+		require.True(t, start.Equal(event.GetStartTime().AsTime()))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		created.Title = "Standup"
+		event, err := client.UpdateEvent(ctx, &eventpb.UpdateEventRequest{Event: created})
+		require.NoError(t, err)
+		require.Equal(t, "Standup", event.GetTitle())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		resp, err := client.ListEvents(ctx, &eventpb.ListEventsRequest{
+			From: timestamppb.New(start.Add(-time.Hour)),
+			To:   timestamppb.New(start.Add(2 * time.Hour)),
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.GetEvents(), 1)
+
+		_, err = client.ListEvents(ctx, &eventpb.ListEventsRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("search", func(t *testing.T) {
+		resp, err := client.SearchEvents(ctx, &eventpb.SearchEventsRequest{Query: "stand"})
+		require.NoError(t, err)
+		require.Len(t, resp.GetResults(), 1)
+		require.Equal(t, created.GetId(), resp.GetResults()[0].GetEvent().GetId())
+		require.Equal(t, "<mark>Standup</mark>", resp.GetResults()[0].GetTitleHighlight())
+
+		resp, err = client.SearchEvents(asUser("2"), &eventpb.SearchEventsRequest{Query: "stand"})
+		require.NoError(t, err)
+		require.Empty(t, resp.GetResults())
+
+		_, err = client.SearchEvents(ctx, &eventpb.SearchEventsRequest{Query: "  "})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid event", func(t *testing.T) {
+		_, err := client.CreateEvent(ctx, &eventpb.CreateEventRequest{Event: &eventpb.Event{}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("events of other users are not found", func(t *testing.T) {
+		_, err := client.GetEvent(asUser("2"), &eventpb.GetEventRequest{Id: created.GetId()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.DeleteEvent(asUser("2"), &eventpb.DeleteEventRequest{Id: created.GetId()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("missing user", func(t *testing.T) {
+		_, err := client.GetEvent(context.Background(), &eventpb.GetEventRequest{Id: created.GetId()})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := client.DeleteEvent(ctx, &eventpb.DeleteEventRequest{Id: created.GetId()})
+		require.NoError(t, err)
+
+		_, err = client.GetEvent(ctx, &eventpb.GetEventRequest{Id: created.GetId()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(commonName string, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	dir := t.TempDir()
+	conf := config.GRPCConfig{Listener: config.ListenerConfig{TLS: config.TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		ClientAuth:   "verify_if_given",
+	}}}
+	certPEM, keyPEM := issue("server", 2, x509.ExtKeyUsageServerAuth)
+	require.NoError(t, os.WriteFile(conf.Listener.TLS.CertFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(conf.Listener.TLS.KeyFile, keyPEM, 0o600))
+	require.NoError(t, os.WriteFile(conf.Listener.TLS.ClientCAFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600))
+	addr := startServer(t, conf)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	newClient := func(certs ...tls.Certificate) eventpb.EventServiceClient {
+		return dial(t, addr, credentials.NewTLS(&tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      roots,
+			Certificates: certs,
+		}))
+	}
+	clientPEM, clientKeyPEM := issue("42", 3, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	require.NoError(t, err)
+	list := &eventpb.ListEventsRequest{From: timestamppb.Now(), To: timestamppb.Now()}
+
+	t.Run("certificate names the user", func(t *testing.T) {
+		_, err := newClient(clientCert).ListEvents(context.Background(), list)
+		require.NoError(t, err)
+
+		_, err = newClient(clientCert).ListEvents(asUser("42"), list)
+		require.NoError(t, err)
+	})
+
+	t.Run("metadata must match certificate", func(t *testing.T) {
+		_, err := newClient(clientCert).ListEvents(asUser("7"), list)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("metadata names the user without certificate", func(t *testing.T) {
+		_, err := newClient().ListEvents(asUser("7"), list)
+		require.NoError(t, err)
+
+		_, err = newClient().ListEvents(context.Background(), list)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
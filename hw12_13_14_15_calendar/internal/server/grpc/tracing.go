@@ -0,0 +1,13 @@
+package internalgrpc
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc/stats"
+)
+
+// tracingHandler starts a server span for every call, named after its
+// full method, continuing the trace of the caller if its metadata carries
+// one. Spans end with the status code of the call.
+func tracingHandler() stats.Handler {
+	return otelgrpc.NewServerHandler()
+}
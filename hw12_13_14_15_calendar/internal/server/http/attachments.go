@@ -0,0 +1,138 @@
+package internalhttp
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// uploadRoute names the upload route, whose body is limited by the
+// attachment size instead of the request body size.
+const uploadRoute = "upload-attachment"
+
+// handleUploadAttachment stores the request body as an attachment. The
+// name comes from the name parameter or the filename of a
+// Content-Disposition header, the MIME type from Content-Type.
+func (s *Server) handleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	eventID, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	// Refuse a declared oversized body before reading it. The limit is
+	// zero while attachments are unsupported.
+	if limit := s.app.MaxAttachmentSize(); limit > 0 && r.ContentLength > limit {
+		s.writeError(w, fmt.Errorf("%w: the limit is %d bytes", app.ErrAttachmentTooLarge, limit))
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+			name = params["filename"]
+		}
+	}
+
+	attachment, err := s.app.UploadAttachment(r.Context(), user, eventID, name, r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, attachment)
+}
+
+func (s *Server) handleListAttachments(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	eventID, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	attachments, err := s.app.ListAttachments(r.Context(), user, eventID)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if attachments == nil {
+		attachments = []*storage.Attachment{}
+	}
+
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+func (s *Server) handleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	user, eventID, id, err := attachmentPath(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	attachment, body, err := s.app.OpenAttachment(r.Context(), user, eventID, id)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	defer body.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", attachment.MimeType)
+	header.Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		s.logger.Error(fmt.Sprintf("failed to send attachment %d: %s", id, err))
+	}
+}
+
+func (s *Server) handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	user, eventID, id, err := attachmentPath(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if err := s.app.DeleteAttachment(r.Context(), user, eventID, id); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// attachmentPath returns the user, the event and the attachment of a
+// request to an attachment.
+func attachmentPath(r *http.Request) (user, eventID, id int64, err error) {
+	if user, err = userID(r); err != nil {
+		return 0, 0, 0, err
+	}
+	if eventID, err = pathID(r); err != nil {
+		return 0, 0, 0, err
+	}
+	id, err = strconv.ParseInt(mux.Vars(r)["attachment"], 10, 64)
+	if err != nil {
+		return 0, 0, 0, storage.ValidationError("invalid attachment id")
+	}
+	return user, eventID, id, nil
+}
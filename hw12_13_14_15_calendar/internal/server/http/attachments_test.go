@@ -0,0 +1,126 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/blob"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Attachments(t *testing.T) {
+	s := newTestServer(t)
+
+	do := func(method, path, user, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(UserIDHeader, user)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	event := &storage.Event{Title: "Review", UserID: 1, StartTime: start, EndTime: start.Add(time.Hour)}
+	require.NoError(t, s.app.CreateEvent(context.Background(), event))
+	attachmentsPath := fmt.Sprintf("/events/%d/attachments", event.ID)
+
+	t.Run("unsupported without a blob store", func(t *testing.T) {
+		rec := do(http.MethodPost, attachmentsPath+"?name=a.txt", "1", "a")
+		require.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	s.app.UseBlobStore(blobs, 16)
+
+	var uploaded storage.Attachment
+	t.Run("uploads and downloads", func(t *testing.T) {
+		rec := do(http.MethodPost, attachmentsPath+"?name=agenda.txt", "1", "1. Intro",
+			"Content-Type", "text/plain; charset=utf-8")
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &uploaded))
+		require.Equal(t, "agenda.txt", uploaded.Name)
+		require.Equal(t, "text/plain; charset=utf-8", uploaded.MimeType)
+		require.Equal(t, int64(8), uploaded.Size)
+		require.NotContains(t, rec.Body.String(), "events/")
+
+		rec = do(http.MethodPost, attachmentsPath, "1", "x", "Content-Disposition", `attachment; filename="notes.md"`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		require.Contains(t, rec.Body.String(), `"mime_type":"application/octet-stream"`)
+
+		downloadPath := fmt.Sprintf("%s/%d", attachmentsPath, uploaded.ID)
+		rec = do(http.MethodGet, downloadPath, "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "1. Intro", rec.Body.String())
+		require.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Equal(t, `attachment; filename=agenda.txt`, rec.Header().Get("Content-Disposition"))
+
+		require.Equal(t, http.StatusNotFound, do(http.MethodGet, downloadPath, "2", "").Code)
+
+		rec = do(http.MethodGet, fmt.Sprintf("/events/%d", event.ID), "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var got storage.Event
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		require.Len(t, got.Attachments, 2)
+	})
+
+	t.Run("rejects invalid uploads", func(t *testing.T) {
+		rec := do(http.MethodPost, attachmentsPath+"?name=big.bin", "1", strings.Repeat("x", 17))
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+		req := httptest.NewRequest(http.MethodPost, attachmentsPath+"?name=big.bin", strings.NewReader(strings.Repeat("x", 17)))
+		req.Header.Set(UserIDHeader, "1")
+		req.ContentLength = -1
+		rec = httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, attachmentsPath, "1", "x").Code)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, attachmentsPath+"?name=../x", "1", "x").Code)
+		require.Equal(t, http.StatusNotFound, do(http.MethodPost, attachmentsPath+"?name=a", "2", "x").Code)
+
+		rec = do(http.MethodGet, attachmentsPath, "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var attachments []storage.Attachment
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &attachments))
+		require.Len(t, attachments, 2)
+	})
+
+	t.Run("deletes", func(t *testing.T) {
+		path := fmt.Sprintf("%s/%d", attachmentsPath, uploaded.ID)
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, path, "1", "").Code)
+		require.Equal(t, http.StatusNotFound, do(http.MethodGet, path, "1", "").Code)
+	})
+}
+
+func TestServer_Conference(t *testing.T) {
+	s := newTestServer(t)
+
+	batch := func(conference string) *httptest.ResponseRecorder {
+		body := `{"atomic":true,"operations":[{"op":"create","event":{"title":"Call",` +
+			`"start_time":"2025-03-01T10:00:00Z","end_time":"2025-03-01T11:00:00Z","conference":` + conference + `}}]}`
+		req := httptest.NewRequest(http.MethodPost, "/events/batch", strings.NewReader(body))
+		req.Header.Set(UserIDHeader, "1")
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := batch(`{"url":" https://meet.example.com/abc ","provider":"meet","passcode":"42"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"conference":{"url":"https://meet.example.com/abc","provider":"meet","passcode":"42"}`)
+
+	for _, conference := range []string{`{"url":""}`, `{"url":"meet.example.com/abc"}`, `{"url":"ftp://example.com"}`} {
+		require.Equal(t, http.StatusBadRequest, batch(conference).Code, conference)
+	}
+}
@@ -0,0 +1,109 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const batchRouteGroup = "batch"
+
+type batchOperation struct {
+	Op    string         `json:"op"`
+	Event *storage.Event `json:"event,omitempty"`
+	// ID identifies the event to delete.
+	ID int64 `json:"id,omitempty"`
+}
+
+type batchRequest struct {
+	// Atomic applies all operations or none of them.
+	Atomic     bool             `json:"atomic"`
+	Operations []batchOperation `json:"operations"`
+}
+
+type batchItemResult struct {
+	Index  int            `json:"index"`
+	Op     string         `json:"op"`
+	Status int            `json:"status"`
+	Event  *storage.Event `json:"event,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchItemResult `json:"results"`
+	// Failed is the number of operations that failed.
+	Failed int `json:"failed"`
+}
+
+// handleBatch creates, updates and deletes events of the user in bulk.
+// An atomic batch that fails responds with the status of the failed
+// operation and its index.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+	if len(req.Operations) == 0 {
+		s.writeError(w, storage.ValidationError("no operations"))
+		return
+	}
+	if len(req.Operations) > s.conf.MaxBatchSize {
+		s.writeError(w, storage.ValidationError(fmt.Sprintf("at most %d operations are allowed", s.conf.MaxBatchSize)))
+		return
+	}
+
+	ops := make([]storage.BatchOp, len(req.Operations))
+	for i, op := range req.Operations {
+		event := op.Event
+		if event == nil || op.Op == storage.BatchDelete {
+			event = &storage.Event{ID: op.ID}
+		}
+		ops[i] = storage.BatchOp{Type: op.Op, Event: event}
+	}
+
+	results, err := s.app.ApplyBatch(r.Context(), user, ops, req.Atomic)
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		code := errorStatus(batchErr.Err)
+		if code != http.StatusInternalServerError {
+			writeJSON(w, code, map[string]interface{}{"error": batchErr.Error(), "index": batchErr.Index})
+			return
+		}
+	}
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	resp := batchResponse{Results: make([]batchItemResult, len(results))}
+	for i, result := range results {
+		item := batchItemResult{Index: i, Op: ops[i].Type, Status: http.StatusOK, Event: result.Event}
+		if ops[i].Type == storage.BatchCreate {
+			item.Status = http.StatusCreated
+		}
+		if result.Err != nil {
+			resp.Failed++
+			item.Status = errorStatus(result.Err)
+			item.Event = nil
+			item.Error = result.Err.Error()
+			if item.Status == http.StatusInternalServerError {
+				s.logger.Error(fmt.Sprintf("batch operation %d failed: %s", i, item.Error))
+				item.Error = http.StatusText(item.Status)
+			}
+		}
+		resp.Results[i] = item
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,73 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Batch(t *testing.T) {
+	s := newTestServer(t)
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/events/batch", strings.NewReader(body))
+		req.Header.Set(UserIDHeader, "1")
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	const event = `{"title":"Sync","start_time":"2025-01-01T10:00:00Z","end_time":"2025-01-01T11:00:00Z"}`
+
+	t.Run("atomic batch fails as a whole", func(t *testing.T) {
+		rec := do(`{"atomic":true,"operations":[
+			{"op":"create","event":` + event + `},
+			{"op":"delete","id":1000}
+		]}`)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), `"index":1`)
+
+		rec = do(`{"operations":[{"op":"delete","id":1}]}`)
+		require.Contains(t, rec.Body.String(), `"status":404`)
+	})
+
+	t.Run("best effort batch reports each operation", func(t *testing.T) {
+		rec := do(`{"operations":[
+			{"op":"create","event":` + event + `},
+			{"op":"create","event":{"title":""}},
+			{"op":"move","id":1}
+		]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp batchResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, 2, resp.Failed)
+		require.Equal(t, http.StatusCreated, resp.Results[0].Status)
+		require.Equal(t, int64(1), resp.Results[0].Event.UserID)
+		require.Equal(t, http.StatusBadRequest, resp.Results[1].Status)
+		require.Equal(t, http.StatusBadRequest, resp.Results[2].Status)
+	})
+
+	t.Run("atomic batch applies all", func(t *testing.T) {
+		rec := do(`{"atomic":true,"operations":[
+			{"op":"create","event":` + event + `},
+			{"op":"delete","id":1}
+		]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp batchResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Zero(t, resp.Failed)
+		require.Equal(t, "Sync", resp.Results[1].Event.Title)
+	})
+
+	t.Run("limits batch size", func(t *testing.T) {
+		ops := strings.Repeat(`{"op":"delete","id":1},`, s.conf.MaxBatchSize)
+		rec := do(`{"operations":[` + ops + `{"op":"delete","id":1}]}`)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
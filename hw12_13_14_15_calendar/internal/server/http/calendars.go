@@ -0,0 +1,116 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+type calendarRequest struct {
+	Name            string `json:"name"`
+	Color           string `json:"color,omitempty"`
+	DefaultReminder int    `json:"default_reminder,omitempty"`
+	TimeZone        string `json:"time_zone,omitempty"`
+}
+
+func (req calendarRequest) calendar(userID int64) *storage.Calendar {
+	return &storage.Calendar{
+		UserID:          userID,
+		Name:            req.Name,
+		Color:           req.Color,
+		DefaultReminder: req.DefaultReminder,
+		TimeZone:        req.TimeZone,
+	}
+}
+
+func (s *Server) handleCreateCalendar(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req calendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+
+	calendar := req.calendar(user)
+	if err := s.app.CreateCalendar(r.Context(), calendar); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, calendar)
+}
+
+func (s *Server) handleListCalendars(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	calendars, err := s.app.ListCalendars(r.Context(), user)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if calendars == nil {
+		calendars = []*storage.Calendar{}
+	}
+
+	writeJSON(w, http.StatusOK, calendars)
+}
+
+func (s *Server) handleUpdateCalendar(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req calendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+
+	calendar := req.calendar(user)
+	calendar.ID = id
+	if err := s.app.UpdateCalendar(r.Context(), calendar); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, calendar)
+}
+
+// handleDeleteCalendar deletes a calendar with its events.
+func (s *Server) handleDeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if err := s.app.DeleteCalendar(r.Context(), user, id); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Calendars(t *testing.T) {
+	s := newTestServer(t)
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(UserIDHeader, user)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+	createCalendar := func(user, body string) storage.Calendar {
+		rec := do(http.MethodPost, "/calendars", user, body)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var calendar storage.Calendar
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &calendar))
+		return calendar
+	}
+	createEvent := func(user string, calendarID int64, notifyAt string) storage.Event {
+		event := fmt.Sprintf(`{"title":"Event","start_time":"2025-03-01T10:00:00Z","end_time":"2025-03-01T11:00:00Z",`+
+			`"calendar_id":%d%s}`, calendarID, notifyAt)
+		rec := do(http.MethodPost, "/events/batch", user, `{"atomic":true,"operations":[{"op":"create","event":`+event+`}]}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp batchResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return *resp.Results[0].Event
+	}
+	list := func(query string) []storage.Event {
+		rec := do(http.MethodGet, "/events?from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z"+query, "1", "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var events []storage.Event
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &events))
+		return events
+	}
+
+	work := createCalendar("1", `{"name":"Work","color":"#FF8800","default_reminder":15,"time_zone":"Europe/Berlin"}`)
+	require.Equal(t, "#ff8800", work.Color)
+	personal := createCalendar("1", `{"name":"Personal"}`)
+	require.Equal(t, "UTC", personal.TimeZone)
+	require.Equal(t, 0, personal.DefaultReminder)
+	other := createCalendar("2", `{"name":"Work"}`)
+
+	t.Run("validates", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/calendars", "1", `{"name":" "}`).Code)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/calendars", "1", `{"name":"x","time_zone":"Mars/Olympus"}`).Code)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/calendars", "1", `{"name":"x","time_zone":"Local"}`).Code)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/calendars", "1", `{"name":"x","default_reminder":-5}`).Code)
+		require.Equal(t, http.StatusConflict, do(http.MethodPost, "/calendars", "1", `{"name":"Work"}`).Code)
+	})
+
+	workEvent := createEvent("1", work.ID, "")
+	personalEvent := createEvent("1", personal.ID, `,"notify_at":"2025-03-01T09:00:00Z"`)
+	createEvent("1", 0, "")
+
+	t.Run("applies default reminder", func(t *testing.T) {
+		require.Equal(t, time.Date(2025, 3, 1, 9, 45, 0, 0, time.UTC), workEvent.NotifyAt.UTC())
+		require.Equal(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC), personalEvent.NotifyAt.UTC())
+	})
+
+	t.Run("rejects calendars of other users", func(t *testing.T) {
+		body := fmt.Sprintf(`{"atomic":true,"operations":[{"op":"create","event":{"title":"x",`+
+			`"start_time":"2025-03-01T10:00:00Z","end_time":"2025-03-01T11:00:00Z","calendar_id":%d}}]}`, other.ID)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/events/batch", "1", body).Code)
+	})
+
+	t.Run("filters listing by calendars", func(t *testing.T) {
+		require.Len(t, list(""), 3)
+		require.Len(t, list(fmt.Sprintf("&calendar=%d", work.ID)), 1)
+		require.Len(t, list(fmt.Sprintf("&calendar=%d&calendar=%d", work.ID, personal.ID)), 2)
+		require.Empty(t, list(fmt.Sprintf("&calendar=%d", other.ID)))
+
+		rec := do(http.MethodGet, "/events?from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z&calendar=0", "1", "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("updates calendars", func(t *testing.T) {
+		path := fmt.Sprintf("/calendars/%d", personal.ID)
+		require.Equal(t, http.StatusNotFound, do(http.MethodPut, path, "2", `{"name":"Mine"}`).Code)
+		require.Equal(t, http.StatusConflict, do(http.MethodPut, path, "1", `{"name":"Work"}`).Code)
+
+		rec := do(http.MethodPut, path, "1", `{"name":"Home","time_zone":"Asia/Tokyo"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Contains(t, rec.Body.String(), `"time_zone":"Asia/Tokyo"`)
+
+		rec = do(http.MethodGet, "/calendars", "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var calendars []storage.Calendar
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &calendars))
+		require.Equal(t, []string{"Home", "Work"}, []string{calendars[0].Name, calendars[1].Name})
+	})
+
+	t.Run("deletes calendars with their events", func(t *testing.T) {
+		path := fmt.Sprintf("/calendars/%d", work.ID)
+		require.Equal(t, http.StatusNotFound, do(http.MethodDelete, path, "2", "").Code)
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, path, "1", "").Code)
+		require.Equal(t, http.StatusNotFound, do(http.MethodDelete, path, "1", "").Code)
+
+		require.Equal(t, http.StatusNotFound, do(http.MethodGet, fmt.Sprintf("/events/%d", workEvent.ID), "1", "").Code)
+		require.Len(t, list(""), 2)
+	})
+}
@@ -0,0 +1,49 @@
+package internalhttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+)
+
+// CORSMiddleware adds CORS headers for allowed origins and answers preflight
+// requests. The settings are read on every request so they can change live.
+func CORSMiddleware(settings func() *config.CORSConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		conf := settings()
+		if origin == "" || !originAllowed(conf.AllowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", origin)
+
+		// Preflight request
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", strings.Join(conf.AllowedMethods, ", "))
+			h.Set("Access-Control-Allow-Headers", strings.Join(conf.AllowedHeaders, ", "))
+			if conf.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(conf.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package internalhttp
+
+import (
+	"errors"
+	"net/http"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// writeError responds with the status matching err.
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	code := errorStatus(err)
+	message := err.Error()
+	if code == http.StatusInternalServerError {
+		s.logger.Error("request failed: " + message)
+		message = http.StatusText(code)
+	}
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNoUser):
+		return http.StatusUnauthorized
+	case storage.IsValidationError(err):
+		return http.StatusBadRequest
+	case storage.IsNotFound(err):
+		return http.StatusNotFound
+	case storage.IsAlreadyExists(err):
+		return http.StatusConflict
+	case errors.Is(err, app.ErrAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, app.ErrWebhooksUnsupported), errors.Is(err, app.ErrBatchUnsupported),
+		errors.Is(err, app.ErrSearchUnsupported), errors.Is(err, app.ErrTagsUnsupported),
+		errors.Is(err, app.ErrAttachmentsUnsupported), errors.Is(err, app.ErrCalendarsUnsupported),
+		errors.Is(err, app.ErrNotificationsUnsupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
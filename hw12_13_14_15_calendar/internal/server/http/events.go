@@ -0,0 +1,91 @@
+package internalhttp
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// handleListEvents lists the user's events between from and to, in
+// RFC 3339. Repeated tag parameters keep events having all the tags,
+// repeated calendar parameters events in any of the calendars.
+func (s *Server) handleListEvents(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		s.writeError(w, storage.ValidationError("invalid from"))
+		return
+	}
+	to, err := time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		s.writeError(w, storage.ValidationError("invalid to"))
+		return
+	}
+
+	var filter app.EventFilter
+	if filter.TagIDs, err = queryIDs(query, "tag"); err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if filter.CalendarIDs, err = queryIDs(query, "calendar"); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	events, err := s.app.ListEvents(r.Context(), user, from, to, filter)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if events == nil {
+		events = []*storage.Event{}
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (s *Server) handleGetEvent(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	event, err := s.app.GetEvent(r.Context(), user, id)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, event)
+}
+
+// queryIDs parses the repeated ID parameter name of a query.
+func queryIDs(query url.Values, name string) ([]int64, error) {
+	ids := make([]int64, 0, len(query[name]))
+	for _, v := range query[name] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, storage.ValidationError("invalid " + name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
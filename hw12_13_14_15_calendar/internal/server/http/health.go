@@ -0,0 +1,109 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	statusUp           = "up"
+	statusDown         = "down"
+	statusReady        = "ready"
+	statusNotReady     = "not_ready"
+	statusShuttingDown = "shutting_down"
+
+	defaultCheckTimeout = 2 * time.Second
+)
+
+type componentStatus struct {
+	Status           string  `json:"status"`
+	Error            string  `json:"error,omitempty"`
+	LatencyMS        float64 `json:"latency_ms,omitempty"`
+	MigrationVersion *uint   `json:"migration_version,omitempty"`
+	MigrationDirty   bool    `json:"migration_dirty,omitempty"`
+}
+
+type readinessResponse struct {
+	Status        string                     `json:"status"`
+	Uptime        string                     `json:"uptime"`
+	UptimeSeconds float64                    `json:"uptime_seconds"`
+	Components    map[string]componentStatus `json:"components"`
+}
+
+// handleLiveness reports that the process is alive and serving requests.
+func (s *Server) handleLiveness(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": statusUp})
+}
+
+// handleReadiness reports whether the server can take traffic.
+// It turns not-ready as soon as graceful shutdown begins.
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	timeout := defaultCheckTimeout
+	if s.conf.Health.CheckTimeout > 0 {
+		timeout = time.Duration(s.conf.Health.CheckTimeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	uptime := time.Since(s.startedAt)
+	resp := readinessResponse{
+		Status:        statusReady,
+		Uptime:        uptime.Truncate(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+		Components: map[string]componentStatus{
+			"http":    {Status: statusUp},
+			"storage": s.checkStorage(ctx),
+		},
+	}
+
+	if s.shuttingDown.Load() {
+		resp.Components["http"] = componentStatus{Status: statusShuttingDown}
+	}
+
+	code := http.StatusOK
+	for _, c := range resp.Components {
+		if c.Status != statusUp {
+			resp.Status = statusNotReady
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	writeJSON(w, code, resp)
+}
+
+func (s *Server) checkStorage(ctx context.Context) componentStatus {
+	start := time.Now()
+	if err := s.app.Ping(ctx); err != nil {
+		return componentStatus{Status: statusDown, Error: err.Error()}
+	}
+
+	status := componentStatus{
+		Status:    statusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	version, dirty, ok, err := s.app.MigrationVersion(ctx)
+	switch {
+	case err != nil:
+		return componentStatus{Status: statusDown, Error: err.Error()}
+	case ok && dirty:
+		status.Status = statusDown
+		status.Error = "migration is dirty"
+	}
+	if ok {
+		status.MigrationVersion = &version
+		status.MigrationDirty = dirty
+	}
+
+	return status
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
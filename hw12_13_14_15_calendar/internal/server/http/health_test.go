@@ -0,0 +1,100 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	calendar := app.New(logg, memorystorage.New())
+	s := NewServer(logg, *calendar, config.GetDefaultConfig().Components.Server)
+	return s
+}
+
+func TestServer_Liveness(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"status":"up"}`, rec.Body.String())
+}
+
+func TestServer_Readiness(t *testing.T) {
+	s := newTestServer(t)
+
+	t.Run("ready", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp readinessResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, statusReady, resp.Status)
+		require.Equal(t, statusUp, resp.Components["storage"].Status)
+		require.Nil(t, resp.Components["storage"].MigrationVersion)
+	})
+
+	t.Run("not ready while shutting down", func(t *testing.T) {
+		require.NoError(t, s.Stop(context.Background()))
+
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		var resp readinessResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, statusNotReady, resp.Status)
+		require.Equal(t, statusShuttingDown, resp.Components["http"].Status)
+	})
+}
+
+func TestServer_ShutdownDelay(t *testing.T) {
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	conf := config.GetDefaultConfig().Components.Server
+	conf.Listener.Host, conf.Listener.Port = "127.0.0.1", 0
+	conf.Health.ShutdownDelay = 1
+	s := NewServer(logg, *app.New(logg, memorystorage.New()), conf)
+
+	l, err := s.Listen()
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(context.Background(), l) }()
+
+	stopped := make(chan error, 1)
+	begin := time.Now()
+	go func() { stopped <- s.Stop(context.Background()) }()
+
+	// The server keeps answering, but not ready, until the delay is over.
+	url := "http://" + l.Addr().String() + "/readyz"
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url) //nolint:noctx
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, <-stopped)
+	require.GreaterOrEqual(t, time.Since(begin), time.Second)
+	require.NoError(t, <-served)
+}
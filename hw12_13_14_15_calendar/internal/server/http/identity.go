@@ -0,0 +1,53 @@
+package internalhttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// UserIDHeader carries the ID of the calling user. The service has no
+// authentication of its own, so clients set it themselves unless they
+// are identified by a client certificate.
+const UserIDHeader = "X-User-ID"
+
+// ClientCertMiddleware maps the subject common name of a verified client
+// certificate to the user ID header. A request whose header names another
+// user is rejected.
+func ClientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if subject == "" {
+			writeJSON(w, http.StatusForbidden, map[string]string{
+				"error": "client certificate has no subject common name",
+			})
+			return
+		}
+
+		if user := r.Header.Get(UserIDHeader); user != "" && user != subject {
+			writeJSON(w, http.StatusForbidden, map[string]string{
+				"error": "user ID does not match client certificate",
+			})
+			return
+		}
+
+		r.Header.Set(UserIDHeader, subject)
+		next.ServeHTTP(w, r)
+	})
+}
+
+var errNoUser = errors.New("missing or invalid " + UserIDHeader + " header")
+
+// userID returns the ID of the calling user.
+func userID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.Header.Get(UserIDHeader), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errNoUser
+	}
+	return id, nil
+}
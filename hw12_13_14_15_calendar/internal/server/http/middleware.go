@@ -13,6 +13,16 @@ type responseWriter struct {
 	statusCode int
 }
 
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Middleware for HTTP request logging.
 func LoggingMiddleware(l *logger.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -42,6 +52,7 @@ func LoggingMiddleware(l *logger.Logger, next http.Handler) http.Handler {
 			Latency:     float64(duration.Milliseconds()),
 			UserAgent:   r.UserAgent(),
 		}
+		record.WithTrace(r.Context())
 
 		// Log the record
 		l.LogRequest(record)
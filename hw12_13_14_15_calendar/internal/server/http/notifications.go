@@ -0,0 +1,100 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+type notificationPreferencesRequest struct {
+	Channel    string `json:"channel,omitempty"`
+	QuietStart string `json:"quiet_start,omitempty"`
+	QuietEnd   string `json:"quiet_end,omitempty"`
+	Digest     bool   `json:"digest"`
+	DigestTime string `json:"digest_time,omitempty"`
+	TimeZone   string `json:"time_zone,omitempty"`
+}
+
+func (s *Server) handleGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	prefs, err := s.app.NotificationPreferences(r.Context(), user)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+func (s *Server) handleSetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req notificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+
+	prefs := &storage.NotificationPreferences{
+		UserID:     user,
+		Channel:    req.Channel,
+		QuietStart: req.QuietStart,
+		QuietEnd:   req.QuietEnd,
+		Digest:     req.Digest,
+		DigestTime: req.DigestTime,
+		TimeZone:   req.TimeZone,
+	}
+	if err := s.app.SetNotificationPreferences(r.Context(), prefs); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+func (s *Server) handleListNotificationHistory(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	var eventID int64
+	if v := query.Get("event"); v != "" {
+		if eventID, err = strconv.ParseInt(v, 10, 64); err != nil || eventID <= 0 {
+			s.writeError(w, storage.ValidationError("invalid event"))
+			return
+		}
+	}
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			s.writeError(w, storage.ValidationError("invalid limit"))
+			return
+		}
+	}
+
+	deliveries, err := s.app.ListNotificationHistory(r.Context(), user, eventID, limit)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*storage.NotificationDelivery{}
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
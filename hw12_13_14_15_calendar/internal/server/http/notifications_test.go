@@ -0,0 +1,73 @@
+package internalhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Notifications(t *testing.T) {
+	s := newTestServer(t)
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if user != "" {
+			req.Header.Set(UserIDHeader, user)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		rec := do(http.MethodGet, "/notifications/preferences", "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `"channel":"push"`)
+		require.Contains(t, rec.Body.String(), `"digest_time":"08:00"`)
+		require.Contains(t, rec.Body.String(), `"time_zone":"UTC"`)
+	})
+
+	t.Run("rejects invalid preferences", func(t *testing.T) {
+		for _, body := range []string{
+			`{"channel":"sms"}`,
+			`{"quiet_start":"22:00"}`,
+			`{"quiet_start":"22:00","quiet_end":"25:00"}`,
+			`{"quiet_start":"7:00","quiet_end":"08:00"}`,
+			`{"quiet_start":"22:00","quiet_end":"22:00"}`,
+			`{"digest_time":"noon"}`,
+			`{"time_zone":"Mars/Olympus"}`,
+			`{"time_zone":"Local"}`,
+		} {
+			rec := do(http.MethodPut, "/notifications/preferences", "1", body)
+			require.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		rec := do(http.MethodPut, "/notifications/preferences", "1",
+			`{"channel":"log","quiet_start":"22:00","quiet_end":"07:00","time_zone":"Europe/Berlin"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = do(http.MethodGet, "/notifications/preferences", "1", "")
+		require.Contains(t, rec.Body.String(), `"channel":"log"`)
+		require.Contains(t, rec.Body.String(), `"quiet_start":"22:00"`)
+		require.Contains(t, rec.Body.String(), `"time_zone":"Europe/Berlin"`)
+
+		rec = do(http.MethodGet, "/notifications/preferences", "2", "")
+		require.Contains(t, rec.Body.String(), `"channel":"push"`)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		rec := do(http.MethodGet, "/notifications/history?event=1&limit=10", "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `[]`, rec.Body.String())
+
+		rec = do(http.MethodGet, "/notifications/history?event=x", "1", "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = do(http.MethodGet, "/notifications/history?limit=-1", "1", "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,14 @@
+package internalhttp
+
+import (
+	"net/http"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/api"
+)
+
+// handleOpenAPI serves the OpenAPI document of the HTTP API.
+func handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(api.OpenAPI)
+}
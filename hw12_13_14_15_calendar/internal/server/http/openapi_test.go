@@ -0,0 +1,194 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/api"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/blob"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// undocumentedRoutes are operational endpoints left out of the API spec.
+var undocumentedRoutes = map[string]bool{
+	"/hello":        true,
+	"/healthz":      true,
+	"/readyz":       true,
+	"/debug/vars":   true,
+	"/openapi.json": true,
+}
+
+var routeVarPattern = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+type openAPIDoc struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// TestOpenAPI_MatchesRoutes fails when setupRoutes and the spec disagree
+// on the documented operations.
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	var routed []string
+	err := s.router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		if undocumentedRoutes[path] {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		require.NoError(t, err, path)
+
+		path = routeVarPattern.ReplaceAllString(path, "{$1}")
+		for _, method := range methods {
+			routed = append(routed, method+" "+path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var doc openAPIDoc
+	require.NoError(t, json.Unmarshal(api.OpenAPI, &doc))
+	require.True(t, strings.HasPrefix(doc.OpenAPI, "3."))
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routed)
+	sort.Strings(documented)
+	require.Equal(t, documented, routed)
+}
+
+// TestOpenAPI_ResponsesMatchSchemas fails when a documented operation
+// responds with a body its schema in the spec does not describe.
+func TestOpenAPI_ResponsesMatchSchemas(t *testing.T) {
+	ctx := context.Background()
+	doc, err := openapi3.NewLoader().LoadFromData(api.OpenAPI)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(ctx))
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+
+	s := newTestServer(t)
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	s.app.UseBlobStore(blobs, 1024)
+
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	event := &storage.Event{
+		Title: "Standup", Description: "Daily sync", UserID: 1,
+		StartTime: start, EndTime: start.Add(15 * time.Minute), NotifyAt: start.Add(-10 * time.Minute),
+	}
+	require.NoError(t, s.app.CreateEvent(ctx, event))
+
+	// The requests run in order, so later ones use the records earlier
+	// ones created. The event stream never ends and is left out.
+	requests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/events?from=2025-03-10T00:00:00Z&to=2025-03-11T00:00:00Z", "", http.StatusOK},
+		{"GET", "/events/1", "", http.StatusOK},
+		{"GET", "/events/99", "", http.StatusNotFound},
+		{"GET", "/events/search?q=standup", "", http.StatusOK},
+		{"POST", "/events/batch", `{"operations":[{"op":"create","event":{"title":"Retro",` +
+			`"start_time":"2025-03-10T15:00:00Z","end_time":"2025-03-10T16:00:00Z"}}]}`, http.StatusOK},
+		{"POST", "/calendars", `{"name":"Work"}`, http.StatusCreated},
+		{"GET", "/calendars", "", http.StatusOK},
+		{"PUT", "/calendars/1", `{"name":"Office","time_zone":"Europe/Berlin"}`, http.StatusOK},
+		{"POST", "/tags", `{"name":"daily"}`, http.StatusCreated},
+		{"GET", "/tags", "", http.StatusOK},
+		{"PUT", "/tags/1", `{"name":"sync"}`, http.StatusOK},
+		{"PUT", "/events/1/tags", `{"tag_ids":[1]}`, http.StatusOK},
+		{"GET", "/events/1/tags", "", http.StatusOK},
+		{"POST", "/events/1/attachments?name=agenda.txt", "1. Intro", http.StatusCreated},
+		{"GET", "/events/1/attachments", "", http.StatusOK},
+		{"GET", "/events/1/attachments/1", "", http.StatusOK},
+		{"DELETE", "/events/1/attachments/1", "", http.StatusNoContent},
+		{"POST", "/webhooks", `{"url":"https://example.com/hook"}`, http.StatusCreated},
+		{"GET", "/webhooks", "", http.StatusOK},
+		{"GET", "/webhooks/1/deliveries", "", http.StatusOK},
+		{"PUT", "/notifications/preferences", `{"channel":"log","quiet_start":"22:00","quiet_end":"07:00"}`,
+			http.StatusOK},
+		{"GET", "/notifications/preferences", "", http.StatusOK},
+		{"GET", "/notifications/history", "", http.StatusOK},
+		{"DELETE", "/webhooks/1", "", http.StatusNoContent},
+		{"DELETE", "/tags/1", "", http.StatusNoContent},
+		{"DELETE", "/calendars/1", "", http.StatusNoContent},
+	}
+
+	covered := map[string]bool{"GET /events/stream": true}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set(UserIDHeader, "1")
+		switch {
+		case strings.HasPrefix(r.body, "{"):
+			req.Header.Set("Content-Type", "application/json")
+		case r.body != "":
+			// An attachment.
+			req.Header.Set("Content-Type", "text/plain")
+		}
+		rec := httptest.NewRecorder()
+		s.handler.ServeHTTP(rec, req)
+		require.Equal(t, r.status, rec.Code, "%s %s: %s", r.method, r.path, rec.Body.String())
+
+		route, params, err := router.FindRoute(req)
+		require.NoError(t, err, "%s %s", r.method, r.path)
+		covered[r.method+" "+route.Path] = true
+
+		err = openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: params,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			},
+			Status: rec.Code,
+			Header: rec.Header(),
+			Body:   io.NopCloser(rec.Body),
+			Options: &openapi3filter.Options{
+				IncludeResponseStatus: true,
+				MultiError:            true,
+			},
+		})
+		require.NoError(t, err, "%s %s", r.method, r.path)
+	}
+
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			require.True(t, covered[method+" "+path], "%s %s is not checked", method, path)
+		}
+	}
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	s := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, string(api.OpenAPI), w.Body.String())
+}
@@ -0,0 +1,173 @@
+package internalhttp
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+)
+
+const (
+	defaultRouteGroup = "default"
+	sweepInterval     = time.Minute
+)
+
+// RateLimiter is a token bucket rate limiter keyed by route group and client.
+type RateLimiter struct {
+	mu        sync.Mutex
+	conf      config.RateLimitConfig
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	group  string
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(conf config.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		conf:      conf,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Update replaces the limiter settings. Existing buckets keep their tokens.
+func (l *RateLimiter) Update(conf config.RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conf = conf
+}
+
+// Allow takes a token from the bucket of client in group. When the bucket
+// is empty it returns false and the time until the next token.
+func (l *RateLimiter) Allow(group, client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate, ok := l.rate(group)
+	if !ok {
+		return true, 0
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	key := group + "|" + client
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{group: group, tokens: float64(rate.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(rate.Burst), b.tokens+now.Sub(b.last).Seconds()*rate.RPS)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate.RPS * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// Middleware rejects requests of clients that exceeded the rate of group
+// with 429 Too Many Requests and a Retry-After header.
+func (l *RateLimiter) Middleware(group string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := l.Allow(group, l.clientKey(r))
+		if !allowed {
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{
+				"error": fmt.Sprintf("rate limit exceeded, retry in %ds", seconds),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rate returns the active rate of group, falling back to the default group.
+func (l *RateLimiter) rate(group string) (config.RateConfig, bool) {
+	if !l.conf.Enabled {
+		return config.RateConfig{}, false
+	}
+
+	rate, ok := l.conf.Groups[group]
+	if !ok {
+		rate, ok = l.conf.Groups[defaultRouteGroup]
+	}
+	if !ok || rate.RPS <= 0 {
+		return config.RateConfig{}, false
+	}
+	if rate.Burst < 1 {
+		rate.Burst = 1
+	}
+
+	return rate, true
+}
+
+// sweep drops buckets that have refilled, as a new bucket starts full anyway.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		rate, ok := l.rate(b.group)
+		if !ok || b.tokens+now.Sub(b.last).Seconds()*rate.RPS >= float64(rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	l.mu.Lock()
+	header := l.conf.UserHeader
+	l.mu.Unlock()
+
+	if header != "" {
+		if user := r.Header.Get(header); user != "" {
+			return "user:" + user
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// BodyLimitMiddleware caps the size of request bodies.
+func BodyLimitMiddleware(limit int64, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{
+				"error": "request body too large",
+			})
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,129 @@
+package internalhttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitConfig{
+		Enabled: true,
+		Groups: map[string]config.RateConfig{
+			"default": {RPS: 1, Burst: 2},
+			"events":  {RPS: 10, Burst: 1},
+		},
+	})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	t.Run("burst then reject", func(t *testing.T) {
+		ok, _ := limiter.Allow("default", "a")
+		require.True(t, ok)
+		ok, _ = limiter.Allow("default", "a")
+		require.True(t, ok)
+
+		ok, wait := limiter.Allow("default", "a")
+		require.False(t, ok)
+		require.Equal(t, time.Second, wait)
+	})
+
+	t.Run("clients and groups have own buckets", func(t *testing.T) {
+		ok, _ := limiter.Allow("default", "b")
+		require.True(t, ok)
+		ok, _ = limiter.Allow("events", "a")
+		require.True(t, ok)
+	})
+
+	t.Run("unknown group uses default", func(t *testing.T) {
+		ok, _ := limiter.Allow("other", "c")
+		require.True(t, ok)
+		ok, _ = limiter.Allow("other", "c")
+		require.True(t, ok)
+		ok, _ = limiter.Allow("other", "c")
+		require.False(t, ok)
+	})
+
+	t.Run("tokens refill", func(t *testing.T) {
+		now = now.Add(time.Second)
+		ok, _ := limiter.Allow("default", "a")
+		require.True(t, ok)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		limiter.Update(config.RateLimitConfig{Enabled: false})
+		for i := 0; i < 10; i++ {
+			ok, _ := limiter.Allow("default", "a")
+			require.True(t, ok)
+		}
+	})
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitConfig{
+		Enabled:    true,
+		UserHeader: "X-User-ID",
+		Groups:     map[string]config.RateConfig{"default": {RPS: 0.5, Burst: 1}},
+	})
+	handler := limiter.Middleware("default", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if user != "" {
+			req.Header.Set("X-User-ID", user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, send("1").Code)
+
+	rec := send("1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusOK, send("2").Code)
+	require.Equal(t, http.StatusOK, send("").Code)
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	handler := BodyLimitMiddleware(4, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var maxErr *http.MaxBytesError
+		if _, err := io.ReadAll(r.Body); errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		code          int
+	}{
+		{name: "within limit", body: "ok", contentLength: 2, code: http.StatusOK},
+		{name: "declared too large", body: "too long", contentLength: 8, code: http.StatusRequestEntityTooLarge},
+		{name: "streamed too large", body: "too long", contentLength: -1, code: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.ContentLength = tc.contentLength
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tc.code, rec.Code)
+		})
+	}
+}
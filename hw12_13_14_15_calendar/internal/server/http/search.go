@@ -0,0 +1,50 @@
+package internalhttp
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// handleSearchEvents searches the user's events. Parameters: q, and
+// optional from and to in RFC 3339 and limit.
+func (s *Server) handleSearchEvents(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	var from, to time.Time
+	for name, dest := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := query.Get(name); v != "" {
+			if *dest, err = time.Parse(time.RFC3339, v); err != nil {
+				s.writeError(w, storage.ValidationError("invalid "+name))
+				return
+			}
+		}
+	}
+
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			s.writeError(w, storage.ValidationError("invalid limit"))
+			return
+		}
+	}
+
+	results, err := s.app.SearchEvents(r.Context(), user, query.Get("q"), from, to, limit)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if results == nil {
+		results = []*storage.SearchResult{}
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
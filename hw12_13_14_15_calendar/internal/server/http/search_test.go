@@ -0,0 +1,45 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_SearchEvents(t *testing.T) {
+	s := newTestServer(t)
+	start := time.Date(2024, 4, 10, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, s.app.CreateEvent(context.Background(), &storage.Event{
+		Title:     "Vendor meeting",
+		UserID:    1,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+	}))
+
+	search := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/events/search?"+query, nil)
+		req.Header.Set(UserIDHeader, "1")
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := search("q=vend&from=2024-01-01T00:00:00Z&to=2024-12-31T00:00:00Z")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var results []storage.SearchResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	require.Len(t, results, 1)
+	require.Equal(t, "<mark>Vendor</mark> meeting", results[0].TitleHighlight)
+
+	rec = search("q=vendor&from=2025-01-01T00:00:00Z")
+	require.JSONEq(t, `[]`, rec.Body.String())
+
+	require.Equal(t, http.StatusBadRequest, search("q=%20!").Code)
+	require.Equal(t, http.StatusBadRequest, search("q=vendor&from=yesterday").Code)
+}
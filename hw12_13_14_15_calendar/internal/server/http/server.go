@@ -2,25 +2,42 @@ package internalhttp
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net"
 	"net/http"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	//nolint:depguard
 	app "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/pubsub"
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/server/http/handlers"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/server/tlsconfig"
 	"github.com/gorilla/mux"
 )
 
 type Server struct {
-	logger *logger.Logger
-	app    app.App
-	server *http.Server
-	router *mux.Router
-	conf   config.ServerConfig
+	logger  *logger.Logger
+	app     app.App
+	server  *http.Server
+	router  *mux.Router
+	handler http.Handler
+	conf    config.ServerConfig
+	cors    atomic.Pointer[config.CORSConfig]
+	limiter *RateLimiter
+	broker  *pubsub.Broker
+
+	// streams is canceled on shutdown to end event streams, which
+	// would otherwise hold it up.
+	streams     context.Context
+	stopStreams context.CancelFunc
+
+	startedAt    time.Time
+	shuttingDown atomic.Bool
 }
 
 func NewServer(logger *logger.Logger, app app.App, conf config.ServerConfig) *Server {
@@ -29,37 +46,149 @@ func NewServer(logger *logger.Logger, app app.App, conf config.ServerConfig) *Se
 		app:    app,
 		router: mux.NewRouter(),
 		conf:   conf,
+
+		limiter:   NewRateLimiter(conf.RateLimit),
+		broker:    pubsub.NewBroker(conf.Stream.LogSize, conf.Stream.BufferSize),
+		startedAt: time.Now(),
+	}
+
+	timeouts := conf.Timeouts
+	s.server = &http.Server{
+		ReadTimeout:       time.Duration(timeouts.Read) * time.Second,
+		ReadHeaderTimeout: time.Duration(timeouts.ReadHeader) * time.Second,
+		WriteTimeout:      time.Duration(timeouts.Write) * time.Second,
+		IdleTimeout:       time.Duration(timeouts.Idle) * time.Second,
 	}
 
+	s.streams, s.stopStreams = context.WithCancel(context.Background())
+	app.OnChange(s.broker.Publish)
+	s.cors.Store(&conf.CORS)
 	s.setupRoutes()
 	return s
 }
 
+// ApplyConfig applies the settings that are safe to change while
+// the server is running.
+func (s *Server) ApplyConfig(conf config.ServerConfig) {
+	s.cors.Store(&conf.CORS)
+	s.limiter.Update(conf.RateLimit)
+}
+
 func (s *Server) setupRoutes() {
+	s.router.Use(RouteSpanMiddleware)
 	s.router.Use(func(next http.Handler) http.Handler {
 		return LoggingMiddleware(s.logger, next)
 	})
-	s.router.HandleFunc("/hello", handlers.HandleHello).Methods("GET")
+	s.router.Use(ClientCertMiddleware)
+	s.router.Use(func(next http.Handler) http.Handler {
+		limited := BodyLimitMiddleware(s.conf.MaxBodySize, next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil && route.GetName() == uploadRoute {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	})
+	// CORS wraps the whole router so preflight requests are answered
+	// before route method matching rejects them.
+	s.handler = TracingMiddleware(CORSMiddleware(s.cors.Load, s.router))
+	s.server.Handler = s.handler
+
+	s.router.Handle("/hello", s.limit(defaultRouteGroup, handlers.HandleHello)).Methods("GET")
+	s.router.HandleFunc("/healthz", s.handleLiveness).Methods("GET")
+	s.router.HandleFunc("/readyz", s.handleReadiness).Methods("GET")
+	s.router.Handle("/debug/vars", expvar.Handler()).Methods("GET")
+	s.router.HandleFunc("/openapi.json", handleOpenAPI).Methods("GET")
+
+	s.router.Handle("/events", s.limit(defaultRouteGroup, s.handleListEvents)).Methods("GET")
+	s.router.Handle("/events/{id:[0-9]+}", s.limit(defaultRouteGroup, s.handleGetEvent)).Methods("GET")
+	s.router.Handle("/events/{id:[0-9]+}/attachments",
+		s.limit(defaultRouteGroup, s.handleUploadAttachment)).Methods("POST").Name(uploadRoute)
+	s.router.Handle("/events/{id:[0-9]+}/attachments",
+		s.limit(defaultRouteGroup, s.handleListAttachments)).Methods("GET")
+	s.router.Handle("/events/{id:[0-9]+}/attachments/{attachment:[0-9]+}",
+		s.limit(defaultRouteGroup, s.handleDownloadAttachment)).Methods("GET")
+	s.router.Handle("/events/{id:[0-9]+}/attachments/{attachment:[0-9]+}",
+		s.limit(defaultRouteGroup, s.handleDeleteAttachment)).Methods("DELETE")
+	s.router.Handle("/events/{id:[0-9]+}/tags", s.limit(defaultRouteGroup, s.handleGetEventTags)).Methods("GET")
+	s.router.Handle("/events/{id:[0-9]+}/tags", s.limit(defaultRouteGroup, s.handleSetEventTags)).Methods("PUT")
+	s.router.Handle("/events/batch", s.limit(batchRouteGroup, s.handleBatch)).Methods("POST")
+	s.router.Handle("/events/search", s.limit(defaultRouteGroup, s.handleSearchEvents)).Methods("GET")
+	s.router.Handle("/events/stream", s.limit(defaultRouteGroup, s.handleEventStream)).Methods("GET")
+
+	s.router.Handle("/calendars", s.limit(defaultRouteGroup, s.handleCreateCalendar)).Methods("POST")
+	s.router.Handle("/calendars", s.limit(defaultRouteGroup, s.handleListCalendars)).Methods("GET")
+	s.router.Handle("/calendars/{id:[0-9]+}", s.limit(defaultRouteGroup, s.handleUpdateCalendar)).Methods("PUT")
+	s.router.Handle("/calendars/{id:[0-9]+}", s.limit(defaultRouteGroup, s.handleDeleteCalendar)).Methods("DELETE")
+	s.router.Handle("/tags", s.limit(defaultRouteGroup, s.handleCreateTag)).Methods("POST")
+	s.router.Handle("/tags", s.limit(defaultRouteGroup, s.handleListTags)).Methods("GET")
+	s.router.Handle("/tags/{id:[0-9]+}", s.limit(defaultRouteGroup, s.handleUpdateTag)).Methods("PUT")
+	s.router.Handle("/tags/{id:[0-9]+}", s.limit(defaultRouteGroup, s.handleDeleteTag)).Methods("DELETE")
+
+	s.router.Handle("/webhooks", s.limit(defaultRouteGroup, s.handleCreateWebhook)).Methods("POST")
+	s.router.Handle("/webhooks", s.limit(defaultRouteGroup, s.handleListWebhooks)).Methods("GET")
+	s.router.Handle("/webhooks/{id:[0-9]+}", s.limit(defaultRouteGroup, s.handleDeleteWebhook)).Methods("DELETE")
+	s.router.Handle("/webhooks/{id:[0-9]+}/deliveries",
+		s.limit(defaultRouteGroup, s.handleListWebhookDeliveries)).Methods("GET")
+
+	s.router.Handle("/notifications/preferences",
+		s.limit(defaultRouteGroup, s.handleGetNotificationPreferences)).Methods("GET")
+	s.router.Handle("/notifications/preferences",
+		s.limit(defaultRouteGroup, s.handleSetNotificationPreferences)).Methods("PUT")
+	s.router.Handle("/notifications/history",
+		s.limit(defaultRouteGroup, s.handleListNotificationHistory)).Methods("GET")
 
 	// s.router.HandleFunc("/events/{id}", s.handleDeleteEvent).Methods("DELETE")
+}
 
-	// s.router.HandleFunc("/events", s.handleListEvents).Methods("GET")
+// limit applies the rate limit of group to handler.
+func (s *Server) limit(group string, handler http.HandlerFunc) http.Handler {
+	return s.limiter.Middleware(group, handler)
 }
 
 func (s *Server) Start(ctx context.Context) error {
+	l, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, l)
+}
+
+// Listen binds the configured address for Serve.
+func (s *Server) Listen() (net.Listener, error) {
 	addr := net.JoinHostPort(s.conf.Listener.Host, strconv.Itoa(s.conf.Listener.Port))
-	s.server = &http.Server{
-		Addr:    addr,
-		Handler: s.router,
+	return net.Listen("tcp", addr)
+}
+
+// Serve serves on l until Stop is called. ctx bounds background work such
+// as reloading certificates; canceling it does not stop the server.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	serve := func() error {
+		return s.server.Serve(l)
 	}
+	scheme := "HTTP"
+	if s.conf.Listener.TLS.Enabled() {
+		certs, err := tlsconfig.New(s.conf.Listener.TLS)
+		if err != nil {
+			l.Close()
+			return err
+		}
+		s.server.TLSConfig = certs.Config()
+
+		go certs.Watch(ctx, func(err error) {
+			s.logger.Error("failed to reload HTTP server certificates: " + err.Error())
+		})
 
-	go func() {
-		<-ctx.Done()
-		s.Stop(context.Background())
-	}()
+		serve = func() error {
+			return s.server.ServeTLS(l, "", "")
+		}
+		scheme = "HTTPS"
+	}
 
-	s.logger.Info(fmt.Sprintf("Starting HTTP server on %s", addr))
-	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+	s.logger.Info(fmt.Sprintf("Starting %s server on %s", scheme, l.Addr()))
+	if err := serve(); err != http.ErrServerClosed {
 		return err
 	}
 
@@ -68,5 +197,19 @@ func (s *Server) Start(ctx context.Context) error {
 
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping HTTP server")
+
+	// Fail readiness first and give the load balancer time to notice
+	// before in-flight requests are drained.
+	if !s.shuttingDown.Swap(true) && s.conf.Health.ShutdownDelay > 0 {
+		delay := time.Duration(s.conf.Health.ShutdownDelay) * time.Second
+		s.logger.Info(fmt.Sprintf("Waiting %s before draining connections", delay))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	s.stopStreams()
 	return s.server.Shutdown(ctx)
 }
@@ -0,0 +1,112 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/pubsub"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const defaultHeartbeat = 15 * time.Second
+
+// streamResetEvent tells the client that changes were missed and it
+// should reload its events.
+const streamResetEvent = "reset"
+
+// handleEventStream streams changes of the user's events as server-sent
+// events. A reconnecting client resumes after its Last-Event-ID.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	lastID, resume := s.broker.LastID(), false
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		if lastID, err = strconv.ParseUint(header, 10, 64); err != nil {
+			s.writeError(w, storage.ValidationError("invalid Last-Event-ID"))
+			return
+		}
+		resume = true
+	}
+
+	// The stream outlives the server write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.writeError(w, err)
+		return
+	}
+
+	sub, backlog, complete := s.broker.Subscribe(user, lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if resume && !complete {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", streamResetEvent)
+	}
+	for _, entry := range backlog {
+		if err := writeStreamEntry(w, entry); err != nil {
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	interval := defaultHeartbeat
+	if s.conf.Stream.Heartbeat > 0 {
+		interval = time.Duration(s.conf.Stream.Heartbeat) * time.Second
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.streams.Done():
+			return
+		case entry, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				// and resumes from the log.
+				return
+			}
+			if err := writeStreamEntry(w, entry); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEntry(w io.Writer, entry pubsub.Entry) error {
+	data, err := json.Marshal(entry.Change)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", entry.ID, entry.Change.Type, data)
+	return err
+}
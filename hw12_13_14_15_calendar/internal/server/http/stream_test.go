@@ -0,0 +1,92 @@
+package internalhttp
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// readStreamEvent reads the next event, skipping comments.
+func readStreamEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && len(fields) > 0:
+			return fields
+		case line == "", strings.HasPrefix(line, ":"):
+		default:
+			name, value, _ := strings.Cut(line, ": ")
+			fields[name] = value
+		}
+	}
+}
+
+func TestServer_EventStream(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.handler)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	open := func(lastEventID string) (*bufio.Reader, func()) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/stream", nil)
+		require.NoError(t, err)
+		req.Header.Set(UserIDHeader, "1")
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return bufio.NewReader(resp.Body), func() { resp.Body.Close() }
+	}
+
+	stream, closeStream := open("")
+
+	event := &storage.Event{Title: "Standup", UserID: 1, StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)}
+	require.NoError(t, s.app.CreateEvent(ctx, event))
+	require.NoError(t, s.app.CreateEvent(ctx, &storage.Event{Title: "Other", UserID: 2}))
+	event.Title = "Daily standup"
+	require.NoError(t, s.app.UpdateEvent(ctx, event))
+
+	created := readStreamEvent(t, stream)
+	require.Equal(t, "event.created", created["event"])
+	require.Contains(t, created["data"], `"title":"Standup"`)
+
+	updated := readStreamEvent(t, stream)
+	require.Equal(t, "event.updated", updated["event"])
+	closeStream()
+
+	t.Run("resumes after Last-Event-ID", func(t *testing.T) {
+		require.NoError(t, s.app.DeleteEvent(ctx, event.ID))
+
+		stream, closeStream := open(created["id"])
+		defer closeStream()
+
+		require.Equal(t, "event.updated", readStreamEvent(t, stream)["event"])
+		require.Equal(t, "event.deleted", readStreamEvent(t, stream)["event"])
+	})
+
+	t.Run("asks to reset after unknown Last-Event-ID", func(t *testing.T) {
+		stream, closeStream := open("1000")
+		defer closeStream()
+
+		require.Equal(t, streamResetEvent, readStreamEvent(t, stream)["event"])
+	})
+}
@@ -0,0 +1,159 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+type tagRequest struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+type eventTagsRequest struct {
+	TagIDs []int64 `json:"tag_ids"`
+}
+
+func (s *Server) handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+
+	tag, err := s.app.CreateTag(r.Context(), user, req.Name, req.Kind, req.Color)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tag)
+}
+
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	tags, err := s.app.ListTags(r.Context(), user)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNilTags(tags))
+}
+
+func (s *Server) handleUpdateTag(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+
+	tag, err := s.app.UpdateTag(r.Context(), user, id, req.Name, req.Color)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+func (s *Server) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if err := s.app.DeleteTag(r.Context(), user, id); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetEventTags(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	tags, err := s.app.GetEventTags(r.Context(), user, id)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNilTags(tags))
+}
+
+func (s *Server) handleSetEventTags(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req eventTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+
+	tags, err := s.app.SetEventTags(r.Context(), user, id, req.TagIDs)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNilTags(tags))
+}
+
+func nonNilTags(tags []*storage.Tag) []*storage.Tag {
+	if tags == nil {
+		return []*storage.Tag{}
+	}
+	return tags
+}
@@ -0,0 +1,92 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Tags(t *testing.T) {
+	s := newTestServer(t)
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(UserIDHeader, user)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+	createTag := func(body string) storage.Tag {
+		rec := do(http.MethodPost, "/tags", "1", body)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var tag storage.Tag
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tag))
+		return tag
+	}
+
+	work := createTag(`{"name":"Work","color":"#1E90FF"}`)
+	require.Equal(t, storage.TagKindTag, work.Kind)
+	require.Equal(t, "#1e90ff", work.Color)
+	meetings := createTag(`{"name":"Meetings","kind":"category"}`)
+	calls := createTag(`{"name":"Calls","kind":"category","color":"#00ff00"}`)
+
+	t.Run("validates", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/tags", "1", `{"name":"x","color":"red"}`).Code)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/tags", "1", `{"name":"x","kind":"label"}`).Code)
+		require.Equal(t, http.StatusConflict, do(http.MethodPost, "/tags", "1", `{"name":"Work"}`).Code)
+	})
+
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	event := &storage.Event{Title: "Sync", UserID: 1, StartTime: start, EndTime: start.Add(time.Hour)}
+	require.NoError(t, s.app.CreateEvent(context.Background(), event))
+	tagsPath := fmt.Sprintf("/events/%d/tags", event.ID)
+
+	t.Run("tags events", func(t *testing.T) {
+		rec := do(http.MethodPut, tagsPath, "1", fmt.Sprintf(`{"tag_ids":[%d,%d,%d]}`, work.ID, meetings.ID, calls.ID))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = do(http.MethodPut, tagsPath, "2", fmt.Sprintf(`{"tag_ids":[%d]}`, work.ID))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = do(http.MethodPut, tagsPath, "1", fmt.Sprintf(`{"tag_ids":[%d,%d]}`, work.ID, meetings.ID))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var tags []storage.Tag
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tags))
+		require.Len(t, tags, 2)
+	})
+
+	t.Run("filters listing by tags", func(t *testing.T) {
+		list := func(query string) []storage.Event {
+			rec := do(http.MethodGet, "/events?from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z"+query, "1", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			var events []storage.Event
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &events))
+			return events
+		}
+
+		require.Len(t, list(""), 1)
+		require.Len(t, list(fmt.Sprintf("&tag=%d&tag=%d", work.ID, meetings.ID)), 1)
+		require.Empty(t, list(fmt.Sprintf("&tag=%d", calls.ID)))
+	})
+
+	t.Run("updates and deletes tags", func(t *testing.T) {
+		rec := do(http.MethodPut, fmt.Sprintf("/tags/%d", work.ID), "1", `{"name":"Office","color":"#000000"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `"name":"Office"`)
+
+		require.Equal(t, http.StatusNotFound, do(http.MethodDelete, fmt.Sprintf("/tags/%d", work.ID), "2", "").Code)
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, fmt.Sprintf("/tags/%d", work.ID), "1", "").Code)
+
+		rec = do(http.MethodGet, tagsPath, "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotContains(t, rec.Body.String(), "Office")
+	})
+}
@@ -0,0 +1,124 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+type createWebhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// createWebhookResponse shows the secret, which is not returned again.
+type createWebhookResponse struct {
+	*storage.Webhook
+	Secret string `json:"secret"`
+}
+
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, storage.ValidationError("invalid request body"))
+		return
+	}
+
+	webhook, err := s.app.CreateWebhook(r.Context(), user, req.URL, req.Secret)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createWebhookResponse{Webhook: webhook, Secret: webhook.Secret})
+}
+
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	webhooks, err := s.app.ListWebhooks(r.Context(), user)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if webhooks == nil {
+		webhooks = []*storage.Webhook{}
+	}
+
+	writeJSON(w, http.StatusOK, webhooks)
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if err := s.app.DeleteWebhook(r.Context(), user, id); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			s.writeError(w, storage.ValidationError("invalid limit"))
+			return
+		}
+	}
+
+	deliveries, err := s.app.ListWebhookDeliveries(r.Context(), user, id, limit)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*storage.WebhookDelivery{}
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// pathID parses the id route variable.
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, storage.ValidationError("invalid id")
+	}
+	return id, nil
+}
@@ -0,0 +1,76 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Webhooks(t *testing.T) {
+	s := newTestServer(t)
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if user != "" {
+			req.Header.Set(UserIDHeader, user)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("requires user", func(t *testing.T) {
+		rec := do(http.MethodGet, "/webhooks", "", "")
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("rejects invalid url", func(t *testing.T) {
+		rec := do(http.MethodPost, "/webhooks", "1", `{"url":"ftp://example.com"}`)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	rec := do(http.MethodPost, "/webhooks", "1", `{"url":"https://example.com/hook"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created struct {
+		ID     int64  `json:"id"`
+		URL    string `json:"url"`
+		Secret string `json:"secret"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, "https://example.com/hook", created.URL)
+	require.NotEmpty(t, created.Secret)
+
+	t.Run("list hides secret", func(t *testing.T) {
+		rec := do(http.MethodGet, "/webhooks", "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotContains(t, rec.Body.String(), created.Secret)
+		require.Contains(t, rec.Body.String(), created.URL)
+
+		rec = do(http.MethodGet, "/webhooks", "2", "")
+		require.JSONEq(t, `[]`, rec.Body.String())
+	})
+
+	t.Run("other users cannot see deliveries", func(t *testing.T) {
+		rec := do(http.MethodGet, "/webhooks/1/deliveries", "2", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = do(http.MethodGet, "/webhooks/1/deliveries", "1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `[]`, rec.Body.String())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		rec := do(http.MethodDelete, "/webhooks/1", "2", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = do(http.MethodDelete, "/webhooks/1", "1", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = do(http.MethodDelete, "/webhooks/1", "1", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
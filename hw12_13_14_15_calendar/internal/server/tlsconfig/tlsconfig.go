@@ -0,0 +1,162 @@
+// Package tlsconfig builds server TLS configurations whose certificates
+// reload when the files change on disk. It is shared by every listener.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+)
+
+var ErrNoCertificates = errors.New("no certificates found")
+
+// Reloader holds the certificate and client CA pool of a listener.
+type Reloader struct {
+	conf config.TLSConfig
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+	// failedModTimes are the modification times of the files the last
+	// failed reload by Watch read, so it is not retried until they change.
+	failedModTimes map[string]time.Time
+}
+
+// New loads the files referenced by conf.
+func New(conf config.TLSConfig) (*Reloader, error) {
+	r := &Reloader{conf: conf}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate, key and client CA again. On error the
+// previously loaded files stay in use.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.conf.CertFile, r.conf.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.conf.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.conf.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA %s: %w", r.conf.ClientCAFile, ErrNoCertificates)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = r.statFiles()
+
+	return nil
+}
+
+// Config returns a TLS configuration that always serves the latest files.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.clientCA != nil {
+				cfg.ClientCAs = r.clientCA
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				if r.conf.ClientAuth == "verify_if_given" {
+					cfg.ClientAuth = tls.VerifyClientCertIfGiven
+				}
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// Watch reloads the files when their modification time changes, checking
+// every reload interval. Reload errors are passed to onError once for
+// every change of the files. Watch blocks until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, onError func(error)) {
+	if r.conf.ReloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(r.conf.ReloadInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reloadChanged(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// reloadChanged reloads the files if they changed since they were last
+// loaded or failed to load.
+func (r *Reloader) reloadChanged() error {
+	current := r.statFiles()
+
+	r.mu.RLock()
+	changed := modified(current, r.modTimes) && modified(current, r.failedModTimes)
+	r.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	err := r.Reload()
+	if err != nil {
+		r.mu.Lock()
+		r.failedModTimes = current
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// modified reports whether any file in current has another modification
+// time in prev.
+func modified(current, prev map[string]time.Time) bool {
+	for path, mod := range current {
+		if !mod.Equal(prev[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) statFiles() map[string]time.Time {
+	modTimes := make(map[string]time.Time, 3)
+	for _, path := range []string{r.conf.CertFile, r.conf.KeyFile, r.conf.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	return modTimes
+}
@@ -0,0 +1,189 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns PEM encoded certificate and key signed by the CA.
+func (ca *testCA) issue(t *testing.T, commonName string, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestReloader(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	conf := config.TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+
+	certPEM, keyPEM := ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, conf.CertFile, certPEM)
+	writeFile(t, conf.KeyFile, keyPEM)
+	writeFile(t, conf.ClientCAFile, ca.pem)
+
+	certs, err := New(conf)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = certs.Config()
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientPEM, clientKeyPEM := ca.issue(t, "42", 3, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				RootCAs:      roots,
+				Certificates: certs,
+			},
+		}}
+	}
+
+	t.Run("client certificate required", func(t *testing.T) {
+		resp, err := newClient().Get(srv.URL) //nolint:noctx
+		if err == nil {
+			resp.Body.Close()
+		}
+		require.Error(t, err)
+	})
+
+	t.Run("client certificate accepted", func(t *testing.T) {
+		resp, err := newClient(clientCert).Get(srv.URL) //nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, big.NewInt(2), resp.TLS.PeerCertificates[0].SerialNumber)
+	})
+
+	t.Run("reloads server certificate", func(t *testing.T) {
+		certPEM, keyPEM := ca.issue(t, "server", 4, x509.ExtKeyUsageServerAuth)
+		writeFile(t, conf.CertFile, certPEM)
+		writeFile(t, conf.KeyFile, keyPEM)
+		require.NoError(t, certs.Reload())
+
+		resp, err := newClient(clientCert).Get(srv.URL) //nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, big.NewInt(4), resp.TLS.PeerCertificates[0].SerialNumber)
+	})
+
+	t.Run("keeps certificate on failed reload", func(t *testing.T) {
+		writeFile(t, conf.KeyFile, []byte("broken"))
+		require.Error(t, certs.Reload())
+
+		resp, err := newClient(clientCert).Get(srv.URL) //nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, big.NewInt(4), resp.TLS.PeerCertificates[0].SerialNumber)
+	})
+
+	t.Run("reports a failed reload once per change", func(t *testing.T) {
+		touch := func(offset time.Duration) {
+			mod := time.Now().Add(offset)
+			require.NoError(t, os.Chtimes(conf.KeyFile, mod, mod))
+		}
+
+		touch(time.Minute)
+		require.Error(t, certs.reloadChanged())
+		require.NoError(t, certs.reloadChanged())
+
+		touch(2 * time.Minute)
+		require.Error(t, certs.reloadChanged())
+
+		certPEM, keyPEM := ca.issue(t, "server", 5, x509.ExtKeyUsageServerAuth)
+		writeFile(t, conf.CertFile, certPEM)
+		writeFile(t, conf.KeyFile, keyPEM)
+		touch(3 * time.Minute)
+		require.NoError(t, certs.reloadChanged())
+
+		resp, err := newClient(clientCert).Get(srv.URL) //nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, big.NewInt(5), resp.TLS.PeerCertificates[0].SerialNumber)
+	})
+}
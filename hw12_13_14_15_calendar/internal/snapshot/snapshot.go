@@ -0,0 +1,317 @@
+// Package snapshot exports the events and calendars of a storage to an
+// archive and restores them into another storage.
+//
+// An archive is gzip-compressed JSON lines: a header naming the format and
+// its version, the calendars, the events, and a trailer with their counts
+// that tells a complete archive from a truncated one. Records hold the
+// JSON of storage.Calendar and storage.Event.
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	// Format names the archive format in the header.
+	Format = "calendar-snapshot"
+	// Version is the version of the archive format written by Export.
+	Version = 1
+
+	defaultBatchSize = 500
+	// progressInterval is how many records are handled between progress
+	// reports.
+	progressInterval = 1000
+	// maxRecordSize bounds a line of an archive.
+	maxRecordSize = 16 << 20
+)
+
+var (
+	ErrUnsupported = errors.New("storage does not support snapshots")
+	ErrInvalid     = errors.New("invalid snapshot")
+)
+
+// Stats counts the records handled so far.
+type Stats struct {
+	Calendars int `json:"calendars"`
+	Events    int `json:"events"`
+}
+
+type header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// record is a line after the header. Exactly one field is set.
+type record struct {
+	Calendar *storage.Calendar `json:"calendar,omitempty"`
+	Event    *storage.Event    `json:"event,omitempty"`
+	End      *Stats            `json:"end,omitempty"`
+}
+
+// Options tune Export and Import.
+type Options struct {
+	// BatchSize is how many records are read or restored at once.
+	BatchSize int
+	// RemapIDs makes Import give restored records new IDs instead of
+	// keeping theirs. Events follow their calendars to the new IDs.
+	RemapIDs bool
+	// Progress, if set, is called every thousand records and when done.
+	Progress func(Stats)
+}
+
+func (o Options) batchSize() int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return defaultBatchSize
+}
+
+func (o Options) report(stats Stats, done bool) {
+	if o.Progress == nil {
+		return
+	}
+	if done || (stats.Calendars+stats.Events)%progressInterval == 0 {
+		o.Progress(stats)
+	}
+}
+
+// Export writes every calendar and event of store to w.
+func Export(ctx context.Context, store storage.Storage, w io.Writer, opts Options) (Stats, error) {
+	var stats Stats
+	snapshotter, ok := storage.As[storage.Snapshotter](store)
+	if !ok {
+		return stats, ErrUnsupported
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(header{Format: Format, Version: Version, CreatedAt: time.Now().UTC()}); err != nil {
+		return stats, err
+	}
+
+	for afterID := int64(0); ; {
+		calendars, err := snapshotter.ScanCalendars(ctx, afterID, opts.batchSize())
+		if err != nil {
+			return stats, err
+		}
+		for _, calendar := range calendars {
+			if err := enc.Encode(record{Calendar: calendar}); err != nil {
+				return stats, err
+			}
+			stats.Calendars++
+			opts.report(stats, false)
+		}
+		if len(calendars) < opts.batchSize() {
+			break
+		}
+		afterID = calendars[len(calendars)-1].ID
+	}
+
+	for afterID := int64(0); ; {
+		events, err := snapshotter.ScanEvents(ctx, afterID, opts.batchSize())
+		if err != nil {
+			return stats, err
+		}
+		for _, event := range events {
+			event.Attachments = nil
+			if err := enc.Encode(record{Event: event}); err != nil {
+				return stats, err
+			}
+			stats.Events++
+			opts.report(stats, false)
+		}
+		if len(events) < opts.batchSize() {
+			break
+		}
+		afterID = events[len(events)-1].ID
+	}
+
+	if err := enc.Encode(record{End: &stats}); err != nil {
+		return stats, err
+	}
+	if err := zw.Close(); err != nil {
+		return stats, err
+	}
+	opts.report(stats, true)
+	return stats, nil
+}
+
+// Import restores the calendars and events of an archive into store.
+// Every batch is restored atomically, but an archive that fails midway
+// leaves the batches before the failure restored.
+func Import(ctx context.Context, store storage.Storage, r io.Reader, opts Options) (Stats, error) {
+	var stats Stats
+	snapshotter, ok := storage.As[storage.Snapshotter](store)
+	if !ok {
+		return stats, ErrUnsupported
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return stats, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	defer zr.Close()
+
+	lines := bufio.NewScanner(zr)
+	lines.Buffer(make([]byte, 0, 64<<10), maxRecordSize)
+
+	var h header
+	if !lines.Scan() {
+		return stats, fmt.Errorf("%w: missing header: %w", ErrInvalid, lineError(lines))
+	}
+	if err := json.Unmarshal(lines.Bytes(), &h); err != nil || h.Format != Format {
+		return stats, fmt.Errorf("%w: not a %s archive", ErrInvalid, Format)
+	}
+	if h.Version < 1 || h.Version > Version {
+		return stats, fmt.Errorf("%w: unsupported version %d", ErrInvalid, h.Version)
+	}
+
+	im := &importer{
+		ctx:       ctx,
+		store:     snapshotter,
+		opts:      opts,
+		calendars: make(map[int64]int64),
+	}
+	for lines.Scan() {
+		var rec record
+		if err := json.Unmarshal(lines.Bytes(), &rec); err != nil {
+			return im.stats, fmt.Errorf("%w: record %d: %w", ErrInvalid, im.records()+1, err)
+		}
+
+		switch {
+		case rec.Calendar != nil && len(im.events) == 0 && im.stats.Events == 0:
+			err = im.addCalendar(rec.Calendar)
+		case rec.Event != nil:
+			err = im.addEvent(rec.Event)
+		case rec.End != nil:
+			if err := im.flush(); err != nil {
+				return im.stats, err
+			}
+			if *rec.End != im.stats {
+				return im.stats, fmt.Errorf("%w: trailer counts %+v, restored %+v", ErrInvalid, *rec.End, im.stats)
+			}
+			opts.report(im.stats, true)
+			return im.stats, nil
+		default:
+			err = fmt.Errorf("%w: unexpected record %d", ErrInvalid, im.records()+1)
+		}
+		if err != nil {
+			return im.stats, err
+		}
+	}
+
+	return im.stats, fmt.Errorf("%w: truncated archive: %w", ErrInvalid, lineError(lines))
+}
+
+// importer restores records in batches.
+type importer struct {
+	ctx   context.Context
+	store storage.Snapshotter
+	opts  Options
+	stats Stats
+
+	pendingCalendars []*storage.Calendar
+	events           []*storage.Event
+	// calendars maps archived calendar IDs to restored ones.
+	calendars map[int64]int64
+}
+
+func (im *importer) records() int {
+	return im.stats.Calendars + im.stats.Events + len(im.pendingCalendars) + len(im.events)
+}
+
+func (im *importer) addCalendar(calendar *storage.Calendar) error {
+	im.pendingCalendars = append(im.pendingCalendars, calendar)
+	if len(im.pendingCalendars) < im.opts.batchSize() {
+		return nil
+	}
+	return im.flushCalendars()
+}
+
+func (im *importer) addEvent(event *storage.Event) error {
+	// Calendars are archived before events, so all of them are known.
+	if err := im.flushCalendars(); err != nil {
+		return err
+	}
+
+	if event.CalendarID != 0 {
+		id, ok := im.calendars[event.CalendarID]
+		if !ok {
+			return fmt.Errorf("%w: event %d is in calendar %d, which is not archived",
+				ErrInvalid, event.ID, event.CalendarID)
+		}
+		event.CalendarID = id
+	}
+
+	im.events = append(im.events, event)
+	if len(im.events) < im.opts.batchSize() {
+		return nil
+	}
+	return im.flushEvents()
+}
+
+func (im *importer) flush() error {
+	if err := im.flushCalendars(); err != nil {
+		return err
+	}
+	return im.flushEvents()
+}
+
+func (im *importer) flushCalendars() error {
+	if len(im.pendingCalendars) == 0 {
+		return nil
+	}
+
+	archived := make([]int64, len(im.pendingCalendars))
+	for i, calendar := range im.pendingCalendars {
+		archived[i] = calendar.ID
+	}
+	if err := im.store.RestoreCalendars(im.ctx, im.pendingCalendars, !im.opts.RemapIDs); err != nil {
+		return fmt.Errorf("restore calendars: %w", err)
+	}
+
+	for i, calendar := range im.pendingCalendars {
+		im.calendars[archived[i]] = calendar.ID
+		im.stats.Calendars++
+		im.opts.report(im.stats, false)
+	}
+	im.pendingCalendars = im.pendingCalendars[:0]
+	return nil
+}
+
+func (im *importer) flushEvents() error {
+	if len(im.events) == 0 {
+		return nil
+	}
+
+	if err := im.store.RestoreEvents(im.ctx, im.events, !im.opts.RemapIDs); err != nil {
+		return fmt.Errorf("restore events: %w", err)
+	}
+
+	for range im.events {
+		im.stats.Events++
+		im.opts.report(im.stats, false)
+	}
+	im.events = im.events[:0]
+	return nil
+}
+
+// lineError is the error that stopped a scanner, or io.ErrUnexpectedEOF
+// if it ran out of input.
+func lineError(lines *bufio.Scanner) error {
+	if err := lines.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	source := newStore()
+	work := &storage.Calendar{UserID: 1, Name: "Work", Color: "#ff0000", TimeZone: "UTC"}
+	require.NoError(t, source.CreateCalendar(ctx, work))
+	for i := 0; i < 25; i++ {
+		event := &storage.Event{
+			Title:     fmt.Sprintf("Event %d", i),
+			UserID:    1,
+			StartTime: start.Add(time.Duration(i) * time.Hour),
+			EndTime:   start.Add(time.Duration(i)*time.Hour + time.Minute),
+		}
+		if i%2 == 0 {
+			event.CalendarID = work.ID
+			event.Conference = &storage.Conference{URL: "https://meet.example.com/a"}
+		}
+		require.NoError(t, source.CreateEvent(ctx, event))
+	}
+
+	var archive bytes.Buffer
+	var reports []Stats
+	stats, err := Export(ctx, source, &archive, Options{
+		BatchSize: 10,
+		Progress:  func(s Stats) { reports = append(reports, s) },
+	})
+	require.NoError(t, err)
+	require.Equal(t, Stats{Calendars: 1, Events: 25}, stats)
+	require.Equal(t, []Stats{stats}, reports)
+
+	scanAll := func(store storage.Snapshotter) []*storage.Event {
+		t.Helper()
+		events, err := store.ScanEvents(ctx, 0, 100)
+		require.NoError(t, err)
+		return events
+	}
+
+	t.Run("keep ids", func(t *testing.T) {
+		target := newStore()
+		stats, err := Import(ctx, target, bytes.NewReader(archive.Bytes()), Options{BatchSize: 7})
+		require.NoError(t, err)
+		require.Equal(t, Stats{Calendars: 1, Events: 25}, stats)
+
+		calendar, err := target.GetCalendar(ctx, work.ID)
+		require.NoError(t, err)
+		require.True(t, work.CreatedAt.Equal(calendar.CreatedAt))
+		calendar.CreatedAt = work.CreatedAt
+		require.Equal(t, *work, *calendar)
+		require.Equal(t, scanAll(source), scanAll(target))
+
+		// New records continue after the restored IDs.
+		event := &storage.Event{Title: "New", UserID: 1, StartTime: start, EndTime: start}
+		require.NoError(t, target.CreateEvent(ctx, event))
+		require.Equal(t, int64(26), event.ID)
+
+		_, err = Import(ctx, target, bytes.NewReader(archive.Bytes()), Options{})
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+	})
+
+	t.Run("remap ids", func(t *testing.T) {
+		target := newStore()
+		other := &storage.Calendar{UserID: 2, Name: "Home"}
+		require.NoError(t, target.CreateCalendar(ctx, other))
+		require.NoError(t, target.CreateEvent(ctx, &storage.Event{Title: "Existing", UserID: 2}))
+
+		_, err := Import(ctx, target, bytes.NewReader(archive.Bytes()), Options{RemapIDs: true})
+		require.NoError(t, err)
+
+		calendars, err := target.ListCalendars(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, calendars, 1)
+		require.NotEqual(t, work.ID, calendars[0].ID)
+
+		events := scanAll(target)
+		require.Len(t, events, 26)
+		for i, event := range events[1:] {
+			require.Equal(t, fmt.Sprintf("Event %d", i), event.Title)
+			require.Equal(t, int64(i+2), event.ID)
+			if i%2 == 0 {
+				require.Equal(t, calendars[0].ID, event.CalendarID)
+			} else {
+				require.Zero(t, event.CalendarID)
+			}
+		}
+	})
+
+	t.Run("invalid archives", func(t *testing.T) {
+		data := archive.Bytes()
+		for name, r := range map[string][]byte{
+			"not gzip":  []byte(`{"format":"calendar-snapshot","version":1}`),
+			"truncated": gzipped(t, bytes.SplitAfter(gunzipped(t, data), []byte("\n"))[:10]...),
+			"version":   gzipped(t, []byte(`{"format":"calendar-snapshot","version":2}`+"\n")),
+			"format":    gzipped(t, []byte(`{"format":"other","version":1}`+"\n")),
+		} {
+			_, err := Import(ctx, newStore(), bytes.NewReader(r), Options{})
+			require.ErrorIs(t, err, ErrInvalid, name)
+		}
+	})
+
+	t.Run("unsupported storage", func(t *testing.T) {
+		_, err := Export(ctx, struct{ storage.Storage }{}, &bytes.Buffer{}, Options{})
+		require.ErrorIs(t, err, ErrUnsupported)
+	})
+}
+
+func gzipped(t *testing.T, lines ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, line := range lines {
+		_, err := zw.Write(line)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func gunzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return plain
+}
+
+func newStore() *memorystorage.MemoryStorage {
+	return memorystorage.New().(*memorystorage.MemoryStorage)
+}
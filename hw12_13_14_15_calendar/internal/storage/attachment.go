@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Attachment describes a file of an event. The bytes are kept in a blob
+// store under Key.
+type Attachment struct {
+	ID        int64     `json:"id" db:"id"`
+	EventID   int64     `json:"event_id" db:"event_id"`
+	Name      string    `json:"name" db:"name"`
+	MimeType  string    `json:"mime_type" db:"mime_type"`
+	Size      int64     `json:"size" db:"size"`
+	Key       string    `json:"-" db:"storage_key"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AttachmentStore is implemented by storages that keep attachment
+// metadata. Attachments are deleted with their event.
+type AttachmentStore interface {
+	CreateAttachment(ctx context.Context, attachment *Attachment) error
+	GetAttachment(ctx context.Context, id int64) (*Attachment, error)
+	// ListAttachments returns the attachments of an event, oldest first.
+	ListAttachments(ctx context.Context, eventID int64) ([]*Attachment, error)
+	DeleteAttachment(ctx context.Context, id int64) error
+}
+
+func AttachmentNotFound(id int64) error {
+	return fmt.Errorf("attachment %d: %w", id, ErrNotFound)
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// Batch operation types.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOp is an operation of a batch. Deletes match Event.ID and
+// Event.UserID.
+type BatchOp struct {
+	Type  string
+	Event *Event
+}
+
+// BatchError reports the operation that failed a batch.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchStore is implemented by storages that apply batches atomically.
+type BatchStore interface {
+	// ApplyBatch applies ops in order, all or nothing. Creates set the
+	// event ID and deletes fill the event with the deleted one. A failed
+	// operation is reported as a *BatchError.
+	ApplyBatch(ctx context.Context, ops []BatchOp) error
+}
@@ -0,0 +1,104 @@
+package cachestorage
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache is a size bounded cache evicting the least recently used items.
+// Items also expire after ttl.
+type lruCache struct {
+	capacity int
+	ttl      time.Duration
+	queue    *list.List
+	items    map[string]*list.Element
+	lock     sync.Mutex
+	now      func() time.Time
+}
+
+// In order to achieve constant complexity
+// during oldest element ousting.
+type cacheValue struct {
+	value     interface{}
+	key       string
+	expiresAt time.Time
+}
+
+func newLRUCache(capacity int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ttl:      ttl,
+		queue:    list.New(),
+		items:    make(map[string]*list.Element, capacity),
+		now:      time.Now,
+	}
+}
+
+func (c *lruCache) Set(key string, value interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	val := cacheValue{value: value, key: key, expiresAt: c.now().Add(c.ttl)}
+
+	item, ok := c.items[key]
+	if ok {
+		item.Value = val
+		c.queue.MoveToFront(item)
+		return true
+	}
+
+	c.items[key] = c.queue.PushFront(val)
+	for c.queue.Len() > c.capacity {
+		c.removeElement(c.queue.Back())
+	}
+
+	return false
+}
+
+func (c *lruCache) Get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	val, _ := item.Value.(cacheValue)
+	if c.ttl > 0 && !c.now().Before(val.expiresAt) {
+		c.removeElement(item)
+		return nil, false
+	}
+
+	c.queue.MoveToFront(item)
+	return val.value, true
+}
+
+func (c *lruCache) Remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.items[key]; ok {
+		c.removeElement(item)
+	}
+}
+
+func (c *lruCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.queue.Len()
+}
+
+func (c *lruCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.queue.Init()
+	c.items = make(map[string]*list.Element, c.capacity)
+}
+
+func (c *lruCache) removeElement(item *list.Element) {
+	val, _ := c.queue.Remove(item).(cacheValue)
+	delete(c.items, val.key)
+}
@@ -0,0 +1,308 @@
+package cachestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultSize = 10000
+	defaultTTL  = time.Minute
+)
+
+// Stats holds cache hit and miss counters.
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Size   int    `json:"size"`
+}
+
+// CachedStorage caches event reads of another storage. Any write of a user
+// invalidates the cached queries of that user.
+type CachedStorage struct {
+	storage.Storage
+	cache *lruCache
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	// writes counts every write so a read racing with one is not cached.
+	writes atomic.Uint64
+
+	// generations are bumped on every write of a user. Query keys include
+	// the generation, so stale results are never read again and age out.
+	mu          sync.Mutex
+	generations map[int64]uint64
+}
+
+func New(inner storage.Storage, conf config.CacheConfig) *CachedStorage {
+	size := conf.Size
+	if size <= 0 {
+		size = defaultSize
+	}
+	ttl := defaultTTL
+	if conf.TTL > 0 {
+		ttl = time.Duration(conf.TTL) * time.Second
+	}
+
+	return &CachedStorage{
+		Storage:     inner,
+		cache:       newLRUCache(size, ttl),
+		generations: make(map[int64]uint64),
+	}
+}
+
+// Unwrap returns the decorated storage.
+func (c *CachedStorage) Unwrap() storage.Storage {
+	return c.Storage
+}
+
+// Stats returns the hit and miss counters.
+func (c *CachedStorage) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Size:   c.cache.Len(),
+	}
+}
+
+func (c *CachedStorage) CreateEvent(ctx context.Context, event *storage.Event) error {
+	c.writes.Add(1)
+	if err := c.Storage.CreateEvent(ctx, event); err != nil {
+		return err
+	}
+
+	c.invalidateUser(event.UserID)
+	return nil
+}
+
+func (c *CachedStorage) UpdateEvent(ctx context.Context, event *storage.Event) error {
+	c.writes.Add(1)
+	if err := c.Storage.UpdateEvent(ctx, event); err != nil {
+		return err
+	}
+
+	c.cache.Remove(eventKey(event.ID))
+	c.invalidateUser(event.UserID)
+	return nil
+}
+
+func (c *CachedStorage) DeleteEvent(ctx context.Context, id int64) error {
+	// The owner is needed to invalidate their queries.
+	existing, err := c.Storage.GetEvent(ctx, id)
+	if err != nil && !storage.IsNotFound(err) {
+		return err
+	}
+
+	c.writes.Add(1)
+	if err := c.Storage.DeleteEvent(ctx, id); err != nil {
+		return err
+	}
+
+	c.cache.Remove(eventKey(id))
+	if existing != nil {
+		c.invalidateUser(existing.UserID)
+	}
+	return nil
+}
+
+// ApplyBatch applies a batch with the decorated storage, which must be
+// a storage.BatchStore.
+func (c *CachedStorage) ApplyBatch(ctx context.Context, ops []storage.BatchOp) error {
+	inner, ok := storage.As[storage.BatchStore](c.Storage)
+	if !ok {
+		return fmt.Errorf("batch: %w", errors.ErrUnsupported)
+	}
+
+	c.writes.Add(1)
+	if err := inner.ApplyBatch(ctx, ops); err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		c.cache.Remove(eventKey(op.Event.ID))
+		c.invalidateUser(op.Event.UserID)
+	}
+	return nil
+}
+
+// CreateCalendar and the other calendar methods use the decorated
+// storage, which must be a storage.CalendarStore. Only deleting a
+// calendar changes events.
+func (c *CachedStorage) CreateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	inner, err := c.calendarStore()
+	if err != nil {
+		return err
+	}
+	return inner.CreateCalendar(ctx, calendar)
+}
+
+func (c *CachedStorage) GetCalendar(ctx context.Context, id int64) (*storage.Calendar, error) {
+	inner, err := c.calendarStore()
+	if err != nil {
+		return nil, err
+	}
+	return inner.GetCalendar(ctx, id)
+}
+
+func (c *CachedStorage) ListCalendars(ctx context.Context, userID int64) ([]*storage.Calendar, error) {
+	inner, err := c.calendarStore()
+	if err != nil {
+		return nil, err
+	}
+	return inner.ListCalendars(ctx, userID)
+}
+
+func (c *CachedStorage) UpdateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	inner, err := c.calendarStore()
+	if err != nil {
+		return err
+	}
+	return inner.UpdateCalendar(ctx, calendar)
+}
+
+func (c *CachedStorage) DeleteCalendar(ctx context.Context, id int64) ([]*storage.Event, error) {
+	inner, err := c.calendarStore()
+	if err != nil {
+		return nil, err
+	}
+
+	c.writes.Add(1)
+	deleted, err := inner.DeleteCalendar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range deleted {
+		c.cache.Remove(eventKey(event.ID))
+		c.invalidateUser(event.UserID)
+	}
+	return deleted, nil
+}
+
+func (c *CachedStorage) ListEventsByCalendars(ctx context.Context,
+	userID int64,
+	calendarIDs []int64,
+	from, to time.Time,
+) ([]*storage.Event, error) {
+	inner, err := c.calendarStore()
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("calendars:%v:%d:%d", calendarIDs, from.UnixNano(), to.UnixNano())
+	return c.query(userID, name, func() ([]*storage.Event, error) {
+		return inner.ListEventsByCalendars(ctx, userID, calendarIDs, from, to)
+	})
+}
+
+func (c *CachedStorage) calendarStore() (storage.CalendarStore, error) {
+	inner, ok := storage.As[storage.CalendarStore](c.Storage)
+	if !ok {
+		return nil, fmt.Errorf("calendars: %w", errors.ErrUnsupported)
+	}
+	return inner, nil
+}
+
+func (c *CachedStorage) GetEvent(ctx context.Context, id int64) (*storage.Event, error) {
+	key := eventKey(id)
+	if v, ok := c.cache.Get(key); ok {
+		c.hits.Add(1)
+		return v.(*storage.Event).Clone(), nil
+	}
+	c.misses.Add(1)
+
+	writes := c.writes.Load()
+	event, err := c.Storage.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.writes.Load() == writes {
+		c.cache.Set(key, event.Clone())
+	}
+	return event, nil
+}
+
+func (c *CachedStorage) ListEvents(ctx context.Context, userID int64, from, to time.Time) ([]*storage.Event, error) {
+	return c.query(userID, fmt.Sprintf("list:%d:%d", from.UnixNano(), to.UnixNano()), func() ([]*storage.Event, error) {
+		return c.Storage.ListEvents(ctx, userID, from, to)
+	})
+}
+
+func (c *CachedStorage) ListUpcomingEvents(ctx context.Context, userID int64, limit int) ([]*storage.Event, error) {
+	// The result depends on the current time, so it is not cached.
+	return c.Storage.ListUpcomingEvents(ctx, userID, limit)
+}
+
+func (c *CachedStorage) GetEventsByTimeRange(ctx context.Context,
+	userID int64,
+	start, end time.Time,
+) ([]*storage.Event, error) {
+	return c.query(userID, fmt.Sprintf("range:%d:%d", start.UnixNano(), end.UnixNano()), func() ([]*storage.Event, error) {
+		return c.Storage.GetEventsByTimeRange(ctx, userID, start, end)
+	})
+}
+
+// query returns the cached result of a query of userID's events, running
+// load on a miss.
+func (c *CachedStorage) query(userID int64, name string,
+	load func() ([]*storage.Event, error),
+) ([]*storage.Event, error) {
+	key := fmt.Sprintf("user:%d:%d:%s", userID, c.generation(userID), name)
+	if v, ok := c.cache.Get(key); ok {
+		c.hits.Add(1)
+		return copyEvents(v.([]*storage.Event)), nil
+	}
+	c.misses.Add(1)
+
+	events, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.Set(key, copyEvents(events))
+	return events, nil
+}
+
+func (c *CachedStorage) generation(userID int64) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[userID]
+}
+
+func (c *CachedStorage) invalidateUser(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[userID]++
+}
+
+func (c *CachedStorage) Close() error {
+	c.cache.Clear()
+	return c.Storage.Close()
+}
+
+func eventKey(id int64) string {
+	return fmt.Sprintf("event:%d", id)
+}
+
+func copyEvents(events []*storage.Event) []*storage.Event {
+	if events == nil {
+		return nil
+	}
+
+	copies := make([]*storage.Event, len(events))
+	for i, e := range events {
+		copies[i] = e.Clone()
+	}
+	return copies
+}
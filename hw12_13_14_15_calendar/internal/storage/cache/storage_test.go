@@ -0,0 +1,170 @@
+package cachestorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedStorage_GetEvent(t *testing.T) {
+	store := New(memorystorage.New(), config.CacheConfig{Enabled: true})
+	ctx := context.Background()
+
+	event := &storage.Event{Title: "Cached", UserID: 1, StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)}
+	require.NoError(t, store.CreateEvent(ctx, event))
+
+	t.Run("miss then hit", func(t *testing.T) {
+		_, err := store.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		got, err := store.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Cached", got.Title)
+		require.Equal(t, Stats{Hits: 1, Misses: 1, Size: 1}, store.Stats())
+	})
+
+	t.Run("returned event is a copy", func(t *testing.T) {
+		got, err := store.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		got.Title = "Changed"
+
+		got, err = store.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Cached", got.Title)
+	})
+
+	t.Run("update invalidates", func(t *testing.T) {
+		event.Title = "Updated"
+		require.NoError(t, store.UpdateEvent(ctx, event))
+
+		got, err := store.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Updated", got.Title)
+	})
+
+	t.Run("delete invalidates", func(t *testing.T) {
+		require.NoError(t, store.DeleteEvent(ctx, event.ID))
+
+		_, err := store.GetEvent(ctx, event.ID)
+		require.True(t, storage.IsNotFound(err))
+	})
+}
+
+func TestCachedStorage_ListEvents(t *testing.T) {
+	store := New(memorystorage.New(), config.CacheConfig{Enabled: true})
+	ctx := context.Background()
+	now := time.Now()
+	from, to := now.Add(-time.Hour), now.Add(24*time.Hour)
+
+	create := func(userID int64, title string) *storage.Event {
+		event := &storage.Event{Title: title, UserID: userID, StartTime: now, EndTime: now.Add(time.Hour)}
+		require.NoError(t, store.CreateEvent(ctx, event))
+		return event
+	}
+	create(1, "First")
+	create(2, "Other user")
+
+	listed, err := store.ListEvents(ctx, 1, from, to)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	other, err := store.ListEvents(ctx, 2, from, to)
+	require.NoError(t, err)
+	require.Len(t, other, 1)
+
+	t.Run("hit", func(t *testing.T) {
+		before := store.Stats().Hits
+		listed, err := store.ListEvents(ctx, 1, from, to)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, before+1, store.Stats().Hits)
+	})
+
+	t.Run("create invalidates only the owner", func(t *testing.T) {
+		create(1, "Second")
+
+		listed, err := store.ListEvents(ctx, 1, from, to)
+		require.NoError(t, err)
+		require.Len(t, listed, 2)
+
+		before := store.Stats().Hits
+		_, err = store.ListEvents(ctx, 2, from, to)
+		require.NoError(t, err)
+		require.Equal(t, before+1, store.Stats().Hits)
+	})
+
+	t.Run("delete invalidates", func(t *testing.T) {
+		listed, err := store.GetEventsByTimeRange(ctx, 1, from, to)
+		require.NoError(t, err)
+		require.Len(t, listed, 2)
+
+		require.NoError(t, store.DeleteEvent(ctx, listed[0].ID))
+
+		listed, err = store.GetEventsByTimeRange(ctx, 1, from, to)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+	})
+}
+
+func TestLRUCache(t *testing.T) {
+	now := time.Now()
+	c := newLRUCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Get("a")
+		c.Set("c", 3)
+
+		_, ok := c.Get("b")
+		require.False(t, ok)
+		v, ok := c.Get("a")
+		require.True(t, ok)
+		require.Equal(t, 1, v)
+	})
+
+	t.Run("expires after ttl", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		_, ok := c.Get("a")
+		require.False(t, ok)
+		require.Equal(t, 1, c.Len())
+	})
+}
+
+func TestAs(t *testing.T) {
+	store := New(memorystorage.New(), config.CacheConfig{})
+	_, ok := storage.As[storage.MigrationVersioner](store)
+	require.False(t, ok)
+}
+
+func TestCachedStorage_DeleteCalendar(t *testing.T) {
+	store := New(memorystorage.New(), config.CacheConfig{Enabled: true})
+	ctx := context.Background()
+	now := time.Now()
+	from, to := now.Add(-time.Hour), now.Add(24*time.Hour)
+
+	work := &storage.Calendar{UserID: 1, Name: "Work"}
+	require.NoError(t, store.CreateCalendar(ctx, work))
+	event := &storage.Event{Title: "Sync", UserID: 1, CalendarID: work.ID, StartTime: now, EndTime: now.Add(time.Hour)}
+	require.NoError(t, store.CreateEvent(ctx, event))
+
+	_, err := store.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	listed, err := store.ListEventsByCalendars(ctx, 1, []int64{work.ID}, from, to)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+
+	deleted, err := store.DeleteCalendar(ctx, work.ID)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+
+	_, err = store.GetEvent(ctx, event.ID)
+	require.True(t, storage.IsNotFound(err))
+	listed, err = store.ListEventsByCalendars(ctx, 1, []int64{work.ID}, from, to)
+	require.NoError(t, err)
+	require.Empty(t, listed)
+}
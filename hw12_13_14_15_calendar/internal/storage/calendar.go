@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Calendar groups events of a user. Its settings apply to the events
+// created in it.
+type Calendar struct {
+	ID     int64  `json:"id" db:"id"`
+	UserID int64  `json:"user_id" db:"user_id"`
+	Name   string `json:"name" db:"name"`
+	// Color is a hex RGB colour such as "#1e90ff".
+	Color string `json:"color" db:"color"`
+	// DefaultReminder is how many minutes before their start events are
+	// reminded of when created without a reminder. Zero means none.
+	DefaultReminder int `json:"default_reminder" db:"default_reminder"`
+	// TimeZone is an IANA time zone name such as "Europe/Berlin".
+	TimeZone  string    `json:"time_zone" db:"time_zone"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CalendarStore is implemented by storages that keep calendars. An event
+// is in the calendar with Event.CalendarID, or in none if it is zero.
+type CalendarStore interface {
+	// CreateCalendar fails with ErrAlreadyExists if the user has a
+	// calendar of the same name.
+	CreateCalendar(ctx context.Context, calendar *Calendar) error
+	GetCalendar(ctx context.Context, id int64) (*Calendar, error)
+	ListCalendars(ctx context.Context, userID int64) ([]*Calendar, error)
+	// UpdateCalendar changes the settings of a calendar of
+	// Calendar.UserID.
+	UpdateCalendar(ctx context.Context, calendar *Calendar) error
+	// DeleteCalendar deletes a calendar with its events and returns the
+	// deleted events.
+	DeleteCalendar(ctx context.Context, id int64) ([]*Event, error)
+	// ListEventsByCalendars returns events of userID overlapping
+	// [from, to] that are in any of calendarIDs, ordered by start time.
+	ListEventsByCalendars(ctx context.Context, userID int64, calendarIDs []int64, from, to time.Time) ([]*Event, error)
+}
+
+func CalendarNotFound(id int64) error {
+	return fmt.Errorf("calendar %d: %w", id, ErrNotFound)
+}
+
+func CalendarAlreadyExists(name string) error {
+	return fmt.Errorf("calendar %q: %w", name, ErrAlreadyExists)
+}
@@ -2,6 +2,9 @@ package storage
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -13,6 +16,55 @@ type Event struct {
 	EndTime     time.Time `json:"end_time" db:"end_time"`
 	UserID      int64     `json:"user_id" db:"user_id"`
 	NotifyAt    time.Time `json:"notify_at,omitempty" db:"notify_at"`
+	// CalendarID is the calendar of the event, or zero for none.
+	CalendarID int64 `json:"calendar_id,omitempty" db:"calendar_id"`
+	// Conference is the video call of the event, if any.
+	Conference *Conference `json:"conference,omitempty" db:"conference"`
+	// Attachments are not kept by the storage; the app fills them in
+	// when it returns a single event.
+	Attachments []*Attachment `json:"attachments,omitempty" db:"-"`
+}
+
+// Clone returns a deep copy of the event.
+func (e *Event) Clone() *Event {
+	clone := *e
+	if e.Conference != nil {
+		conference := *e.Conference
+		clone.Conference = &conference
+	}
+	if e.Attachments != nil {
+		clone.Attachments = make([]*Attachment, len(e.Attachments))
+		for i, attachment := range e.Attachments {
+			attachmentCopy := *attachment
+			clone.Attachments[i] = &attachmentCopy
+		}
+	}
+	return &clone
+}
+
+// Conference is a link to join a video call.
+type Conference struct {
+	URL string `json:"url"`
+	// Provider names the service, such as "zoom" or "meet".
+	Provider  string `json:"provider,omitempty"`
+	MeetingID string `json:"meeting_id,omitempty"`
+	Passcode  string `json:"passcode,omitempty"`
+}
+
+// Value stores the conference as JSON text.
+func (c Conference) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+func (c *Conference) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return errors.New("conference: unsupported column type")
 }
 
 type Storage interface {
@@ -27,3 +79,31 @@ type Storage interface {
 	Close() error
 	Ping() error
 }
+
+// MigrationVersioner is implemented by storages with a migrated schema.
+type MigrationVersioner interface {
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+// Unwrapper is implemented by storages that decorate another storage.
+type Unwrapper interface {
+	Unwrap() Storage
+}
+
+// As finds an implementation of T, such as MigrationVersioner, in s or
+// the storages it decorates.
+func As[T any](s Storage) (T, bool) {
+	for s != nil {
+		if v, ok := s.(T); ok {
+			return v, true
+		}
+		u, ok := s.(Unwrapper)
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,80 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (m *MemoryStorage) CreateAttachment(ctx context.Context, attachment *storage.Attachment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.events[attachment.EventID]; !exists {
+		return storage.EventNotFound(attachment.EventID)
+	}
+
+	m.lastAttachmentID++
+	attachment.ID = m.lastAttachmentID
+	attachment.CreatedAt = time.Now()
+
+	attachmentCopy := *attachment
+	m.attachments[attachment.ID] = &attachmentCopy
+	return nil
+}
+
+func (m *MemoryStorage) GetAttachment(ctx context.Context, id int64) (*storage.Attachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	attachment, exists := m.attachments[id]
+	if !exists {
+		return nil, storage.AttachmentNotFound(id)
+	}
+
+	attachmentCopy := *attachment
+	return &attachmentCopy, nil
+}
+
+func (m *MemoryStorage) ListAttachments(ctx context.Context, eventID int64) ([]*storage.Attachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var attachments []*storage.Attachment
+	for _, attachment := range m.attachments {
+		if attachment.EventID == eventID {
+			attachmentCopy := *attachment
+			attachments = append(attachments, &attachmentCopy)
+		}
+	}
+
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].ID < attachments[j].ID
+	})
+	return attachments, nil
+}
+
+func (m *MemoryStorage) DeleteAttachment(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.attachments[id]; !exists {
+		return storage.AttachmentNotFound(id)
+	}
+
+	delete(m.attachments, id)
+	return nil
+}
+
+// deleteAttachmentsOf deletes the attachments of an event. The caller
+// holds the lock.
+func (m *MemoryStorage) deleteAttachmentsOf(eventID int64) {
+	for id, attachment := range m.attachments {
+		if attachment.EventID == eventID {
+			delete(m.attachments, id)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (m *MemoryStorage) ApplyBatch(ctx context.Context, ops []storage.BatchOp) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Operations are applied one by one and rolled back to the snapshot
+	// if any fails.
+	events := make(map[int64]*storage.Event, len(m.events))
+	for id, event := range m.events {
+		events[id] = event
+	}
+	notified := make(map[int64]bool, len(m.notified))
+	for id := range m.notified {
+		notified[id] = true
+	}
+	eventTags := make(map[int64]map[int64]struct{}, len(m.eventTags))
+	for id, tags := range m.eventTags {
+		eventTags[id] = tags
+	}
+	attachments := make(map[int64]*storage.Attachment, len(m.attachments))
+	for id, attachment := range m.attachments {
+		attachments[id] = attachment
+	}
+	lastID, lastOutboxID, outboxLen := m.lastID, m.lastOutboxID, len(m.outbox)
+
+	for i, op := range ops {
+		if err := m.applyOp(op); err != nil {
+			m.events, m.notified, m.eventTags, m.attachments = events, notified, eventTags, attachments
+			m.lastID, m.lastOutboxID, m.outbox = lastID, lastOutboxID, m.outbox[:outboxLen]
+			m.index = make(searchIndex)
+			for _, event := range events {
+				m.index.add(event)
+			}
+			return &storage.BatchError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// applyOp applies a batch operation. The caller holds the lock.
+func (m *MemoryStorage) applyOp(op storage.BatchOp) error {
+	event := op.Event
+	switch op.Type {
+	case storage.BatchCreate:
+		if err := m.checkCalendar(event); err != nil {
+			return err
+		}
+		m.lastID++
+		event.ID = m.lastID
+		eventCopy := event.Clone()
+		eventCopy.Attachments = nil
+		m.events[event.ID] = eventCopy
+		m.index.add(eventCopy)
+		return nil
+
+	case storage.BatchUpdate:
+		existing, exists := m.events[event.ID]
+		if !exists || existing.UserID != event.UserID {
+			return storage.EventNotFound(event.ID)
+		}
+		if err := m.checkCalendar(event); err != nil {
+			return err
+		}
+		if !existing.NotifyAt.Equal(event.NotifyAt) {
+			delete(m.notified, event.ID)
+		}
+		eventCopy := event.Clone()
+		eventCopy.Attachments = nil
+		m.events[event.ID] = eventCopy
+		m.index.remove(existing)
+		m.index.add(eventCopy)
+		return nil
+
+	case storage.BatchDelete:
+		existing, exists := m.events[event.ID]
+		if !exists || existing.UserID != event.UserID {
+			return storage.EventNotFound(event.ID)
+		}
+		*event = *existing
+		m.removeEvent(existing)
+		return nil
+	}
+
+	return storage.ValidationError(fmt.Sprintf("unknown operation %q", op.Type))
+}
@@ -0,0 +1,148 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (m *MemoryStorage) CreateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hasCalendar(calendar) {
+		return storage.CalendarAlreadyExists(calendar.Name)
+	}
+
+	m.lastCalendarID++
+	calendar.ID = m.lastCalendarID
+	calendar.CreatedAt = time.Now()
+
+	calendarCopy := *calendar
+	m.calendars[calendar.ID] = &calendarCopy
+	return nil
+}
+
+func (m *MemoryStorage) GetCalendar(ctx context.Context, id int64) (*storage.Calendar, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	calendar, exists := m.calendars[id]
+	if !exists {
+		return nil, storage.CalendarNotFound(id)
+	}
+
+	calendarCopy := *calendar
+	return &calendarCopy, nil
+}
+
+func (m *MemoryStorage) ListCalendars(ctx context.Context, userID int64) ([]*storage.Calendar, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var calendars []*storage.Calendar
+	for _, calendar := range m.calendars {
+		if calendar.UserID == userID {
+			calendarCopy := *calendar
+			calendars = append(calendars, &calendarCopy)
+		}
+	}
+
+	sort.Slice(calendars, func(i, j int) bool { return calendars[i].Name < calendars[j].Name })
+	return calendars, nil
+}
+
+func (m *MemoryStorage) UpdateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.calendars[calendar.ID]
+	if !exists || existing.UserID != calendar.UserID {
+		return storage.CalendarNotFound(calendar.ID)
+	}
+	if m.hasCalendar(calendar) {
+		return storage.CalendarAlreadyExists(calendar.Name)
+	}
+
+	calendar.CreatedAt = existing.CreatedAt
+	calendarCopy := *calendar
+	m.calendars[calendar.ID] = &calendarCopy
+	return nil
+}
+
+func (m *MemoryStorage) DeleteCalendar(ctx context.Context, id int64) ([]*storage.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.calendars[id]; !exists {
+		return nil, storage.CalendarNotFound(id)
+	}
+
+	var deleted []*storage.Event
+	for _, event := range m.events {
+		if event.CalendarID == id {
+			deleted = append(deleted, event)
+		}
+	}
+	sortEventsByStartTime(deleted)
+
+	for i, event := range deleted {
+		m.removeEvent(event)
+		deleted[i] = event.Clone()
+	}
+	delete(m.calendars, id)
+	return deleted, nil
+}
+
+func (m *MemoryStorage) ListEventsByCalendars(ctx context.Context,
+	userID int64,
+	calendarIDs []int64,
+	from, to time.Time,
+) ([]*storage.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	calendars := make(map[int64]struct{}, len(calendarIDs))
+	for _, id := range calendarIDs {
+		calendars[id] = struct{}{}
+	}
+
+	var events []*storage.Event
+	for _, event := range m.events {
+		if _, ok := calendars[event.CalendarID]; !ok ||
+			event.UserID != userID || event.StartTime.After(to) || event.EndTime.Before(from) {
+			continue
+		}
+
+		events = append(events, event.Clone())
+	}
+
+	sortEventsByStartTime(events)
+	return events, nil
+}
+
+// hasCalendar reports whether the owner of calendar has another calendar
+// of the same name. The caller holds the lock.
+func (m *MemoryStorage) hasCalendar(calendar *storage.Calendar) bool {
+	for _, other := range m.calendars {
+		if other.ID != calendar.ID && other.UserID == calendar.UserID && other.Name == calendar.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCalendar fails if the calendar of event does not exist. The
+// caller holds the lock.
+func (m *MemoryStorage) checkCalendar(event *storage.Event) error {
+	if event.CalendarID == 0 {
+		return nil
+	}
+	if _, exists := m.calendars[event.CalendarID]; !exists {
+		return storage.CalendarNotFound(event.CalendarID)
+	}
+	return nil
+}
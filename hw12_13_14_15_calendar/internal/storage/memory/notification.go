@@ -0,0 +1,145 @@
+package memorystorage
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// digestRecord is the last digest enqueued for a user.
+type digestRecord struct {
+	day      string
+	eventIDs []int64
+}
+
+func (m *MemoryStorage) GetNotificationPreferences(ctx context.Context,
+	userID int64,
+) (*storage.NotificationPreferences, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prefs, exists := m.preferences[userID]
+	if !exists {
+		return storage.DefaultNotificationPreferences(userID), nil
+	}
+
+	prefsCopy := *prefs
+	return &prefsCopy, nil
+}
+
+func (m *MemoryStorage) SetNotificationPreferences(ctx context.Context, prefs *storage.NotificationPreferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefs.UpdatedAt = time.Now()
+	prefsCopy := *prefs
+	m.preferences[prefs.UserID] = &prefsCopy
+	return nil
+}
+
+func (m *MemoryStorage) AddNotificationDelivery(ctx context.Context, delivery *storage.NotificationDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastNotificationDeliveryID++
+	delivery.ID = m.lastNotificationDeliveryID
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+
+	deliveryCopy := *delivery
+	history := append(m.notifications[delivery.UserID], &deliveryCopy)
+	if len(history) > storage.NotificationHistorySize {
+		history = history[len(history)-storage.NotificationHistorySize:]
+	}
+	m.notifications[delivery.UserID] = history
+	return nil
+}
+
+func (m *MemoryStorage) ListNotificationDeliveries(ctx context.Context,
+	userID, eventID int64,
+	limit int,
+) ([]*storage.NotificationDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.notifications[userID]
+	if limit <= 0 || limit > storage.NotificationHistorySize {
+		limit = storage.NotificationHistorySize
+	}
+
+	var deliveries []*storage.NotificationDelivery
+	for i := len(history) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if eventID != 0 && history[i].EventID != eventID {
+			continue
+		}
+		deliveryCopy := *history[i]
+		deliveries = append(deliveries, &deliveryCopy)
+	}
+	return deliveries, nil
+}
+
+func (m *MemoryStorage) NotificationHandled(ctx context.Context, userID int64, key string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, delivery := range m.notifications[userID] {
+		if delivery.Key == key && delivery.Status != storage.DeliveryFailed &&
+			delivery.Status != storage.DeliveryDeferred {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStorage) InDigest(ctx context.Context, userID, eventID int64) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, id := range m.digested[userID].eventIDs {
+		if id == eventID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStorage) ListDigestPreferences(ctx context.Context) ([]*storage.NotificationPreferences, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*storage.NotificationPreferences
+	for _, prefs := range m.preferences {
+		if prefs.Digest {
+			prefsCopy := *prefs
+			result = append(result, &prefsCopy)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UserID < result[j].UserID
+	})
+	return result, nil
+}
+
+func (m *MemoryStorage) EnqueueDigest(ctx context.Context, day string, digest storage.Notification) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Days compare as strings, and those up to the last one are done.
+	if last, ok := m.digested[digest.UserID]; ok && day <= last.day {
+		return false, nil
+	}
+
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		return false, err
+	}
+
+	m.digested[digest.UserID] = digestRecord{day: day, eventIDs: append([]int64(nil), digest.EventIDs...)}
+	m.addOutbox(storage.TopicNotification, storage.DigestKey(digest.UserID, day), payload)
+	return true, nil
+}
@@ -0,0 +1,194 @@
+package memorystorage
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// stateVersion is the version of the state written by Save.
+const stateVersion = 1
+
+// state is everything a MemoryStorage holds. It is encoded with gob,
+// which unlike JSON keeps fields hidden from the API, such as webhook
+// secrets and attachment keys.
+type state struct {
+	Version int
+
+	Events   []*storage.Event
+	LastID   int64
+	Notified []int64
+
+	// Outbox leases are not kept: the queue the messages were relayed to
+	// is gone, so they are relayed again.
+	Outbox       []*storage.OutboxMessage
+	LastOutboxID int64
+
+	Tags      []*storage.Tag
+	LastTagID int64
+	EventTags map[int64][]int64
+
+	Attachments      []*storage.Attachment
+	LastAttachmentID int64
+
+	Calendars      []*storage.Calendar
+	LastCalendarID int64
+
+	Webhooks       []*storage.Webhook
+	LastWebhookID  int64
+	Deliveries     map[int64][]*storage.WebhookDelivery
+	LastDeliveryID int64
+
+	Preferences                []*storage.NotificationPreferences
+	Notifications              map[int64][]*storage.NotificationDelivery
+	LastNotificationDeliveryID int64
+	Digests                    []digestState
+}
+
+type digestState struct {
+	UserID   int64
+	Day      string
+	EventIDs []int64
+}
+
+// Save writes everything the storage holds to w, for Load to restore.
+func (m *MemoryStorage) Save(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s := state{
+		Version:                    stateVersion,
+		LastID:                     m.lastID,
+		Outbox:                     m.outbox,
+		LastOutboxID:               m.lastOutboxID,
+		LastTagID:                  m.lastTagID,
+		EventTags:                  make(map[int64][]int64, len(m.eventTags)),
+		LastAttachmentID:           m.lastAttachmentID,
+		LastCalendarID:             m.lastCalendarID,
+		LastWebhookID:              m.lastWebhookID,
+		Deliveries:                 m.deliveries,
+		LastDeliveryID:             m.lastDeliveryID,
+		Notifications:              m.notifications,
+		LastNotificationDeliveryID: m.lastNotificationDeliveryID,
+	}
+	for _, event := range m.events {
+		s.Events = append(s.Events, event)
+	}
+	for id, notified := range m.notified {
+		if notified {
+			s.Notified = append(s.Notified, id)
+		}
+	}
+	for _, tag := range m.tags {
+		s.Tags = append(s.Tags, tag)
+	}
+	for eventID, tagIDs := range m.eventTags {
+		for tagID := range tagIDs {
+			s.EventTags[eventID] = append(s.EventTags[eventID], tagID)
+		}
+	}
+	for _, attachment := range m.attachments {
+		s.Attachments = append(s.Attachments, attachment)
+	}
+	for _, calendar := range m.calendars {
+		s.Calendars = append(s.Calendars, calendar)
+	}
+	for _, webhook := range m.webhooks {
+		s.Webhooks = append(s.Webhooks, webhook)
+	}
+	for _, prefs := range m.preferences {
+		s.Preferences = append(s.Preferences, prefs)
+	}
+	for userID, digest := range m.digested {
+		s.Digests = append(s.Digests, digestState{UserID: userID, Day: digest.day, EventIDs: digest.eventIDs})
+	}
+
+	return gob.NewEncoder(w).Encode(&s)
+}
+
+// Load replaces everything the storage holds with the state Save wrote
+// to r.
+func (m *MemoryStorage) Load(r io.Reader) error {
+	var s state
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return fmt.Errorf("decode memory storage state: %w", err)
+	}
+	if s.Version != stateVersion {
+		return fmt.Errorf("unsupported memory storage state version %d", s.Version)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = make(map[int64]*storage.Event, len(s.Events))
+	m.index = make(searchIndex)
+	for _, event := range s.Events {
+		m.events[event.ID] = event
+		m.index.add(event)
+	}
+	m.lastID = s.LastID
+	m.notified = make(map[int64]bool, len(s.Notified))
+	for _, id := range s.Notified {
+		m.notified[id] = true
+	}
+
+	m.outbox = s.Outbox
+	m.lastOutboxID = s.LastOutboxID
+	m.claimed = make(map[int64]time.Time)
+
+	m.tags = make(map[int64]*storage.Tag, len(s.Tags))
+	for _, tag := range s.Tags {
+		m.tags[tag.ID] = tag
+	}
+	m.lastTagID = s.LastTagID
+	m.eventTags = make(map[int64]map[int64]struct{}, len(s.EventTags))
+	for eventID, tagIDs := range s.EventTags {
+		m.eventTags[eventID] = make(map[int64]struct{}, len(tagIDs))
+		for _, tagID := range tagIDs {
+			m.eventTags[eventID][tagID] = struct{}{}
+		}
+	}
+
+	m.attachments = make(map[int64]*storage.Attachment, len(s.Attachments))
+	for _, attachment := range s.Attachments {
+		m.attachments[attachment.ID] = attachment
+	}
+	m.lastAttachmentID = s.LastAttachmentID
+
+	m.calendars = make(map[int64]*storage.Calendar, len(s.Calendars))
+	for _, calendar := range s.Calendars {
+		m.calendars[calendar.ID] = calendar
+	}
+	m.lastCalendarID = s.LastCalendarID
+
+	m.webhooks = make(map[int64]*storage.Webhook, len(s.Webhooks))
+	for _, webhook := range s.Webhooks {
+		m.webhooks[webhook.ID] = webhook
+	}
+	m.lastWebhookID = s.LastWebhookID
+	m.deliveries = s.Deliveries
+	if m.deliveries == nil {
+		m.deliveries = make(map[int64][]*storage.WebhookDelivery)
+	}
+	m.lastDeliveryID = s.LastDeliveryID
+
+	m.preferences = make(map[int64]*storage.NotificationPreferences, len(s.Preferences))
+	for _, prefs := range s.Preferences {
+		m.preferences[prefs.UserID] = prefs
+	}
+	m.notifications = s.Notifications
+	if m.notifications == nil {
+		m.notifications = make(map[int64][]*storage.NotificationDelivery)
+	}
+	m.lastNotificationDeliveryID = s.LastNotificationDeliveryID
+	m.digested = make(map[int64]digestRecord, len(s.Digests))
+	for _, digest := range s.Digests {
+		m.digested[digest.UserID] = digestRecord{day: digest.Day, eventIDs: digest.EventIDs}
+	}
+
+	return nil
+}
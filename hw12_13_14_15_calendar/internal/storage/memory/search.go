@@ -0,0 +1,128 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Title words weigh more than description words in the rank.
+const (
+	titleWeight       = 2
+	descriptionWeight = 1
+)
+
+// searchIndex maps words to the weights of the events containing them.
+type searchIndex map[string]map[int64]float64
+
+func (idx searchIndex) add(event *storage.Event) {
+	idx.update(event, 1)
+}
+
+func (idx searchIndex) remove(event *storage.Event) {
+	idx.update(event, -1)
+}
+
+func (idx searchIndex) update(event *storage.Event, sign float64) {
+	weights := make(map[string]float64)
+	for _, word := range storage.Tokenize(event.Title) {
+		weights[word] += titleWeight
+	}
+	for _, word := range storage.Tokenize(event.Description) {
+		weights[word] += descriptionWeight
+	}
+
+	for word, weight := range weights {
+		postings := idx[word]
+		if postings == nil {
+			postings = make(map[int64]float64)
+			idx[word] = postings
+		}
+
+		postings[event.ID] += sign * weight
+		if postings[event.ID] <= 0 {
+			delete(postings, event.ID)
+		}
+		if len(postings) == 0 {
+			delete(idx, word)
+		}
+	}
+}
+
+// match returns the rank of the events containing a word starting with
+// each of terms.
+func (idx searchIndex) match(terms []string) map[int64]float64 {
+	var ranks map[int64]float64
+	for _, term := range terms {
+		termRanks := make(map[int64]float64)
+		for word, postings := range idx {
+			if !strings.HasPrefix(word, term) {
+				continue
+			}
+			for id, weight := range postings {
+				// Whole words rank above prefixes.
+				if word != term {
+					weight /= 2
+				}
+				termRanks[id] += weight
+			}
+		}
+
+		if ranks == nil {
+			ranks = termRanks
+			continue
+		}
+		for id := range ranks {
+			if rank, ok := termRanks[id]; ok {
+				ranks[id] += rank
+			} else {
+				delete(ranks, id)
+			}
+		}
+	}
+	return ranks
+}
+
+func (m *MemoryStorage) SearchEvents(ctx context.Context,
+	userID int64,
+	terms []string,
+	from, to time.Time,
+	limit int,
+) ([]*storage.SearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*storage.SearchResult
+	for id, rank := range m.index.match(terms) {
+		event := m.events[id]
+		if event.UserID != userID || event.StartTime.After(to) || event.EndTime.Before(from) {
+			continue
+		}
+
+		result := &storage.SearchResult{
+			Event:          event.Clone(),
+			Rank:           rank,
+			TitleHighlight: storage.Highlight(event.Title, terms),
+		}
+		if event.Description != "" {
+			result.DescriptionHighlight = storage.Highlight(event.Description, terms)
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Event.StartTime.After(results[j].Event.StartTime)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
@@ -0,0 +1,120 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (m *MemoryStorage) ScanCalendars(ctx context.Context, afterID int64, limit int) ([]*storage.Calendar, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var calendars []*storage.Calendar
+	for id, calendar := range m.calendars {
+		if id > afterID {
+			calendarCopy := *calendar
+			calendars = append(calendars, &calendarCopy)
+		}
+	}
+
+	sort.Slice(calendars, func(i, j int) bool { return calendars[i].ID < calendars[j].ID })
+	if len(calendars) > limit {
+		calendars = calendars[:limit]
+	}
+	return calendars, nil
+}
+
+func (m *MemoryStorage) ScanEvents(ctx context.Context, afterID int64, limit int) ([]*storage.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var events []*storage.Event
+	for id, event := range m.events {
+		if id > afterID {
+			events = append(events, event.Clone())
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (m *MemoryStorage) RestoreCalendars(ctx context.Context, calendars []*storage.Calendar, keepIDs bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Everything is checked first, so nothing is restored on failure.
+	names := make(map[int64]map[string]bool)
+	for _, calendar := range calendars {
+		if keepIDs {
+			if _, exists := m.calendars[calendar.ID]; exists {
+				return storage.CalendarAlreadyExists(calendar.Name)
+			}
+		}
+		if names[calendar.UserID] == nil {
+			names[calendar.UserID] = make(map[string]bool)
+		}
+		if m.hasCalendar(&storage.Calendar{UserID: calendar.UserID, Name: calendar.Name}) ||
+			names[calendar.UserID][calendar.Name] {
+			return storage.CalendarAlreadyExists(calendar.Name)
+		}
+		names[calendar.UserID][calendar.Name] = true
+	}
+
+	for _, calendar := range calendars {
+		if keepIDs {
+			m.lastCalendarID = max(m.lastCalendarID, calendar.ID)
+		} else {
+			m.lastCalendarID++
+			calendar.ID = m.lastCalendarID
+		}
+
+		calendarCopy := *calendar
+		m.calendars[calendar.ID] = &calendarCopy
+	}
+	return nil
+}
+
+func (m *MemoryStorage) RestoreEvents(ctx context.Context, events []*storage.Event, keepIDs bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make(map[int64]bool, len(events))
+	for _, event := range events {
+		if keepIDs {
+			if _, exists := m.events[event.ID]; exists || ids[event.ID] {
+				return storage.EventAlreadyExists(event.ID)
+			}
+			ids[event.ID] = true
+		}
+		if err := m.checkCalendar(event); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, event := range events {
+		if keepIDs {
+			m.lastID = max(m.lastID, event.ID)
+		} else {
+			m.lastID++
+			event.ID = m.lastID
+		}
+
+		eventCopy := event.Clone()
+		eventCopy.Attachments = nil
+		m.events[event.ID] = eventCopy
+		m.index.add(eventCopy)
+		if !event.NotifyAt.IsZero() && !event.NotifyAt.After(now) {
+			m.notified[event.ID] = true
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package memorystorage
 
 import (
 	"context"
+	"encoding/json"
 	"sort"
 	"sync"
 	"time"
@@ -11,14 +12,58 @@ import (
 )
 
 type MemoryStorage struct {
-	mu     sync.RWMutex
-	events map[int64]*storage.Event
-	lastID int64
+	mu       sync.RWMutex
+	events   map[int64]*storage.Event
+	lastID   int64
+	notified map[int64]bool
+	index    searchIndex
+
+	// Outbox messages are appended under the same lock as the changes
+	// they describe.
+	outbox       []*storage.OutboxMessage
+	lastOutboxID int64
+	// claimed holds the lease end of relayed messages not yet
+	// acknowledged.
+	claimed map[int64]time.Time
+
+	tags      map[int64]*storage.Tag
+	lastTagID int64
+	eventTags map[int64]map[int64]struct{}
+
+	attachments      map[int64]*storage.Attachment
+	lastAttachmentID int64
+
+	calendars      map[int64]*storage.Calendar
+	lastCalendarID int64
+
+	webhooks       map[int64]*storage.Webhook
+	lastWebhookID  int64
+	deliveries     map[int64][]*storage.WebhookDelivery
+	lastDeliveryID int64
+
+	preferences                map[int64]*storage.NotificationPreferences
+	notifications              map[int64][]*storage.NotificationDelivery
+	lastNotificationDeliveryID int64
+	// digested holds the last digest of each user.
+	digested map[int64]digestRecord
 }
 
 func New() storage.Storage {
 	return &MemoryStorage{
-		events: make(map[int64]*storage.Event),
+		events:      make(map[int64]*storage.Event),
+		notified:    make(map[int64]bool),
+		claimed:     make(map[int64]time.Time),
+		index:       make(searchIndex),
+		tags:        make(map[int64]*storage.Tag),
+		eventTags:   make(map[int64]map[int64]struct{}),
+		attachments: make(map[int64]*storage.Attachment),
+		calendars:   make(map[int64]*storage.Calendar),
+		webhooks:    make(map[int64]*storage.Webhook),
+		deliveries:  make(map[int64][]*storage.WebhookDelivery),
+
+		preferences:   make(map[int64]*storage.NotificationPreferences),
+		notifications: make(map[int64][]*storage.NotificationDelivery),
+		digested:      make(map[int64]digestRecord),
 	}
 }
 
@@ -26,13 +71,20 @@ func (m *MemoryStorage) CreateEvent(ctx context.Context, event *storage.Event) e
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := m.checkCalendar(event); err != nil {
+		return err
+	}
+
 	// Generate new ID
 	m.lastID++
 	event.ID = m.lastID
 
-	// Create deep copy to prevent external modifications
-	eventCopy := *event
-	m.events[event.ID] = &eventCopy
+	// Create deep copy to prevent external modifications. Attachments
+	// are kept apart from the event.
+	eventCopy := event.Clone()
+	eventCopy.Attachments = nil
+	m.events[event.ID] = eventCopy
+	m.index.add(eventCopy)
 
 	return nil
 }
@@ -50,10 +102,21 @@ func (m *MemoryStorage) UpdateEvent(ctx context.Context, event *storage.Event) e
 	if existing.UserID != event.UserID {
 		return storage.EventNotFound(event.ID)
 	}
+	if err := m.checkCalendar(event); err != nil {
+		return err
+	}
+
+	// A changed reminder time needs a new notification.
+	if !existing.NotifyAt.Equal(event.NotifyAt) {
+		delete(m.notified, event.ID)
+	}
 
 	// Create deep copy to prevent external modifications
-	eventCopy := *event
-	m.events[event.ID] = &eventCopy
+	eventCopy := event.Clone()
+	eventCopy.Attachments = nil
+	m.events[event.ID] = eventCopy
+	m.index.remove(existing)
+	m.index.add(eventCopy)
 
 	return nil
 }
@@ -62,14 +125,25 @@ func (m *MemoryStorage) DeleteEvent(ctx context.Context, id int64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.events[id]; !exists {
+	existing, exists := m.events[id]
+	if !exists {
 		return storage.EventNotFound(id)
 	}
 
-	delete(m.events, id)
+	m.removeEvent(existing)
 	return nil
 }
 
+// removeEvent deletes an event with everything linked to it. The caller
+// holds the lock.
+func (m *MemoryStorage) removeEvent(event *storage.Event) {
+	delete(m.events, event.ID)
+	delete(m.notified, event.ID)
+	delete(m.eventTags, event.ID)
+	m.deleteAttachmentsOf(event.ID)
+	m.index.remove(event)
+}
+
 func (m *MemoryStorage) GetEvent(ctx context.Context, id int64) (*storage.Event, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -80,8 +154,7 @@ func (m *MemoryStorage) GetEvent(ctx context.Context, id int64) (*storage.Event,
 	}
 
 	// Return copy to prevent external modifications
-	eventCopy := *event
-	return &eventCopy, nil
+	return event.Clone(), nil
 }
 
 func (m *MemoryStorage) ListEvents(ctx context.Context, userID int64, from, to time.Time) ([]*storage.Event, error) {
@@ -95,8 +168,7 @@ func (m *MemoryStorage) ListEvents(ctx context.Context, userID int64, from, to t
 			!event.StartTime.After(to) &&
 			!event.EndTime.Before(from) {
 			// Add copy to prevent external modifications
-			eventCopy := *event
-			events = append(events, &eventCopy)
+			events = append(events, event.Clone())
 		}
 	}
 
@@ -113,8 +185,7 @@ func (m *MemoryStorage) ListUpcomingEvents(ctx context.Context, userID int64, li
 	// First, collect all upcoming events for the user
 	for _, event := range m.events {
 		if event.UserID == userID && event.StartTime.After(now) {
-			eventCopy := *event
-			events = append(events, &eventCopy)
+			events = append(events, event.Clone())
 		}
 	}
 
@@ -136,9 +207,8 @@ func (m *MemoryStorage) ListEventsNeedingNotification(ctx context.Context, befor
 	var events []*storage.Event
 
 	for _, event := range m.events {
-		if !event.NotifyAt.IsZero() && event.NotifyAt.Before(before) {
-			eventCopy := *event
-			events = append(events, &eventCopy)
+		if !event.NotifyAt.IsZero() && event.NotifyAt.Before(before) && !m.notified[event.ID] {
+			events = append(events, event.Clone())
 		}
 	}
 
@@ -162,8 +232,7 @@ func (m *MemoryStorage) GetEventsByTimeRange(ctx context.Context,
 			(isTimeInRange(event.StartTime, start, end) ||
 				isTimeInRange(event.EndTime, start, end) ||
 				(event.StartTime.Before(start) && event.EndTime.After(end))) {
-			eventCopy := *event
-			events = append(events, &eventCopy)
+			events = append(events, event.Clone())
 		}
 	}
 
@@ -179,6 +248,19 @@ func (m *MemoryStorage) Close() error {
 
 	// Clear all data
 	m.events = make(map[int64]*storage.Event)
+	m.notified = make(map[int64]bool)
+	m.index = make(searchIndex)
+	m.tags = make(map[int64]*storage.Tag)
+	m.eventTags = make(map[int64]map[int64]struct{})
+	m.attachments = make(map[int64]*storage.Attachment)
+	m.calendars = make(map[int64]*storage.Calendar)
+	m.outbox = nil
+	m.claimed = make(map[int64]time.Time)
+	m.webhooks = make(map[int64]*storage.Webhook)
+	m.deliveries = make(map[int64][]*storage.WebhookDelivery)
+	m.preferences = make(map[int64]*storage.NotificationPreferences)
+	m.notifications = make(map[int64][]*storage.NotificationDelivery)
+	m.digested = make(map[int64]digestRecord)
 	return nil
 }
 
@@ -186,8 +268,109 @@ func (m *MemoryStorage) Ping() error {
 	return nil // Memory storage is always available
 }
 
+func (m *MemoryStorage) EnqueueDueNotifications(ctx context.Context, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*storage.Event
+	for _, event := range m.events {
+		if !event.NotifyAt.IsZero() && event.NotifyAt.Before(before) && !m.notified[event.ID] {
+			due = append(due, event)
+		}
+	}
+	sortEventsByNotifyAt(due)
+
+	for _, event := range due {
+		payload, err := json.Marshal(storage.Notification{
+			EventID:   event.ID,
+			Title:     event.Title,
+			StartTime: event.StartTime,
+			UserID:    event.UserID,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		m.notified[event.ID] = true
+		m.addOutbox(storage.TopicNotification, storage.NotificationKey(event.ID, event.NotifyAt), payload)
+	}
+
+	return len(due), nil
+}
+
+func (m *MemoryStorage) FetchOutbox(ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*storage.OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var messages []*storage.OutboxMessage
+	for _, msg := range m.outbox {
+		if len(messages) == limit {
+			break
+		}
+		if until, ok := m.claimed[msg.ID]; ok && now.Before(until) {
+			continue
+		}
+
+		m.claimed[msg.ID] = now.Add(lease)
+		msgCopy := *msg
+		messages = append(messages, &msgCopy)
+	}
+
+	return messages, nil
+}
+
+func (m *MemoryStorage) DeleteOutbox(ctx context.Context, ids []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remove := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+		delete(m.claimed, id)
+	}
+
+	pending := m.outbox[:0]
+	for _, msg := range m.outbox {
+		if !remove[msg.ID] {
+			pending = append(pending, msg)
+		}
+	}
+	m.outbox = pending
+
+	return nil
+}
+
+func (m *MemoryStorage) DeferOutbox(ctx context.Context, id int64, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A message acknowledged meanwhile is not claimed again.
+	for _, msg := range m.outbox {
+		if msg.ID == id {
+			m.claimed[id] = until
+			break
+		}
+	}
+	return nil
+}
+
 // Helper functions
 
+func (m *MemoryStorage) addOutbox(topic, key string, payload []byte) {
+	m.lastOutboxID++
+	m.outbox = append(m.outbox, &storage.OutboxMessage{
+		ID:             m.lastOutboxID,
+		Topic:          topic,
+		IdempotencyKey: key,
+		Payload:        payload,
+		CreatedAt:      time.Now(),
+	})
+}
+
 func sortEventsByStartTime(events []*storage.Event) {
 	sort.Slice(events, func(i, j int) bool {
 		return events[i].StartTime.Before(events[j].StartTime)
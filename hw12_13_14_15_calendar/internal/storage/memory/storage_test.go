@@ -1,6 +1,7 @@
 package memorystorage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
@@ -301,3 +302,479 @@ func TestMemoryStorage_CloseAndPing(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestMemoryStorage_Outbox(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+	now := time.Now()
+
+	event := &storage.Event{
+		Title:     "Remind Me",
+		UserID:    1,
+		StartTime: now.Add(time.Hour),
+		EndTime:   now.Add(2 * time.Hour),
+		NotifyAt:  now.Add(-time.Minute),
+	}
+	require.NoError(t, store.CreateEvent(ctx, event))
+
+	t.Run("due notifications are enqueued once", func(t *testing.T) {
+		n, err := store.EnqueueDueNotifications(ctx, now)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		n, err = store.EnqueueDueNotifications(ctx, now)
+		require.NoError(t, err)
+		require.Zero(t, n)
+
+		listed, err := store.ListEventsNeedingNotification(ctx, now)
+		require.NoError(t, err)
+		require.Empty(t, listed)
+
+		messages, err := store.FetchOutbox(ctx, 10, time.Hour)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, storage.TopicNotification, messages[0].Topic)
+		require.Equal(t, storage.NotificationKey(event.ID, event.NotifyAt), messages[0].IdempotencyKey)
+		require.NoError(t, store.DeleteOutbox(ctx, []int64{messages[0].ID}))
+	})
+
+	t.Run("claimed messages are relayed again after the lease", func(t *testing.T) {
+		event.NotifyAt = now.Add(-45 * time.Second)
+		require.NoError(t, store.UpdateEvent(ctx, event))
+		_, err := store.EnqueueDueNotifications(ctx, now)
+		require.NoError(t, err)
+
+		claimed, err := store.FetchOutbox(ctx, 10, time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+
+		time.Sleep(5 * time.Millisecond)
+		again, err := store.FetchOutbox(ctx, 10, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, claimed, again)
+
+		pending, err := store.FetchOutbox(ctx, 10, time.Hour)
+		require.NoError(t, err)
+		require.Empty(t, pending)
+		require.NoError(t, store.DeleteOutbox(ctx, []int64{claimed[0].ID}))
+	})
+
+	t.Run("changed reminder is enqueued again", func(t *testing.T) {
+		event.NotifyAt = now.Add(-30 * time.Second)
+		require.NoError(t, store.UpdateEvent(ctx, event))
+
+		n, err := store.EnqueueDueNotifications(ctx, now)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		messages, err := store.FetchOutbox(ctx, 10, time.Hour)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, storage.NotificationKey(event.ID, event.NotifyAt), messages[0].IdempotencyKey)
+	})
+}
+
+func TestMemoryStorage_ApplyBatch(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+	now := time.Now()
+
+	existing := &storage.Event{Title: "Existing", UserID: 1, StartTime: now, EndTime: now.Add(time.Hour)}
+	require.NoError(t, store.CreateEvent(ctx, existing))
+
+	t.Run("applies all operations", func(t *testing.T) {
+		created := &storage.Event{Title: "Created", UserID: 1, StartTime: now, EndTime: now.Add(time.Hour)}
+		deleted := &storage.Event{ID: existing.ID, UserID: 1}
+		require.NoError(t, store.ApplyBatch(ctx, []storage.BatchOp{
+			{Type: storage.BatchCreate, Event: created},
+			{Type: storage.BatchDelete, Event: deleted},
+		}))
+
+		require.NotZero(t, created.ID)
+		require.Equal(t, "Existing", deleted.Title)
+		_, err := store.GetEvent(ctx, existing.ID)
+		require.True(t, storage.IsNotFound(err))
+	})
+
+	t.Run("rolls back on failure", func(t *testing.T) {
+		err := store.ApplyBatch(ctx, []storage.BatchOp{
+			{Type: storage.BatchCreate, Event: &storage.Event{Title: "Rolled back", UserID: 1}},
+			{Type: storage.BatchUpdate, Event: &storage.Event{ID: 1000, Title: "Missing", UserID: 1}},
+		})
+		var batchErr *storage.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 1, batchErr.Index)
+		require.True(t, storage.IsNotFound(err))
+
+		events, err := store.ListEvents(ctx, 1, now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "Created", events[0].Title)
+	})
+
+	t.Run("deletes only own events", func(t *testing.T) {
+		other := &storage.Event{Title: "Other", UserID: 2, StartTime: now, EndTime: now}
+		require.NoError(t, store.CreateEvent(ctx, other))
+
+		err := store.ApplyBatch(ctx, []storage.BatchOp{
+			{Type: storage.BatchDelete, Event: &storage.Event{ID: other.ID, UserID: 1}},
+		})
+		require.True(t, storage.IsNotFound(err))
+	})
+}
+
+func TestMemoryStorage_SearchEvents(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+	spring := time.Date(2024, 4, 10, 10, 0, 0, 0, time.UTC)
+
+	vendor := &storage.Event{
+		Title:       "Vendor meeting",
+		Description: "Contract review with the vendor",
+		UserID:      1,
+		StartTime:   spring,
+		EndTime:     spring.Add(time.Hour),
+	}
+	standup := &storage.Event{
+		Title:       "Standup",
+		Description: "Vendor updates",
+		UserID:      1,
+		StartTime:   spring.AddDate(0, 3, 0),
+		EndTime:     spring.AddDate(0, 3, 0).Add(time.Hour),
+	}
+	other := &storage.Event{Title: "Vendor call", UserID: 2, StartTime: spring, EndTime: spring}
+	for _, e := range []*storage.Event{vendor, standup, other} {
+		require.NoError(t, store.CreateEvent(ctx, e))
+	}
+
+	search := func(query string, from, to time.Time) []*storage.SearchResult {
+		results, err := store.SearchEvents(ctx, 1, storage.Tokenize(query), from, to, 10)
+		require.NoError(t, err)
+		return results
+	}
+	always := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ranks title matches first", func(t *testing.T) {
+		results := search("vendor", time.Time{}, always)
+		require.Len(t, results, 2)
+		require.Equal(t, vendor.ID, results[0].Event.ID)
+		require.Equal(t, "<mark>Vendor</mark> meeting", results[0].TitleHighlight)
+		require.Equal(t, "Contract review with the <mark>vendor</mark>", results[0].DescriptionHighlight)
+		require.Equal(t, standup.ID, results[1].Event.ID)
+	})
+
+	t.Run("matches prefixes of all terms", func(t *testing.T) {
+		results := search("vend meet", time.Time{}, always)
+		require.Len(t, results, 1)
+		require.Equal(t, "<mark>Vendor</mark> <mark>meeting</mark>", results[0].TitleHighlight)
+	})
+
+	t.Run("filters by time range", func(t *testing.T) {
+		results := search("vendor", spring.AddDate(0, 1, 0), always)
+		require.Len(t, results, 1)
+		require.Equal(t, standup.ID, results[0].Event.ID)
+	})
+
+	t.Run("follows updates and deletes", func(t *testing.T) {
+		standup.Description = "Daily sync"
+		require.NoError(t, store.UpdateEvent(ctx, standup))
+		require.Len(t, search("vendor", time.Time{}, always), 1)
+		require.Len(t, search("daily", time.Time{}, always), 1)
+
+		require.NoError(t, store.DeleteEvent(ctx, vendor.ID))
+		require.Empty(t, search("vendor", time.Time{}, always))
+	})
+
+	t.Run("escapes highlights", func(t *testing.T) {
+		review := &storage.Event{
+			Title:       `<img src=x onerror="alert(1)"> review`,
+			Description: "Follow-up with bob@example.com & co",
+			UserID:      1,
+			StartTime:   spring,
+			EndTime:     spring.Add(time.Hour),
+		}
+		require.NoError(t, store.CreateEvent(ctx, review))
+
+		results := search("review", time.Time{}, always)
+		require.Len(t, results, 1)
+		require.Equal(t, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>review</mark>", results[0].TitleHighlight)
+
+		// Emails and hyphenated words are found by their parts.
+		results = search("example up", time.Time{}, always)
+		require.Len(t, results, 1)
+		require.Equal(t, "Follow-<mark>up</mark> with bob@<mark>example</mark>.com &amp; co",
+			results[0].DescriptionHighlight)
+	})
+}
+
+func TestMemoryStorage_Tags(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+	now := time.Now()
+
+	work := &storage.Tag{UserID: 1, Name: "work", Kind: storage.TagKindTag, Color: "#1e90ff"}
+	urgent := &storage.Tag{UserID: 1, Name: "urgent", Kind: storage.TagKindTag, Color: "#ff0000"}
+	require.NoError(t, store.CreateTag(ctx, work))
+	require.NoError(t, store.CreateTag(ctx, urgent))
+	require.True(t, storage.IsAlreadyExists(store.CreateTag(ctx, &storage.Tag{UserID: 1, Name: "work", Kind: storage.TagKindTag})))
+	category := &storage.Tag{UserID: 1, Name: "work", Kind: storage.TagKindCategory}
+	require.NoError(t, store.CreateTag(ctx, category))
+
+	home := &storage.Tag{UserID: 1, Name: "home", Kind: storage.TagKindCategory}
+	require.NoError(t, store.CreateTag(ctx, home))
+	err := store.UpdateTag(ctx, &storage.Tag{ID: home.ID, UserID: 1, Name: "work"})
+	require.True(t, storage.IsAlreadyExists(err))
+	require.EqualError(t, err, `category "work": already exists`)
+
+	first := &storage.Event{Title: "First", UserID: 1, StartTime: now, EndTime: now.Add(time.Hour)}
+	second := &storage.Event{Title: "Second", UserID: 1, StartTime: now, EndTime: now.Add(time.Hour)}
+	require.NoError(t, store.CreateEvent(ctx, first))
+	require.NoError(t, store.CreateEvent(ctx, second))
+	require.NoError(t, store.SetEventTags(ctx, first.ID, []int64{work.ID, urgent.ID}))
+	require.NoError(t, store.SetEventTags(ctx, second.ID, []int64{work.ID}))
+
+	list := func(tagIDs ...int64) []*storage.Event {
+		events, err := store.ListEventsByTags(ctx, 1, tagIDs, now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		return events
+	}
+
+	require.Len(t, list(work.ID), 2)
+	require.Len(t, list(work.ID, urgent.ID), 1)
+
+	tags, err := store.GetEventTags(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"urgent", "work"}, []string{tags[0].Name, tags[1].Name})
+
+	require.NoError(t, store.DeleteTag(ctx, urgent.ID))
+	require.Empty(t, list(urgent.ID))
+	tags, err = store.GetEventTags(ctx, first.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+
+	require.NoError(t, store.DeleteEvent(ctx, first.ID))
+	require.Len(t, list(work.ID), 1)
+}
+
+func TestMemoryStorage_Attachments(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+	now := time.Now()
+
+	event := &storage.Event{Title: "Review", UserID: 1, StartTime: now, EndTime: now.Add(time.Hour)}
+	require.NoError(t, store.CreateEvent(ctx, event))
+
+	agenda := &storage.Attachment{EventID: event.ID, Name: "agenda.pdf", MimeType: "application/pdf", Size: 10, Key: "events/1/a"}
+	notes := &storage.Attachment{EventID: event.ID, Name: "notes.txt", MimeType: "text/plain", Size: 5, Key: "events/1/b"}
+	require.NoError(t, store.CreateAttachment(ctx, agenda))
+	require.NoError(t, store.CreateAttachment(ctx, notes))
+	require.NotZero(t, agenda.ID)
+	require.False(t, agenda.CreatedAt.IsZero())
+	require.True(t, storage.IsNotFound(store.CreateAttachment(ctx, &storage.Attachment{EventID: 1000, Name: "x"})))
+
+	got, err := store.GetAttachment(ctx, agenda.ID)
+	require.NoError(t, err)
+	require.Equal(t, agenda, got)
+
+	attachments, err := store.ListAttachments(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"agenda.pdf", "notes.txt"}, []string{attachments[0].Name, attachments[1].Name})
+
+	require.NoError(t, store.DeleteAttachment(ctx, agenda.ID))
+	require.True(t, storage.IsNotFound(store.DeleteAttachment(ctx, agenda.ID)))
+
+	require.NoError(t, store.DeleteEvent(ctx, event.ID))
+	_, err = store.GetAttachment(ctx, notes.ID)
+	require.True(t, storage.IsNotFound(err))
+}
+
+func TestMemoryStorage_Calendars(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+	now := time.Now()
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	work := &storage.Calendar{UserID: 1, Name: "Work", Color: "#1e90ff", TimeZone: "UTC"}
+	home := &storage.Calendar{UserID: 1, Name: "Home", Color: "#00ff00", TimeZone: "UTC"}
+	require.NoError(t, store.CreateCalendar(ctx, work))
+	require.NoError(t, store.CreateCalendar(ctx, home))
+	require.True(t, storage.IsAlreadyExists(store.CreateCalendar(ctx, &storage.Calendar{UserID: 1, Name: "Work"})))
+	require.NoError(t, store.CreateCalendar(ctx, &storage.Calendar{UserID: 2, Name: "Work"}))
+
+	calendars, err := store.ListCalendars(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Home", "Work"}, []string{calendars[0].Name, calendars[1].Name})
+
+	home.Name = "Work"
+	require.True(t, storage.IsAlreadyExists(store.UpdateCalendar(ctx, home)))
+	home.Name, home.DefaultReminder = "Personal", 15
+	require.NoError(t, store.UpdateCalendar(ctx, home))
+	got, err := store.GetCalendar(ctx, home.ID)
+	require.NoError(t, err)
+	require.Equal(t, home, got)
+
+	create := func(title string, calendarID int64) *storage.Event {
+		event := &storage.Event{Title: title, UserID: 1, CalendarID: calendarID, StartTime: now, EndTime: now.Add(time.Hour)}
+		require.NoError(t, store.CreateEvent(ctx, event))
+		return event
+	}
+	standup := create("Standup", work.ID)
+	create("Review", work.ID)
+	create("Gym", home.ID)
+	create("Loose", 0)
+	require.True(t, storage.IsNotFound(store.CreateEvent(ctx, &storage.Event{Title: "x", UserID: 1, CalendarID: 1000})))
+
+	list := func(calendarIDs ...int64) []*storage.Event {
+		events, err := store.ListEventsByCalendars(ctx, 1, calendarIDs, from, to)
+		require.NoError(t, err)
+		return events
+	}
+	require.Len(t, list(work.ID), 2)
+	require.Len(t, list(work.ID, home.ID), 3)
+
+	deleted, err := store.DeleteCalendar(ctx, work.ID)
+	require.NoError(t, err)
+	require.Len(t, deleted, 2)
+	_, err = store.GetEvent(ctx, standup.ID)
+	require.True(t, storage.IsNotFound(err))
+	require.Empty(t, list(work.ID))
+
+	events, err := store.ListEvents(ctx, 1, from, to)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	_, err = store.DeleteCalendar(ctx, work.ID)
+	require.True(t, storage.IsNotFound(err))
+}
+
+func TestMemoryStorage_Notifications(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+
+	prefs, err := store.GetNotificationPreferences(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, storage.DefaultNotificationPreferences(1), prefs)
+
+	prefs.QuietStart, prefs.QuietEnd, prefs.TimeZone = "22:00", "07:00", "Europe/Berlin"
+	require.NoError(t, store.SetNotificationPreferences(ctx, prefs))
+	require.False(t, prefs.UpdatedAt.IsZero())
+	got, err := store.GetNotificationPreferences(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, prefs, got)
+
+	for i := 0; i < storage.NotificationHistorySize+2; i++ {
+		require.NoError(t, store.AddNotificationDelivery(ctx, &storage.NotificationDelivery{
+			UserID:  1,
+			EventID: int64(i%2 + 1),
+			Key:     fmt.Sprintf("key-%d", i),
+			Channel: storage.ChannelPush,
+			Status:  storage.DeliverySent,
+		}))
+	}
+	require.NoError(t, store.AddNotificationDelivery(ctx, &storage.NotificationDelivery{UserID: 2, Key: "other"}))
+
+	deliveries, err := store.ListNotificationDeliveries(ctx, 1, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, storage.NotificationHistorySize)
+	require.Equal(t, fmt.Sprintf("key-%d", storage.NotificationHistorySize+1), deliveries[0].Key)
+	require.Equal(t, "key-2", deliveries[len(deliveries)-1].Key)
+
+	deliveries, err = store.ListNotificationDeliveries(ctx, 1, 2, 3)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+	for _, delivery := range deliveries {
+		require.Equal(t, int64(2), delivery.EventID)
+	}
+}
+
+func TestMemoryStorage_SaveLoad(t *testing.T) {
+	store := New().(*MemoryStorage)
+	ctx := context.Background()
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	calendar := &storage.Calendar{UserID: 1, Name: "Work", Color: "#1e90ff", TimeZone: "UTC"}
+	require.NoError(t, store.CreateCalendar(ctx, calendar))
+	event := &storage.Event{
+		Title: "Standup", UserID: 1, CalendarID: calendar.ID,
+		StartTime: start, EndTime: start.Add(15 * time.Minute), NotifyAt: start.Add(-time.Minute),
+	}
+	require.NoError(t, store.CreateEvent(ctx, event))
+	_, err := store.EnqueueDueNotifications(ctx, start)
+	require.NoError(t, err)
+
+	tag := &storage.Tag{UserID: 1, Name: "daily", Kind: storage.TagKindTag}
+	require.NoError(t, store.CreateTag(ctx, tag))
+	require.NoError(t, store.SetEventTags(ctx, event.ID, []int64{tag.ID}))
+	attachment := &storage.Attachment{EventID: event.ID, Name: "notes.txt", Size: 5, Key: "events/1/a"}
+	require.NoError(t, store.CreateAttachment(ctx, attachment))
+	webhook := &storage.Webhook{UserID: 1, URL: "https://example.com/hook", Secret: "s3cret"}
+	require.NoError(t, store.CreateWebhook(ctx, webhook))
+	require.NoError(t, store.AddWebhookDelivery(ctx, &storage.WebhookDelivery{WebhookID: webhook.ID, Success: true}))
+	require.NoError(t, store.SetNotificationPreferences(ctx, &storage.NotificationPreferences{
+		UserID: 1, Channel: storage.ChannelLog, Digest: true, DigestTime: "08:00", TimeZone: "UTC",
+	}))
+	require.NoError(t, store.AddNotificationDelivery(ctx, &storage.NotificationDelivery{
+		UserID: 1, EventID: event.ID, Key: "key", Status: storage.DeliverySent,
+	}))
+	_, err = store.EnqueueDigest(ctx, "2025-03-10", storage.Notification{UserID: 1, EventIDs: []int64{event.ID}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, store.Save(&buf))
+	loaded := New().(*MemoryStorage)
+	require.NoError(t, loaded.Load(&buf))
+
+	got, err := loaded.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Standup", got.Title)
+	require.Equal(t, calendar.ID, got.CalendarID)
+	require.True(t, start.Equal(got.StartTime))
+	results, err := loaded.SearchEvents(ctx, 1, storage.Tokenize("standup"), start, start.Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// Reminders sent before the restart are not sent again, and pending
+	// outbox messages are relayed.
+	due, err := loaded.EnqueueDueNotifications(ctx, start)
+	require.NoError(t, err)
+	require.Zero(t, due)
+	messages, err := loaded.FetchOutbox(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+
+	tags, err := loaded.GetEventTags(ctx, event.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	require.Equal(t, "daily", tags[0].Name)
+
+	gotAttachment, err := loaded.GetAttachment(ctx, attachment.ID)
+	require.NoError(t, err)
+	require.Equal(t, "events/1/a", gotAttachment.Key)
+
+	gotWebhook, err := loaded.GetWebhook(ctx, webhook.ID)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", gotWebhook.Secret)
+	webhookDeliveries, err := loaded.ListWebhookDeliveries(ctx, webhook.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, webhookDeliveries, 1)
+
+	prefs, err := loaded.GetNotificationPreferences(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, prefs.Digest)
+	handled, err := loaded.NotificationHandled(ctx, 1, "key")
+	require.NoError(t, err)
+	require.True(t, handled)
+	inDigest, err := loaded.InDigest(ctx, 1, event.ID)
+	require.NoError(t, err)
+	require.True(t, inDigest)
+	added, err := loaded.EnqueueDigest(ctx, "2025-03-10", storage.Notification{UserID: 1})
+	require.NoError(t, err)
+	require.False(t, added)
+
+	// New records do not reuse IDs.
+	next := &storage.Event{Title: "Retro", UserID: 1, StartTime: start, EndTime: start.Add(time.Hour)}
+	require.NoError(t, loaded.CreateEvent(ctx, next))
+	require.Greater(t, next.ID, event.ID)
+
+	require.Error(t, loaded.Load(bytes.NewReader([]byte("not a state"))))
+}
@@ -0,0 +1,176 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (m *MemoryStorage) CreateTag(ctx context.Context, tag *storage.Tag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hasTag(tag) {
+		return storage.TagAlreadyExists(tag.Kind, tag.Name)
+	}
+
+	m.lastTagID++
+	tag.ID = m.lastTagID
+	tag.CreatedAt = time.Now()
+
+	tagCopy := *tag
+	m.tags[tag.ID] = &tagCopy
+	return nil
+}
+
+func (m *MemoryStorage) GetTag(ctx context.Context, id int64) (*storage.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tag, exists := m.tags[id]
+	if !exists {
+		return nil, storage.TagNotFound(id)
+	}
+
+	tagCopy := *tag
+	return &tagCopy, nil
+}
+
+func (m *MemoryStorage) ListTags(ctx context.Context, userID int64) ([]*storage.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tags []*storage.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			tagCopy := *tag
+			tags = append(tags, &tagCopy)
+		}
+	}
+
+	sortTags(tags)
+	return tags, nil
+}
+
+func (m *MemoryStorage) UpdateTag(ctx context.Context, tag *storage.Tag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.tags[tag.ID]
+	if !exists || existing.UserID != tag.UserID {
+		return storage.TagNotFound(tag.ID)
+	}
+
+	tag.Kind, tag.CreatedAt = existing.Kind, existing.CreatedAt
+	if m.hasTag(tag) {
+		return storage.TagAlreadyExists(tag.Kind, tag.Name)
+	}
+
+	tagCopy := *tag
+	m.tags[tag.ID] = &tagCopy
+	return nil
+}
+
+func (m *MemoryStorage) DeleteTag(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tags[id]; !exists {
+		return storage.TagNotFound(id)
+	}
+
+	delete(m.tags, id)
+	for _, tags := range m.eventTags {
+		delete(tags, id)
+	}
+	return nil
+}
+
+func (m *MemoryStorage) SetEventTags(ctx context.Context, eventID int64, tagIDs []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.events[eventID]; !exists {
+		return storage.EventNotFound(eventID)
+	}
+
+	tags := make(map[int64]struct{}, len(tagIDs))
+	for _, id := range tagIDs {
+		if _, exists := m.tags[id]; !exists {
+			return storage.TagNotFound(id)
+		}
+		tags[id] = struct{}{}
+	}
+
+	m.eventTags[eventID] = tags
+	return nil
+}
+
+func (m *MemoryStorage) GetEventTags(ctx context.Context, eventID int64) ([]*storage.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tags []*storage.Tag
+	for id := range m.eventTags[eventID] {
+		tagCopy := *m.tags[id]
+		tags = append(tags, &tagCopy)
+	}
+
+	sortTags(tags)
+	return tags, nil
+}
+
+func (m *MemoryStorage) ListEventsByTags(ctx context.Context,
+	userID int64,
+	tagIDs []int64,
+	from, to time.Time,
+) ([]*storage.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var events []*storage.Event
+	for _, event := range m.events {
+		if event.UserID != userID || event.StartTime.After(to) || event.EndTime.Before(from) ||
+			!m.hasAllTags(event.ID, tagIDs) {
+			continue
+		}
+
+		events = append(events, event.Clone())
+	}
+
+	sortEventsByStartTime(events)
+	return events, nil
+}
+
+// hasTag reports whether the owner of tag has another tag of the same
+// kind and name. The caller holds the lock.
+func (m *MemoryStorage) hasTag(tag *storage.Tag) bool {
+	for _, other := range m.tags {
+		if other.ID != tag.ID && other.UserID == tag.UserID && other.Kind == tag.Kind && other.Name == tag.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStorage) hasAllTags(eventID int64, tagIDs []int64) bool {
+	tags := m.eventTags[eventID]
+	for _, id := range tagIDs {
+		if _, ok := tags[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sortTags(tags []*storage.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Kind != tags[j].Kind {
+			return tags[i].Kind < tags[j].Kind
+		}
+		return tags[i].Name < tags[j].Name
+	})
+}
@@ -0,0 +1,110 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (m *MemoryStorage) CreateWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastWebhookID++
+	webhook.ID = m.lastWebhookID
+	webhook.CreatedAt = time.Now()
+
+	webhookCopy := *webhook
+	m.webhooks[webhook.ID] = &webhookCopy
+	return nil
+}
+
+func (m *MemoryStorage) GetWebhook(ctx context.Context, id int64) (*storage.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	webhook, exists := m.webhooks[id]
+	if !exists {
+		return nil, storage.WebhookNotFound(id)
+	}
+
+	webhookCopy := *webhook
+	return &webhookCopy, nil
+}
+
+func (m *MemoryStorage) ListWebhooks(ctx context.Context, userID int64) ([]*storage.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var webhooks []*storage.Webhook
+	for _, webhook := range m.webhooks {
+		if webhook.UserID == userID {
+			webhookCopy := *webhook
+			webhooks = append(webhooks, &webhookCopy)
+		}
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+func (m *MemoryStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.webhooks[id]; !exists {
+		return storage.WebhookNotFound(id)
+	}
+
+	delete(m.webhooks, id)
+	delete(m.deliveries, id)
+	return nil
+}
+
+func (m *MemoryStorage) AddWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.webhooks[delivery.WebhookID]; !exists {
+		return storage.WebhookNotFound(delivery.WebhookID)
+	}
+
+	m.lastDeliveryID++
+	delivery.ID = m.lastDeliveryID
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+
+	deliveryCopy := *delivery
+	log := append(m.deliveries[delivery.WebhookID], &deliveryCopy)
+	if len(log) > storage.WebhookDeliveryLogSize {
+		log = log[len(log)-storage.WebhookDeliveryLogSize:]
+	}
+	m.deliveries[delivery.WebhookID] = log
+	return nil
+}
+
+func (m *MemoryStorage) ListWebhookDeliveries(ctx context.Context,
+	webhookID int64,
+	limit int,
+) ([]*storage.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	log := m.deliveries[webhookID]
+	if limit <= 0 || limit > len(log) {
+		limit = len(log)
+	}
+
+	deliveries := make([]*storage.WebhookDelivery, 0, limit)
+	for i := len(log) - 1; i >= len(log)-limit; i-- {
+		deliveryCopy := *log[i]
+		deliveries = append(deliveries, &deliveryCopy)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Notification channels.
+const (
+	// ChannelPush delivers notifications to the event streams and
+	// webhooks of the user.
+	ChannelPush = "push"
+	// ChannelLog only writes notifications to the service log.
+	ChannelLog = "log"
+)
+
+// Outcomes of notification deliveries.
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+	// DeliveryDeferred notifications fell in the quiet hours of the user
+	// and are delivered again when they end.
+	DeliveryDeferred = "deferred"
+	// DeliveryDigested notifications are about events in a daily digest
+	// already enqueued for the user.
+	DeliveryDigested = "digested"
+)
+
+// TimeOfDayLayout is the layout of times of day in preferences.
+const TimeOfDayLayout = "15:04"
+
+// DigestDayLayout is the layout of the days of digests.
+const DigestDayLayout = "2006-01-02"
+
+// NotificationHistorySize is the number of recent deliveries kept per user.
+const NotificationHistorySize = 1000
+
+// NotificationPreferences are the notification settings of a user. Times
+// of day are "15:04" in TimeZone.
+type NotificationPreferences struct {
+	UserID  int64  `json:"user_id" db:"user_id"`
+	Channel string `json:"channel" db:"channel"`
+	// Reminders due from QuietStart until QuietEnd, which may be on the
+	// next day, are sent at QuietEnd. Both are empty without quiet hours.
+	QuietStart string `json:"quiet_start,omitempty" db:"quiet_start"`
+	QuietEnd   string `json:"quiet_end,omitempty" db:"quiet_end"`
+	// Digest replaces reminders of the events in the daily agenda sent at
+	// DigestTime. Reminders of events added to the day after its agenda
+	// was enqueued are still sent.
+	Digest     bool      `json:"digest" db:"digest"`
+	DigestTime string    `json:"digest_time" db:"digest_time"`
+	TimeZone   string    `json:"time_zone" db:"time_zone"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultNotificationPreferences are the preferences of a user who set
+// none: push reminders at any time.
+func DefaultNotificationPreferences(userID int64) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID:     userID,
+		Channel:    ChannelPush,
+		DigestTime: "08:00",
+		TimeZone:   "UTC",
+	}
+}
+
+// NotificationDelivery is an attempt to deliver a notification to a user.
+type NotificationDelivery struct {
+	ID     int64 `json:"id" db:"id"`
+	UserID int64 `json:"user_id" db:"user_id"`
+	// EventID is zero for notifications about no single event.
+	EventID int64 `json:"event_id,omitempty" db:"event_id"`
+	// Key is shared by the attempts to deliver a notification.
+	Key       string    `json:"key" db:"key"`
+	Channel   string    `json:"channel" db:"channel"`
+	Status    string    `json:"status" db:"status"`
+	Error     string    `json:"error,omitempty" db:"error"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// NotificationStore is implemented by storages that keep notification
+// preferences and delivery history.
+type NotificationStore interface {
+	// GetNotificationPreferences returns the preferences of a user, or the
+	// defaults if they set none.
+	GetNotificationPreferences(ctx context.Context, userID int64) (*NotificationPreferences, error)
+	SetNotificationPreferences(ctx context.Context, prefs *NotificationPreferences) error
+	// AddNotificationDelivery records a delivery, dropping the oldest of
+	// the user beyond NotificationHistorySize.
+	AddNotificationDelivery(ctx context.Context, delivery *NotificationDelivery) error
+	// ListNotificationDeliveries returns up to limit deliveries of a user,
+	// newest first. A non-zero eventID only lists those of the event.
+	ListNotificationDeliveries(ctx context.Context, userID, eventID int64, limit int) ([]*NotificationDelivery, error)
+	// NotificationHandled reports whether a delivery of the notification
+	// with key, other than a failed or deferred one, is in the history of
+	// the user.
+	NotificationHandled(ctx context.Context, userID int64, key string) (bool, error)
+	// InDigest reports whether the event is in the agenda of the last
+	// digest enqueued for the user.
+	InDigest(ctx context.Context, userID, eventID int64) (bool, error)
+}
+
+// DigestOutbox is implemented by storages that enqueue daily digests.
+type DigestOutbox interface {
+	// ListDigestPreferences returns the preferences of users who chose a
+	// daily digest.
+	ListDigestPreferences(ctx context.Context) ([]*NotificationPreferences, error)
+	// EnqueueDigest adds digest to the outbox unless a digest of its user
+	// for day was added before, atomically. It reports whether it was
+	// added.
+	EnqueueDigest(ctx context.Context, day string, digest Notification) (bool, error)
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TopicNotification is the outbox topic of notifications.
+const TopicNotification = "notification"
+
+// OutboxMessage is a message stored in the same transaction as the change
+// it describes, waiting to be relayed to the queue.
+type OutboxMessage struct {
+	ID int64 `db:"id"`
+	// Topic is TopicNotification.
+	Topic string `db:"topic"`
+	// IdempotencyKey is unique per message, so consumers can drop
+	// redelivered copies.
+	IdempotencyKey string    `db:"idempotency_key"`
+	Payload        []byte    `db:"payload"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// NotificationDigest is the kind of daily agenda notifications.
+// Reminders have no kind.
+const NotificationDigest = "digest"
+
+// Notification is a reminder about an upcoming event, or the daily agenda
+// of a user.
+type Notification struct {
+	Kind      string    `json:"kind,omitempty"`
+	EventID   int64     `json:"event_id"`
+	Title     string    `json:"title"`
+	StartTime time.Time `json:"start_time"`
+	UserID    int64     `json:"user_id"`
+	// Text and HTML are the rendered agenda of a digest, and EventIDs the
+	// events in it.
+	Text     string  `json:"text,omitempty"`
+	HTML     string  `json:"html,omitempty"`
+	EventIDs []int64 `json:"event_ids,omitempty"`
+}
+
+// Outbox is implemented by storages that record changes for reliable
+// publishing.
+type Outbox interface {
+	// EnqueueDueNotifications marks events with NotifyAt before the given
+	// time as notified and adds a notification for each to the outbox,
+	// atomically. It returns the number of notifications added.
+	EnqueueDueNotifications(ctx context.Context, before time.Time) (int, error)
+	// FetchOutbox claims up to limit pending messages, oldest first. A
+	// claimed message is not returned again until lease has passed, so a
+	// message whose consumer never acknowledged it is relayed again.
+	FetchOutbox(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error)
+	// DeleteOutbox acknowledges messages their consumer handled.
+	DeleteOutbox(ctx context.Context, ids []int64) error
+	// DeferOutbox claims the message with id until the given time, when
+	// it is relayed again.
+	DeferOutbox(ctx context.Context, id int64, until time.Time) error
+}
+
+// NotificationKey is the idempotency key of the notification of an event.
+func NotificationKey(eventID int64, notifyAt time.Time) string {
+	return fmt.Sprintf("%s:%d:%d", TopicNotification, eventID, notifyAt.Unix())
+}
+
+// DigestKey is the idempotency key of the digest of a user for a day
+// formatted with DigestDayLayout.
+func DigestKey(userID int64, day string) string {
+	return fmt.Sprintf("%s:%s:%d:%s", TopicNotification, NotificationDigest, userID, day)
+}
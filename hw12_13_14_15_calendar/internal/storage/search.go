@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Highlighted terms are wrapped in these markers. The text is HTML-escaped,
+// so the markers are the only tags in it.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SearchResult is an event matching a search, with the matched terms of
+// its title and description highlighted.
+type SearchResult struct {
+	Event                *Event  `json:"event"`
+	Rank                 float64 `json:"rank"`
+	TitleHighlight       string  `json:"title_highlight"`
+	DescriptionHighlight string  `json:"description_highlight,omitempty"`
+}
+
+// Searcher is implemented by storages with full-text search.
+type Searcher interface {
+	// SearchEvents returns up to limit events of userID overlapping
+	// [from, to] that contain a word starting with each of terms, best
+	// matches first.
+	SearchEvents(ctx context.Context, userID int64, terms []string, from, to time.Time, limit int) ([]*SearchResult, error)
+}
+
+// Tokenize splits text into lower-cased words. Any character other than a
+// letter or digit separates words, so emails, URLs and hyphenated words are
+// split into their parts. Postgres indexes the same words.
+func Tokenize(text string) []string {
+	words := wordPattern.FindAllString(text, -1)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
+
+// Highlight HTML-escapes text and marks its words starting with any of
+// terms.
+func Highlight(text string, terms []string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[loc[0]:loc[1]])
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				b.WriteString(html.EscapeString(text[last:loc[0]]))
+				b.WriteString(HighlightStart + html.EscapeString(text[loc[0]:loc[1]]) + HighlightStop)
+				last = loc[1]
+				break
+			}
+		}
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package storage
+
+import "context"
+
+// Snapshotter is implemented by storages whose events and calendars can
+// be exported and restored as a whole, e.g. to move them to another
+// storage.
+type Snapshotter interface {
+	// ScanCalendars returns up to limit calendars with IDs above afterID,
+	// ordered by ID.
+	ScanCalendars(ctx context.Context, afterID int64, limit int) ([]*Calendar, error)
+	// ScanEvents returns up to limit events with IDs above afterID,
+	// ordered by ID.
+	ScanEvents(ctx context.Context, afterID int64, limit int) ([]*Event, error)
+	// RestoreCalendars inserts calendars all or none. With keepIDs they
+	// keep their IDs and a taken ID fails with ErrAlreadyExists;
+	// otherwise new IDs are set.
+	RestoreCalendars(ctx context.Context, calendars []*Calendar, keepIDs bool) error
+	// RestoreEvents inserts events like RestoreCalendars. Their calendars
+	// must exist. Reminders that are already due count as sent, and no
+	// changes are published for restored events.
+	RestoreEvents(ctx context.Context, events []*Event, keepIDs bool) error
+}
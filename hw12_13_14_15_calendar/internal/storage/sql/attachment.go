@@ -0,0 +1,84 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (p *PostgresStorage) CreateAttachment(ctx context.Context, attachment *storage.Attachment) error {
+	const query = `
+    INSERT INTO attachments (event_id, name, mime_type, size, storage_key)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, created_at
+    `
+
+	err := p.db.QueryRowxContext(ctx, query,
+		attachment.EventID, attachment.Name, attachment.MimeType, attachment.Size, attachment.Key,
+	).Scan(&attachment.ID, &attachment.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return storage.EventNotFound(attachment.EventID)
+	}
+	if err != nil {
+		return storage.DatabaseError("create attachment", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) GetAttachment(ctx context.Context, id int64) (*storage.Attachment, error) {
+	const query = `
+    SELECT id, event_id, name, mime_type, size, storage_key, created_at
+    FROM attachments
+    WHERE id = $1
+    `
+
+	attachment := &storage.Attachment{}
+	err := p.db.GetContext(ctx, attachment, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.AttachmentNotFound(id)
+	}
+	if err != nil {
+		return nil, storage.DatabaseError("get attachment", err)
+	}
+
+	return attachment, nil
+}
+
+func (p *PostgresStorage) ListAttachments(ctx context.Context, eventID int64) ([]*storage.Attachment, error) {
+	const query = `
+    SELECT id, event_id, name, mime_type, size, storage_key, created_at
+    FROM attachments
+    WHERE event_id = $1
+    ORDER BY id
+    `
+
+	var attachments []*storage.Attachment
+	if err := p.db.SelectContext(ctx, &attachments, query, eventID); err != nil {
+		return nil, storage.DatabaseError("list attachments", err)
+	}
+
+	return attachments, nil
+}
+
+func (p *PostgresStorage) DeleteAttachment(ctx context.Context, id int64) error {
+	const query = `DELETE FROM attachments WHERE id = $1`
+
+	result, err := p.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return storage.DatabaseError("delete attachment", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return storage.DatabaseError("get affected rows", err)
+	}
+	if rows == 0 {
+		return storage.AttachmentNotFound(id)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package sqlstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// insertChunkSize keeps multi-row inserts below the limit of 65535
+// parameters per statement.
+const insertChunkSize = 1000
+
+func (p *PostgresStorage) ApplyBatch(ctx context.Context, ops []storage.BatchOp) error {
+	err := p.withTx(ctx, func(tx *sqlx.Tx) error {
+		// Runs of creates and deletes are sent as one statement each.
+		for start := 0; start < len(ops); {
+			end := start + 1
+			for end < len(ops) && ops[end].Type == ops[start].Type && ops[start].Type != storage.BatchUpdate {
+				end++
+			}
+
+			var err error
+			switch ops[start].Type {
+			case storage.BatchCreate:
+				err = insertEvents(ctx, tx, ops[start:end])
+			case storage.BatchUpdate:
+				err = updateEvent(ctx, tx, start, ops[start].Event)
+			case storage.BatchDelete:
+				err = deleteEvents(ctx, tx, start, ops[start:end])
+			default:
+				err = &storage.BatchError{
+					Index: start,
+					Err:   storage.ValidationError(fmt.Sprintf("unknown operation %q", ops[start].Type)),
+				}
+			}
+			if err != nil {
+				return err
+			}
+			start = end
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		p.replicas.wrote(op.Event.UserID)
+	}
+	return nil
+}
+
+func insertEvents(ctx context.Context, tx *sqlx.Tx, ops []storage.BatchOp) error {
+	for start := 0; start < len(ops); start += insertChunkSize {
+		chunk := ops[start:min(start+insertChunkSize, len(ops))]
+
+		var query strings.Builder
+		query.WriteString(`INSERT INTO events (title, description, start_time, end_time, user_id, notify_at, conference, calendar_id) VALUES `)
+		args := make([]interface{}, 0, len(chunk)*8)
+		for i, op := range chunk {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			n := len(args)
+			fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, NULLIF($%d, 0))", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+			e := op.Event
+			args = append(args, e.Title, e.Description, e.StartTime, e.EndTime, e.UserID, e.NotifyAt, e.Conference, e.CalendarID)
+		}
+		// Rows of a multi-row VALUES are returned in order.
+		query.WriteString(` RETURNING id`)
+
+		rows, err := tx.QueryContext(ctx, query.String(), args...)
+		if err != nil {
+			return storage.DatabaseError("batch create", err)
+		}
+		for i := 0; rows.Next() && i < len(chunk); i++ {
+			if err := rows.Scan(&chunk[i].Event.ID); err != nil {
+				rows.Close()
+				return storage.DatabaseError("scan id", err)
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return storage.DatabaseError("batch create", err)
+		}
+	}
+
+	return nil
+}
+
+func updateEvent(ctx context.Context, tx *sqlx.Tx, index int, event *storage.Event) error {
+	const query = `
+    UPDATE events SET
+        title = :title,
+        description = :description,
+        start_time = :start_time,
+        end_time = :end_time,
+        conference = :conference,
+        calendar_id = NULLIF(:calendar_id, 0),
+        notified_at = CASE WHEN notify_at IS DISTINCT FROM :notify_at THEN NULL ELSE notified_at END,
+        notify_at = :notify_at
+    WHERE id = :id AND user_id = :user_id
+    `
+
+	result, err := tx.NamedExecContext(ctx, query, event)
+	if err != nil {
+		return storage.DatabaseError("batch update", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return storage.DatabaseError("get affected rows", err)
+	}
+	if rows == 0 {
+		return &storage.BatchError{Index: index, Err: storage.EventNotFound(event.ID)}
+	}
+
+	return nil
+}
+
+func deleteEvents(ctx context.Context, tx *sqlx.Tx, offset int, ops []storage.BatchOp) error {
+	const query = `
+    DELETE FROM events
+    WHERE (id, user_id) IN (SELECT * FROM unnest($1::BIGINT[], $2::BIGINT[]))
+    RETURNING id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
+    `
+
+	ids := make([]int64, len(ops))
+	users := make([]int64, len(ops))
+	for i, op := range ops {
+		ids[i], users[i] = op.Event.ID, op.Event.UserID
+	}
+
+	var deleted []*storage.Event
+	if err := tx.SelectContext(ctx, &deleted, query, pq.Array(ids), pq.Array(users)); err != nil {
+		return storage.DatabaseError("batch delete", err)
+	}
+
+	byID := make(map[int64]*storage.Event, len(deleted))
+	for _, event := range deleted {
+		byID[event.ID] = event
+	}
+
+	// A missing or repeated ID fails the batch.
+	for i, op := range ops {
+		event, ok := byID[op.Event.ID]
+		if !ok {
+			return &storage.BatchError{Index: offset + i, Err: storage.EventNotFound(op.Event.ID)}
+		}
+		delete(byID, op.Event.ID)
+		*op.Event = *event
+	}
+
+	return nil
+}
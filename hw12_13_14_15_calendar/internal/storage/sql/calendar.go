@@ -0,0 +1,153 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func (p *PostgresStorage) CreateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	const query = `
+    INSERT INTO calendars (user_id, name, color, default_reminder, time_zone)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, created_at
+    `
+
+	err := p.db.QueryRowxContext(ctx, query,
+		calendar.UserID, calendar.Name, calendar.Color, calendar.DefaultReminder, calendar.TimeZone).
+		Scan(&calendar.ID, &calendar.CreatedAt)
+	if isUniqueViolation(err) {
+		return storage.CalendarAlreadyExists(calendar.Name)
+	}
+	if err != nil {
+		return storage.DatabaseError("create calendar", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) GetCalendar(ctx context.Context, id int64) (*storage.Calendar, error) {
+	const query = `
+    SELECT id, user_id, name, color, default_reminder, time_zone, created_at
+    FROM calendars
+    WHERE id = $1
+    `
+
+	calendar := &storage.Calendar{}
+	err := p.db.GetContext(ctx, calendar, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.CalendarNotFound(id)
+	}
+	if err != nil {
+		return nil, storage.DatabaseError("get calendar", err)
+	}
+
+	return calendar, nil
+}
+
+func (p *PostgresStorage) ListCalendars(ctx context.Context, userID int64) ([]*storage.Calendar, error) {
+	const query = `
+    SELECT id, user_id, name, color, default_reminder, time_zone, created_at
+    FROM calendars
+    WHERE user_id = $1
+    ORDER BY name
+    `
+
+	var calendars []*storage.Calendar
+	if err := p.db.SelectContext(ctx, &calendars, query, userID); err != nil {
+		return nil, storage.DatabaseError("list calendars", err)
+	}
+
+	return calendars, nil
+}
+
+func (p *PostgresStorage) UpdateCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	const query = `
+    UPDATE calendars SET name = $3, color = $4, default_reminder = $5, time_zone = $6
+    WHERE id = $1 AND user_id = $2
+    RETURNING created_at
+    `
+
+	err := p.db.QueryRowxContext(ctx, query, calendar.ID, calendar.UserID,
+		calendar.Name, calendar.Color, calendar.DefaultReminder, calendar.TimeZone).
+		Scan(&calendar.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.CalendarNotFound(calendar.ID)
+	}
+	if isUniqueViolation(err) {
+		return storage.CalendarAlreadyExists(calendar.Name)
+	}
+	if err != nil {
+		return storage.DatabaseError("update calendar", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) DeleteCalendar(ctx context.Context, id int64) ([]*storage.Event, error) {
+	// Events are deleted one statement ahead of the foreign key cascade
+	// so that they are returned to the caller.
+	const (
+		deleteEvents = `
+    DELETE FROM events WHERE calendar_id = $1
+    RETURNING id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
+    `
+		deleteCalendar = `DELETE FROM calendars WHERE id = $1 RETURNING user_id`
+	)
+
+	var (
+		deleted []*storage.Event
+		userID  int64
+	)
+	err := p.withTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.SelectContext(ctx, &deleted, deleteEvents, id); err != nil {
+			return storage.DatabaseError("delete calendar events", err)
+		}
+		err := tx.GetContext(ctx, &userID, deleteCalendar, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.CalendarNotFound(id)
+		}
+		if err != nil {
+			return storage.DatabaseError("delete calendar", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.replicas.wrote(userID)
+	return deleted, nil
+}
+
+func (p *PostgresStorage) ListEventsByCalendars(ctx context.Context,
+	userID int64,
+	calendarIDs []int64,
+	from, to time.Time,
+) ([]*storage.Event, error) {
+	const query = `
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
+    FROM events
+    WHERE user_id = $1
+    AND start_time <= $3
+    AND end_time >= $2
+    AND calendar_id = ANY($4)
+    ORDER BY start_time ASC
+    `
+
+	var events []*storage.Event
+	err := p.selectEvents(ctx, userID, &events, query, userID, from, to, pq.Array(calendarIDs))
+	if err != nil {
+		return nil, storage.DatabaseError("list by calendars", err)
+	}
+
+	return events, nil
+}
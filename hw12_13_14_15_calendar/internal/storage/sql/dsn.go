@@ -0,0 +1,90 @@
+package sqlstorage
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/jmoiron/sqlx"
+)
+
+const defaultPort = 5432
+
+// DSN builds a postgres URL for conf with every component escaped.
+func DSN(conf config.StorageConfig) string {
+	host, port := conf.Address, ""
+	if h, p, err := net.SplitHostPort(conf.Address); err == nil {
+		host, port = h, p
+	}
+	if conf.Port > 0 {
+		port = strconv.Itoa(conf.Port)
+	}
+	if port == "" {
+		port = strconv.Itoa(defaultPort)
+	}
+
+	query := url.Values{}
+	sslMode := conf.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	query.Set("sslmode", sslMode)
+	if conf.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(conf.ConnectTimeout))
+	}
+	if conf.ApplicationName != "" {
+		query.Set("application_name", conf.ApplicationName)
+	}
+	if conf.SearchPath != "" {
+		query.Set("search_path", conf.SearchPath)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(conf.Username, conf.Password),
+		Host:     net.JoinHostPort(host, port),
+		Path:     "/" + conf.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// connect opens the database, retrying with exponential backoff while
+// the server is not accepting connections yet. It gives up once ctx is
+// done.
+func connect(ctx context.Context,
+	dsn string,
+	retry config.StorageRetryConfig,
+	onRetry func(attempt int, err error),
+) (*sqlx.DB, error) {
+	backoff := time.Duration(retry.InitialBackoff) * time.Second
+	maxBackoff := time.Duration(retry.MaxBackoff) * time.Second
+
+	for attempt := 1; ; attempt++ {
+		db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= retry.Attempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		onRetry(attempt, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func (p *PostgresStorage) GetNotificationPreferences(ctx context.Context,
+	userID int64,
+) (*storage.NotificationPreferences, error) {
+	const query = `
+    SELECT user_id, channel, quiet_start, quiet_end, digest, digest_time, time_zone, updated_at
+    FROM notification_preferences
+    WHERE user_id = $1
+    `
+
+	prefs := &storage.NotificationPreferences{}
+	err := p.db.GetContext(ctx, prefs, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.DefaultNotificationPreferences(userID), nil
+	}
+	if err != nil {
+		return nil, storage.DatabaseError("get notification preferences", err)
+	}
+
+	return prefs, nil
+}
+
+func (p *PostgresStorage) SetNotificationPreferences(ctx context.Context, prefs *storage.NotificationPreferences) error {
+	const query = `
+    INSERT INTO notification_preferences (
+        user_id, channel, quiet_start, quiet_end, digest, digest_time, time_zone
+    ) VALUES (
+        :user_id, :channel, :quiet_start, :quiet_end, :digest, :digest_time, :time_zone
+    )
+    ON CONFLICT (user_id) DO UPDATE SET
+        channel = EXCLUDED.channel,
+        quiet_start = EXCLUDED.quiet_start,
+        quiet_end = EXCLUDED.quiet_end,
+        digest = EXCLUDED.digest,
+        digest_time = EXCLUDED.digest_time,
+        time_zone = EXCLUDED.time_zone,
+        updated_at = CURRENT_TIMESTAMP
+    RETURNING updated_at`
+
+	rows, err := sqlx.NamedQueryContext(ctx, p.db, query, prefs)
+	if err != nil {
+		return storage.DatabaseError("set notification preferences", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&prefs.UpdatedAt); err != nil {
+			return storage.DatabaseError("scan updated_at", err)
+		}
+	}
+
+	return rows.Err()
+}
+
+func (p *PostgresStorage) AddNotificationDelivery(ctx context.Context, delivery *storage.NotificationDelivery) error {
+	const insert = `
+    INSERT INTO notification_deliveries (
+        user_id, event_id, key, channel, status, error
+    ) VALUES (
+        :user_id, :event_id, :key, :channel, :status, :error
+    ) RETURNING id, created_at`
+
+	const prune = `
+    DELETE FROM notification_deliveries
+    WHERE user_id = $1
+    AND id NOT IN (
+        SELECT id FROM notification_deliveries
+        WHERE user_id = $1
+        ORDER BY id DESC
+        LIMIT $2
+    )`
+
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, insert, delivery)
+		if err != nil {
+			return storage.DatabaseError("add notification delivery", err)
+		}
+		defer rows.Close()
+
+		if rows.Next() {
+			if err := rows.Scan(&delivery.ID, &delivery.CreatedAt); err != nil {
+				return storage.DatabaseError("scan id", err)
+			}
+		}
+		rows.Close()
+
+		_, err = tx.ExecContext(ctx, prune, delivery.UserID, storage.NotificationHistorySize)
+		if err != nil {
+			return storage.DatabaseError("prune notification deliveries", err)
+		}
+		return nil
+	})
+}
+
+func (p *PostgresStorage) ListNotificationDeliveries(ctx context.Context,
+	userID, eventID int64,
+	limit int,
+) ([]*storage.NotificationDelivery, error) {
+	const query = `
+    SELECT id, user_id, event_id, key, channel, status, error, created_at
+    FROM notification_deliveries
+    WHERE user_id = $1
+    AND ($2::BIGINT = 0 OR event_id = $2)
+    ORDER BY id DESC
+    LIMIT $3
+    `
+
+	if limit <= 0 || limit > storage.NotificationHistorySize {
+		limit = storage.NotificationHistorySize
+	}
+
+	var deliveries []*storage.NotificationDelivery
+	if err := p.db.SelectContext(ctx, &deliveries, query, userID, eventID, limit); err != nil {
+		return nil, storage.DatabaseError("list notification deliveries", err)
+	}
+
+	return deliveries, nil
+}
+
+func (p *PostgresStorage) NotificationHandled(ctx context.Context, userID int64, key string) (bool, error) {
+	const query = `
+    SELECT EXISTS (
+        SELECT 1 FROM notification_deliveries
+        WHERE user_id = $1 AND key = $2 AND status NOT IN ($3, $4)
+    )
+    `
+
+	var handled bool
+	err := p.db.GetContext(ctx, &handled, query, userID, key, storage.DeliveryFailed, storage.DeliveryDeferred)
+	if err != nil {
+		return false, storage.DatabaseError("check notification delivery", err)
+	}
+
+	return handled, nil
+}
+
+func (p *PostgresStorage) InDigest(ctx context.Context, userID, eventID int64) (bool, error) {
+	const query = `
+    SELECT EXISTS (
+        SELECT 1 FROM notification_digests
+        WHERE user_id = $1 AND $2 = ANY(event_ids)
+    )
+    `
+
+	var found bool
+	if err := p.db.GetContext(ctx, &found, query, userID, eventID); err != nil {
+		return false, storage.DatabaseError("check digest", err)
+	}
+
+	return found, nil
+}
+
+func (p *PostgresStorage) ListDigestPreferences(ctx context.Context) ([]*storage.NotificationPreferences, error) {
+	const query = `
+    SELECT user_id, channel, quiet_start, quiet_end, digest, digest_time, time_zone, updated_at
+    FROM notification_preferences
+    WHERE digest
+    ORDER BY user_id
+    `
+
+	var prefs []*storage.NotificationPreferences
+	if err := p.db.SelectContext(ctx, &prefs, query); err != nil {
+		return nil, storage.DatabaseError("list digest preferences", err)
+	}
+
+	return prefs, nil
+}
+
+func (p *PostgresStorage) EnqueueDigest(ctx context.Context, day string, digest storage.Notification) (bool, error) {
+	// A single statement claims the day and fills the outbox atomically.
+	// Days up to the last claimed one are done.
+	const query = `
+    WITH claimed AS (
+        INSERT INTO notification_digests (user_id, day, event_ids) VALUES ($1, $2, $6)
+        ON CONFLICT (user_id) DO UPDATE
+        SET day = EXCLUDED.day, event_ids = EXCLUDED.event_ids, created_at = CURRENT_TIMESTAMP
+        WHERE notification_digests.day < EXCLUDED.day
+        RETURNING user_id
+    )
+    INSERT INTO outbox (topic, idempotency_key, payload)
+    SELECT $3, $4, $5 FROM claimed
+    ON CONFLICT (idempotency_key) DO NOTHING
+    `
+
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := p.db.ExecContext(ctx, query, digest.UserID, day,
+		storage.TopicNotification, storage.DigestKey(digest.UserID, day), string(payload), pq.Array(digest.EventIDs))
+	if err != nil {
+		return false, storage.DatabaseError("enqueue digest", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, storage.DatabaseError("get affected rows", err)
+	}
+
+	return rows > 0, nil
+}
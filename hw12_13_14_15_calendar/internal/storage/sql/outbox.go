@@ -0,0 +1,94 @@
+package sqlstorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/lib/pq"
+)
+
+func (p *PostgresStorage) EnqueueDueNotifications(ctx context.Context, before time.Time) (int, error) {
+	// A single statement marks the events and fills the outbox atomically.
+	// Events without a reminder store the zero time.
+	const query = `
+    WITH due AS (
+        UPDATE events SET notified_at = CURRENT_TIMESTAMP
+        WHERE notify_at <= $1
+        AND notify_at > $2
+        AND notified_at IS NULL
+        RETURNING id, title, start_time, user_id, notify_at
+    )
+    INSERT INTO outbox (topic, idempotency_key, payload)
+    SELECT $3,
+        $3 || ':' || id || ':' || floor(EXTRACT(EPOCH FROM notify_at))::BIGINT,
+        json_build_object('event_id', id, 'title', title, 'start_time', start_time, 'user_id', user_id)
+    FROM due
+    ORDER BY notify_at
+    ON CONFLICT (idempotency_key) DO NOTHING
+    `
+
+	result, err := p.db.ExecContext(ctx, query, before, time.Time{}, storage.TopicNotification)
+	if err != nil {
+		return 0, storage.DatabaseError("enqueue notifications", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, storage.DatabaseError("get affected rows", err)
+	}
+
+	return int(rows), nil
+}
+
+func (p *PostgresStorage) FetchOutbox(ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*storage.OutboxMessage, error) {
+	// Concurrent relays skip the rows claimed by each other.
+	const query = `
+    UPDATE outbox SET locked_until = $2
+    WHERE id IN (
+        SELECT id FROM outbox
+        WHERE locked_until IS NULL OR locked_until <= $1
+        ORDER BY id ASC
+        LIMIT $3
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING id, topic, idempotency_key, payload, created_at
+    `
+
+	now := time.Now()
+	var messages []*storage.OutboxMessage
+	if err := p.db.SelectContext(ctx, &messages, query, now, now.Add(lease), limit); err != nil {
+		return nil, storage.DatabaseError("fetch outbox", err)
+	}
+
+	// RETURNING keeps no order.
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+	return messages, nil
+}
+
+func (p *PostgresStorage) DeferOutbox(ctx context.Context, id int64, until time.Time) error {
+	const query = `UPDATE outbox SET locked_until = $2 WHERE id = $1`
+
+	if _, err := p.db.ExecContext(ctx, query, id, until); err != nil {
+		return storage.DatabaseError("defer outbox", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) DeleteOutbox(ctx context.Context, ids []int64) error {
+	const query = `DELETE FROM outbox WHERE id = ANY($1)`
+
+	if _, err := p.db.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+		return storage.DatabaseError("delete outbox", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package sqlstorage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+const defaultCheckInterval = 5 * time.Second
+
+type replica struct {
+	db      *sqlx.DB
+	name    string
+	healthy atomic.Bool
+}
+
+// replicaSet routes reads to healthy replicas in round-robin and remembers
+// recent writers, whose reads go to the primary for a while.
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	logger   *logger.Logger
+
+	window  time.Duration
+	mu      sync.Mutex
+	writers map[int64]time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newReplicaSet(conf config.ReplicaConfig, logg *logger.Logger) (*replicaSet, error) {
+	rs := &replicaSet{
+		logger:  logg,
+		window:  time.Duration(conf.ReadYourWrites) * time.Second,
+		writers: make(map[int64]time.Time),
+		done:    make(chan struct{}),
+	}
+
+	for _, dsn := range conf.DSNs {
+		// Open does not connect, so an unavailable replica does not
+		// block startup; the health check finds it.
+		db, err := sqlx.Open("postgres", dsn)
+		if err != nil {
+			rs.close()
+			return nil, err
+		}
+		rs.replicas = append(rs.replicas, &replica{db: db, name: redact(dsn)})
+	}
+
+	interval := defaultCheckInterval
+	if conf.CheckInterval > 0 {
+		interval = time.Duration(conf.CheckInterval) * time.Second
+	}
+
+	// Replicas join the rotation after their first successful check.
+	ctx, cancel := context.WithCancel(context.Background())
+	rs.cancel = cancel
+	go rs.watch(ctx, interval)
+
+	return rs, nil
+}
+
+// pick returns the next healthy replica, or nil if reads of userID must go
+// to the primary.
+func (rs *replicaSet) pick(userID int64) *replica {
+	if rs == nil || len(rs.replicas) == 0 || rs.wroteRecently(userID) {
+		return nil
+	}
+
+	healthy := make([]*replica, 0, len(rs.replicas))
+	for _, r := range rs.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	return healthy[rs.next.Add(1)%uint64(len(healthy))]
+}
+
+// markDown takes a replica out of rotation until the next health check.
+func (rs *replicaSet) markDown(r *replica, err error) {
+	if r.healthy.Swap(false) {
+		rs.logger.Warn(fmt.Sprintf("replica %s is down: %s", r.name, err.Error()))
+	}
+}
+
+// wrote records a write by userID for read-your-writes.
+func (rs *replicaSet) wrote(userID int64) {
+	if rs == nil || rs.window <= 0 {
+		return
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.writers[userID] = time.Now()
+}
+
+func (rs *replicaSet) wroteRecently(userID int64) bool {
+	if rs.window <= 0 {
+		return false
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	last, ok := rs.writers[userID]
+	if ok && time.Since(last) >= rs.window {
+		delete(rs.writers, userID)
+		return false
+	}
+	return ok
+}
+
+func (rs *replicaSet) watch(ctx context.Context, interval time.Duration) {
+	defer close(rs.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	rs.checkAll(ctx, interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rs.checkAll(ctx, interval)
+			rs.forgetWriters()
+		}
+	}
+}
+
+func (rs *replicaSet) checkAll(ctx context.Context, timeout time.Duration) {
+	for _, r := range rs.replicas {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		if err != nil {
+			rs.markDown(r, err)
+			continue
+		}
+		if !r.healthy.Swap(true) {
+			rs.logger.Info(fmt.Sprintf("replica %s is up", r.name))
+		}
+	}
+}
+
+func (rs *replicaSet) forgetWriters() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for userID, last := range rs.writers {
+		if time.Since(last) >= rs.window {
+			delete(rs.writers, userID)
+		}
+	}
+}
+
+func (rs *replicaSet) close() error {
+	if rs.cancel != nil {
+		rs.cancel()
+		<-rs.done
+	}
+
+	var firstErr error
+	for _, r := range rs.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// redact hides the password of a DSN for logging.
+func redact(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "replica"
+	}
+	return u.Redacted()
+}
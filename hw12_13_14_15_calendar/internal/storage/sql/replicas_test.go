@@ -0,0 +1,65 @@
+package sqlstorage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReplicaSet(t *testing.T, n int, window time.Duration) *replicaSet {
+	t.Helper()
+
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	rs := &replicaSet{
+		logger:  logg,
+		window:  window,
+		writers: make(map[int64]time.Time),
+	}
+	for i := 0; i < n; i++ {
+		r := &replica{name: string(rune('a' + i))}
+		r.healthy.Store(true)
+		rs.replicas = append(rs.replicas, r)
+	}
+	return rs
+}
+
+func TestReplicaSet_Pick(t *testing.T) {
+	t.Run("nil set reads from primary", func(t *testing.T) {
+		var rs *replicaSet
+		require.Nil(t, rs.pick(1))
+		rs.wrote(1)
+	})
+
+	t.Run("round robin over healthy replicas", func(t *testing.T) {
+		rs := newTestReplicaSet(t, 3, 0)
+		rs.markDown(rs.replicas[1], errors.New("down"))
+
+		seen := map[string]int{}
+		for i := 0; i < 6; i++ {
+			seen[rs.pick(1).name]++
+		}
+		require.Equal(t, map[string]int{"a": 3, "c": 3}, seen)
+	})
+
+	t.Run("falls back to primary without healthy replicas", func(t *testing.T) {
+		rs := newTestReplicaSet(t, 2, 0)
+		rs.markDown(rs.replicas[0], errors.New("down"))
+		rs.markDown(rs.replicas[1], errors.New("down"))
+		require.Nil(t, rs.pick(1))
+	})
+
+	t.Run("read your writes", func(t *testing.T) {
+		rs := newTestReplicaSet(t, 1, time.Hour)
+		rs.wrote(1)
+		require.Nil(t, rs.pick(1))
+		require.NotNil(t, rs.pick(2))
+
+		rs.writers[1] = time.Now().Add(-2 * time.Hour)
+		require.NotNil(t, rs.pick(1))
+	})
+}
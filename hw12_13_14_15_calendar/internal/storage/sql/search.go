@@ -0,0 +1,70 @@
+package sqlstorage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+type searchRow struct {
+	storage.Event
+	Rank float64 `db:"rank"`
+}
+
+func (p *PostgresStorage) SearchEvents(ctx context.Context,
+	userID int64,
+	terms []string,
+	from, to time.Time,
+	limit int,
+) ([]*storage.SearchResult, error) {
+	// The 'simple' configuration does not stem words, so prefixes match
+	// as typed, like in memory storage. Highlights are made like there too
+	// rather than by ts_headline, which would neither escape the text nor
+	// split words the way the search vector does.
+	const query = `
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id,
+        ts_rank(search_vector, q) AS rank
+    FROM events, to_tsquery('simple', $2) AS q
+    WHERE user_id = $1
+    AND search_vector @@ q
+    AND start_time <= $4
+    AND end_time >= $3
+    ORDER BY rank DESC, start_time DESC
+    LIMIT $5
+    `
+
+	var rows []*searchRow
+	err := selectFromReplica(ctx, p, userID, &rows, query, userID, prefixQuery(terms), from, to, limit)
+	if err != nil {
+		return nil, storage.DatabaseError("search", err)
+	}
+
+	results := make([]*storage.SearchResult, len(rows))
+	for i, row := range rows {
+		event := row.Event
+		results[i] = &storage.SearchResult{
+			Event:          &event,
+			Rank:           row.Rank,
+			TitleHighlight: storage.Highlight(event.Title, terms),
+		}
+		if event.Description != "" {
+			results[i].DescriptionHighlight = storage.Highlight(event.Description, terms)
+		}
+	}
+
+	return results, nil
+}
+
+// prefixQuery builds a tsquery matching words starting with every term.
+// Terms come from storage.Tokenize, so they hold no tsquery operators.
+func prefixQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
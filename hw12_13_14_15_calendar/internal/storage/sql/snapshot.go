@@ -0,0 +1,135 @@
+package sqlstorage
+
+import (
+	"context"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+func (p *PostgresStorage) ScanCalendars(ctx context.Context, afterID int64, limit int) ([]*storage.Calendar, error) {
+	const query = `
+    SELECT id, user_id, name, color, default_reminder, time_zone, created_at
+    FROM calendars
+    WHERE id > $1
+    ORDER BY id
+    LIMIT $2
+    `
+
+	var calendars []*storage.Calendar
+	if err := p.db.SelectContext(ctx, &calendars, query, afterID, limit); err != nil {
+		return nil, storage.DatabaseError("scan calendars", err)
+	}
+
+	return calendars, nil
+}
+
+func (p *PostgresStorage) ScanEvents(ctx context.Context, afterID int64, limit int) ([]*storage.Event, error) {
+	const query = `
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
+    FROM events
+    WHERE id > $1
+    ORDER BY id
+    LIMIT $2
+    `
+
+	var events []*storage.Event
+	if err := p.db.SelectContext(ctx, &events, query, afterID, limit); err != nil {
+		return nil, storage.DatabaseError("scan events", err)
+	}
+
+	return events, nil
+}
+
+func (p *PostgresStorage) RestoreCalendars(ctx context.Context, calendars []*storage.Calendar, keepIDs bool) error {
+	const (
+		insert = `
+    INSERT INTO calendars (user_id, name, color, default_reminder, time_zone, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id
+    `
+		insertWithID = `
+    INSERT INTO calendars (id, user_id, name, color, default_reminder, time_zone, created_at)
+    VALUES ($7, $1, $2, $3, $4, $5, $6)
+    RETURNING id
+    `
+	)
+
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		for _, calendar := range calendars {
+			query := insert
+			args := []interface{}{calendar.UserID, calendar.Name, calendar.Color,
+				calendar.DefaultReminder, calendar.TimeZone, calendar.CreatedAt}
+			if keepIDs {
+				query = insertWithID
+				args = append(args, calendar.ID)
+			}
+
+			err := tx.QueryRowxContext(ctx, query, args...).Scan(&calendar.ID)
+			if isUniqueViolation(err) {
+				return storage.CalendarAlreadyExists(calendar.Name)
+			}
+			if err != nil {
+				return storage.DatabaseError("restore calendar", err)
+			}
+		}
+
+		if keepIDs {
+			return syncSequence(ctx, tx, "calendars")
+		}
+		return nil
+	})
+}
+
+func (p *PostgresStorage) RestoreEvents(ctx context.Context, events []*storage.Event, keepIDs bool) error {
+	// Due reminders are marked as sent, as the exporting storage has
+	// sent them or given up on them.
+	const (
+		columns = `title, description, start_time, end_time, user_id, notify_at, conference, calendar_id, notified_at`
+		values  = `$1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0),
+        CASE WHEN $6 > $9 AND $6 <= CURRENT_TIMESTAMP THEN CURRENT_TIMESTAMP END`
+		insert       = `INSERT INTO events (` + columns + `) VALUES (` + values + `) RETURNING id`
+		insertWithID = `INSERT INTO events (id, ` + columns + `) VALUES ($10, ` + values + `) RETURNING id`
+	)
+
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		for _, e := range events {
+			query := insert
+			args := []interface{}{e.Title, e.Description, e.StartTime, e.EndTime, e.UserID,
+				e.NotifyAt, e.Conference, e.CalendarID, time.Time{}}
+			if keepIDs {
+				query = insertWithID
+				args = append(args, e.ID)
+			}
+
+			err := tx.QueryRowxContext(ctx, query, args...).Scan(&e.ID)
+			if isUniqueViolation(err) {
+				return storage.EventAlreadyExists(e.ID)
+			}
+			if isForeignKeyViolation(err) {
+				return storage.CalendarNotFound(e.CalendarID)
+			}
+			if err != nil {
+				return storage.DatabaseError("restore event", err)
+			}
+		}
+
+		if keepIDs {
+			return syncSequence(ctx, tx, "events")
+		}
+		return nil
+	})
+}
+
+// syncSequence moves the ID sequence of table past the IDs inserted
+// explicitly. table is a constant.
+func syncSequence(ctx context.Context, tx *sqlx.Tx, table string) error {
+	query := `SELECT setval(pg_get_serial_sequence('` + table + `', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM ` + table
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return storage.DatabaseError("sync "+table+" sequence", err)
+	}
+	return nil
+}
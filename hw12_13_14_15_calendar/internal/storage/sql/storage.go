@@ -10,6 +10,8 @@ import (
 	//nolint:depguard
 	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
 	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	//nolint:depguard
 	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
 	//nolint:depguard
 	migrator "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/migrations"
@@ -17,32 +19,27 @@ import (
 )
 
 type PostgresStorage struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	replicas *replicaSet
 }
 
-func New(conf config.StorageConfig) (storage.Storage, error) {
-	connStr := fmt.Sprintf(
-		"postgresql://%s:%s@%s/%s?sslmode=disable",
-		conf.Username,
-		conf.Password,
-		conf.Address,
-		conf.Database,
-	)
+func New(ctx context.Context, conf config.StorageConfig, logg *logger.Logger) (storage.Storage, error) {
+	connStr := DSN(conf)
 
-	mg, err := migrator.New(connStr)
+	// Connect first so migrations run only once the server is up.
+	db, err := connect(ctx, connStr, conf.Retry, func(attempt int, err error) {
+		logg.Warn(fmt.Sprintf("postgres is not available (attempt %d/%d): %s",
+			attempt, conf.Retry.Attempts, err.Error()))
+	})
 	if err != nil {
-		return nil, storage.DatabaseError("migration", err)
-	}
-	defer mg.Close()
-
-	// Run migrations
-	if err := mg.Up(); err != nil {
-		return nil, storage.DatabaseError("migration", err)
+		return nil, storage.DatabaseError("connect", err)
 	}
 
-	db, err := sqlx.Connect("postgres", connStr)
-	if err != nil {
-		return nil, storage.DatabaseError("connect", err)
+	if conf.AutoMigrate {
+		if err := migrate(connStr); err != nil {
+			db.Close()
+			return nil, err
+		}
 	}
 
 	if conf.Pool.MaxOpenConns > 0 {
@@ -62,18 +59,46 @@ func New(conf config.StorageConfig) (storage.Storage, error) {
 		db.SetConnMaxLifetime(5 * time.Minute) // default
 	}
 
-	return &PostgresStorage{db: db}, nil
+	var replicas *replicaSet
+	if len(conf.Replicas.DSNs) > 0 {
+		replicas, err = newReplicaSet(conf.Replicas, logg)
+		if err != nil {
+			db.Close()
+			return nil, storage.DatabaseError("open replica", err)
+		}
+	}
+
+	return &PostgresStorage{db: db, replicas: replicas}, nil
+}
+
+func migrate(connStr string) error {
+	mg, err := migrator.New(connStr)
+	if err != nil {
+		return storage.DatabaseError("migration", err)
+	}
+	defer mg.Close()
+
+	// Run migrations
+	if err := mg.Up(); err != nil {
+		return storage.DatabaseError("migration", err)
+	}
+
+	return nil
 }
 
 func (p *PostgresStorage) CreateEvent(ctx context.Context, event *storage.Event) error {
 	const query = `
     INSERT INTO events (
-        title, description, start_time, end_time, user_id, notify_at
+        title, description, start_time, end_time, user_id, notify_at, conference, calendar_id
     ) VALUES (
-        :title, :description, :start_time, :end_time, :user_id, :notify_at
+        :title, :description, :start_time, :end_time, :user_id, :notify_at, :conference,
+        NULLIF(:calendar_id, 0)
     ) RETURNING id`
 
 	rows, err := p.db.NamedQueryContext(ctx, query, event)
+	if isForeignKeyViolation(err) {
+		return storage.CalendarNotFound(event.CalendarID)
+	}
 	if err != nil {
 		return storage.DatabaseError("create", err)
 	}
@@ -85,21 +110,29 @@ func (p *PostgresStorage) CreateEvent(ctx context.Context, event *storage.Event)
 		}
 	}
 
+	p.replicas.wrote(event.UserID)
 	return nil
 }
 
 func (p *PostgresStorage) UpdateEvent(ctx context.Context, event *storage.Event) error {
+	// A changed reminder time needs a new notification.
 	const query = `
     UPDATE events SET 
         title = :title,
         description = :description,
         start_time = :start_time,
         end_time = :end_time,
+        conference = :conference,
+        calendar_id = NULLIF(:calendar_id, 0),
+        notified_at = CASE WHEN notify_at IS DISTINCT FROM :notify_at THEN NULL ELSE notified_at END,
         notify_at = :notify_at
     WHERE id = :id AND user_id = :user_id
     `
 
 	result, err := p.db.NamedExecContext(ctx, query, event)
+	if isForeignKeyViolation(err) {
+		return storage.CalendarNotFound(event.CalendarID)
+	}
 	if err != nil {
 		return storage.DatabaseError("update", err)
 	}
@@ -113,32 +146,34 @@ func (p *PostgresStorage) UpdateEvent(ctx context.Context, event *storage.Event)
 		return storage.EventNotFound(event.ID)
 	}
 
+	p.replicas.wrote(event.UserID)
 	return nil
 }
 
 func (p *PostgresStorage) DeleteEvent(ctx context.Context, id int64) error {
-	const query = `DELETE FROM events WHERE id = $1`
+	const query = `
+    DELETE FROM events WHERE id = $1
+    RETURNING id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
+    `
 
-	result, err := p.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return storage.DatabaseError("delete", err)
+	event := &storage.Event{}
+	err := p.db.GetContext(ctx, event, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.EventNotFound(id)
 	}
-
-	rows, err := result.RowsAffected()
 	if err != nil {
-		return storage.DatabaseError("get affected rows", err)
-	}
-
-	if rows == 0 {
-		return storage.EventNotFound(id)
+		return storage.DatabaseError("delete", err)
 	}
 
+	p.replicas.wrote(event.UserID)
 	return nil
 }
 
 func (p *PostgresStorage) GetEvent(ctx context.Context, id int64) (*storage.Event, error) {
 	const query = `
-    SELECT id, title, description, start_time, end_time, user_id, notify_at
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
     FROM events
     WHERE id = $1
     `
@@ -157,7 +192,8 @@ func (p *PostgresStorage) GetEvent(ctx context.Context, id int64) (*storage.Even
 
 func (p *PostgresStorage) ListEvents(ctx context.Context, userID int64, from, to time.Time) ([]*storage.Event, error) {
 	const query = `
-    SELECT id, title, description, start_time, end_time, user_id, notify_at
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
     FROM events
     WHERE user_id = $1
     AND start_time <= $3
@@ -166,7 +202,7 @@ func (p *PostgresStorage) ListEvents(ctx context.Context, userID int64, from, to
     `
 
 	var events []*storage.Event
-	err := p.db.SelectContext(ctx, &events, query, userID, from, to)
+	err := p.selectEvents(ctx, userID, &events, query, userID, from, to)
 	if err != nil {
 		return nil, storage.DatabaseError("list", err)
 	}
@@ -176,7 +212,8 @@ func (p *PostgresStorage) ListEvents(ctx context.Context, userID int64, from, to
 
 func (p *PostgresStorage) ListUpcomingEvents(ctx context.Context, userID int64, limit int) ([]*storage.Event, error) {
 	const query = `
-    SELECT id, title, description, start_time, end_time, user_id, notify_at
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
     FROM events
     WHERE user_id = $1
     AND start_time > CURRENT_TIMESTAMP
@@ -185,7 +222,7 @@ func (p *PostgresStorage) ListUpcomingEvents(ctx context.Context, userID int64,
     `
 
 	var events []*storage.Event
-	err := p.db.SelectContext(ctx, &events, query, userID, limit)
+	err := p.selectEvents(ctx, userID, &events, query, userID, limit)
 	if err != nil {
 		return nil, storage.DatabaseError("list upcoming", err)
 	}
@@ -196,15 +233,18 @@ func (p *PostgresStorage) ListUpcomingEvents(ctx context.Context, userID int64,
 func (p *PostgresStorage) ListEventsNeedingNotification(ctx context.Context,
 	before time.Time) ([]*storage.Event, error) {
 	const query = `
-    SELECT id, title, description, start_time, end_time, user_id, notify_at
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
     FROM events
     WHERE notify_at <= $1
-    AND notify_at IS NOT NULL
+    AND notify_at > $2
+    AND notified_at IS NULL
     ORDER BY notify_at ASC
     `
 
+	// Events without a reminder store the zero time.
 	var events []*storage.Event
-	err := p.db.SelectContext(ctx, &events, query, before)
+	err := p.db.SelectContext(ctx, &events, query, before, time.Time{})
 	if err != nil {
 		return nil, storage.DatabaseError("list notifications", err)
 	}
@@ -216,7 +256,8 @@ func (p *PostgresStorage) GetEventsByTimeRange(ctx context.Context,
 	userID int64,
 	start, end time.Time) ([]*storage.Event, error) {
 	const query = `
-    SELECT id, title, description, start_time, end_time, user_id, notify_at
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
     FROM events
     WHERE user_id = $1
     AND (
@@ -228,7 +269,7 @@ func (p *PostgresStorage) GetEventsByTimeRange(ctx context.Context,
     `
 
 	var events []*storage.Event
-	err := p.db.SelectContext(ctx, &events, query, userID, start, end)
+	err := p.selectEvents(ctx, userID, &events, query, userID, start, end)
 	if err != nil {
 		return nil, storage.DatabaseError("get by time range", err)
 	}
@@ -236,7 +277,36 @@ func (p *PostgresStorage) GetEventsByTimeRange(ctx context.Context,
 	return events, nil
 }
 
+// selectEvents runs a read-only query of userID's events on a healthy replica,
+// falling back to the primary.
+func (p *PostgresStorage) selectEvents(ctx context.Context, userID int64,
+	dest *[]*storage.Event, query string, args ...interface{},
+) error {
+	return selectFromReplica(ctx, p, userID, dest, query, args...)
+}
+
+// selectFromReplica is selectEvents for rows of any type.
+func selectFromReplica[T any](ctx context.Context, p *PostgresStorage, userID int64,
+	dest *[]T, query string, args ...interface{},
+) error {
+	if r := p.replicas.pick(userID); r != nil {
+		err := r.db.SelectContext(ctx, dest, query, args...)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		p.replicas.markDown(r, err)
+		*dest = nil
+	}
+
+	return p.db.SelectContext(ctx, dest, query, args...)
+}
+
 func (p *PostgresStorage) Close() error {
+	if p.replicas != nil {
+		if err := p.replicas.close(); err != nil {
+			return storage.DatabaseError("close replicas", err)
+		}
+	}
 	if err := p.db.Close(); err != nil {
 		return storage.DatabaseError("close", err)
 	}
@@ -249,3 +319,21 @@ func (p *PostgresStorage) Ping() error {
 	}
 	return nil
 }
+
+func (p *PostgresStorage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	const query = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	var (
+		version uint
+		dirty   bool
+	)
+	err := p.db.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, storage.DatabaseError("migration version", err)
+	}
+
+	return version, dirty, nil
+}
@@ -0,0 +1,223 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func (p *PostgresStorage) CreateTag(ctx context.Context, tag *storage.Tag) error {
+	const query = `
+    INSERT INTO tags (user_id, name, kind, color)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id, created_at
+    `
+
+	err := p.db.QueryRowxContext(ctx, query, tag.UserID, tag.Name, tag.Kind, tag.Color).
+		Scan(&tag.ID, &tag.CreatedAt)
+	if isUniqueViolation(err) {
+		return storage.TagAlreadyExists(tag.Kind, tag.Name)
+	}
+	if err != nil {
+		return storage.DatabaseError("create tag", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) GetTag(ctx context.Context, id int64) (*storage.Tag, error) {
+	const query = `
+    SELECT id, user_id, name, kind, color, created_at
+    FROM tags
+    WHERE id = $1
+    `
+
+	tag := &storage.Tag{}
+	err := p.db.GetContext(ctx, tag, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.TagNotFound(id)
+	}
+	if err != nil {
+		return nil, storage.DatabaseError("get tag", err)
+	}
+
+	return tag, nil
+}
+
+func (p *PostgresStorage) ListTags(ctx context.Context, userID int64) ([]*storage.Tag, error) {
+	const query = `
+    SELECT id, user_id, name, kind, color, created_at
+    FROM tags
+    WHERE user_id = $1
+    ORDER BY kind, name
+    `
+
+	var tags []*storage.Tag
+	if err := p.db.SelectContext(ctx, &tags, query, userID); err != nil {
+		return nil, storage.DatabaseError("list tags", err)
+	}
+
+	return tags, nil
+}
+
+func (p *PostgresStorage) UpdateTag(ctx context.Context, tag *storage.Tag) error {
+	const query = `
+    UPDATE tags SET name = $3, color = $4
+    WHERE id = $1 AND user_id = $2
+    RETURNING kind, created_at
+    `
+
+	err := p.db.QueryRowxContext(ctx, query, tag.ID, tag.UserID, tag.Name, tag.Color).
+		Scan(&tag.Kind, &tag.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.TagNotFound(tag.ID)
+	}
+	if isUniqueViolation(err) {
+		// The failed update returned nothing, so the kind of the
+		// conflict is read from the row.
+		existing, getErr := p.GetTag(ctx, tag.ID)
+		if getErr != nil {
+			return getErr
+		}
+		return storage.TagAlreadyExists(existing.Kind, tag.Name)
+	}
+	if err != nil {
+		return storage.DatabaseError("update tag", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) DeleteTag(ctx context.Context, id int64) error {
+	// Links to events are removed by the foreign key cascade.
+	const query = `DELETE FROM tags WHERE id = $1 RETURNING user_id`
+
+	var userID int64
+	err := p.db.GetContext(ctx, &userID, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.TagNotFound(id)
+	}
+	if err != nil {
+		return storage.DatabaseError("delete tag", err)
+	}
+
+	p.replicas.wrote(userID)
+	return nil
+}
+
+func (p *PostgresStorage) SetEventTags(ctx context.Context, eventID int64, tagIDs []int64) error {
+	// The event is locked so that a foreign key violation can only come
+	// from a tag deleted concurrently.
+	const (
+		lock   = `SELECT user_id FROM events WHERE id = $1 FOR SHARE`
+		unlink = `DELETE FROM event_tags WHERE event_id = $1`
+		link   = `INSERT INTO event_tags (event_id, tag_id) SELECT $1, unnest($2::BIGINT[])`
+	)
+
+	var userID int64
+	err := p.withTx(ctx, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx, &userID, lock, eventID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.EventNotFound(eventID)
+		}
+		if err != nil {
+			return storage.DatabaseError("lock event", err)
+		}
+		if _, err := tx.ExecContext(ctx, unlink, eventID); err != nil {
+			return storage.DatabaseError("unlink tags", err)
+		}
+		if len(tagIDs) == 0 {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, link, eventID, pq.Array(tagIDs))
+		if isForeignKeyViolation(err) {
+			return p.missingTag(ctx, tagIDs)
+		}
+		if err != nil {
+			return storage.DatabaseError("link tags", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	p.replicas.wrote(userID)
+	return nil
+}
+
+// missingTag reports the first of tagIDs that does not exist.
+func (p *PostgresStorage) missingTag(ctx context.Context, tagIDs []int64) error {
+	const query = `
+    SELECT t.id FROM unnest($1::BIGINT[]) AS t(id)
+    WHERE NOT EXISTS (SELECT 1 FROM tags WHERE tags.id = t.id)
+    LIMIT 1
+    `
+
+	var id int64
+	err := p.db.GetContext(ctx, &id, query, pq.Array(tagIDs))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.DatabaseError("link tags", errors.New("foreign key violation with every tag present"))
+	}
+	if err != nil {
+		return storage.DatabaseError("find missing tag", err)
+	}
+
+	return storage.TagNotFound(id)
+}
+
+func (p *PostgresStorage) GetEventTags(ctx context.Context, eventID int64) ([]*storage.Tag, error) {
+	const query = `
+    SELECT t.id, t.user_id, t.name, t.kind, t.color, t.created_at
+    FROM tags t
+    JOIN event_tags et ON et.tag_id = t.id
+    WHERE et.event_id = $1
+    ORDER BY t.kind, t.name
+    `
+
+	var tags []*storage.Tag
+	if err := p.db.SelectContext(ctx, &tags, query, eventID); err != nil {
+		return nil, storage.DatabaseError("get event tags", err)
+	}
+
+	return tags, nil
+}
+
+func (p *PostgresStorage) ListEventsByTags(ctx context.Context,
+	userID int64,
+	tagIDs []int64,
+	from, to time.Time,
+) ([]*storage.Event, error) {
+	// tagIDs hold no duplicates, so an event with every tag has as many
+	// links among them.
+	const query = `
+    SELECT id, title, description, start_time, end_time, user_id, notify_at, conference,
+        COALESCE(calendar_id, 0) AS calendar_id
+    FROM events
+    WHERE user_id = $1
+    AND start_time <= $3
+    AND end_time >= $2
+    AND id IN (
+        SELECT event_id FROM event_tags
+        WHERE tag_id = ANY($4)
+        GROUP BY event_id
+        HAVING count(*) = $5
+    )
+    ORDER BY start_time ASC
+    `
+
+	var events []*storage.Event
+	err := p.selectEvents(ctx, userID, &events, query, userID, from, to, pq.Array(tagIDs), len(tagIDs))
+	if err != nil {
+		return nil, storage.DatabaseError("list by tags", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,149 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+func (p *PostgresStorage) CreateWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	const query = `
+    INSERT INTO webhooks (user_id, url, secret)
+    VALUES ($1, $2, $3)
+    RETURNING id, created_at
+    `
+
+	err := p.db.QueryRowxContext(ctx, query, webhook.UserID, webhook.URL, webhook.Secret).
+		Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return storage.DatabaseError("create webhook", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) GetWebhook(ctx context.Context, id int64) (*storage.Webhook, error) {
+	const query = `
+    SELECT id, user_id, url, secret, created_at
+    FROM webhooks
+    WHERE id = $1
+    `
+
+	webhook := &storage.Webhook{}
+	err := p.db.GetContext(ctx, webhook, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.WebhookNotFound(id)
+	}
+	if err != nil {
+		return nil, storage.DatabaseError("get webhook", err)
+	}
+
+	return webhook, nil
+}
+
+func (p *PostgresStorage) ListWebhooks(ctx context.Context, userID int64) ([]*storage.Webhook, error) {
+	const query = `
+    SELECT id, user_id, url, secret, created_at
+    FROM webhooks
+    WHERE user_id = $1
+    ORDER BY id ASC
+    `
+
+	var webhooks []*storage.Webhook
+	if err := p.db.SelectContext(ctx, &webhooks, query, userID); err != nil {
+		return nil, storage.DatabaseError("list webhooks", err)
+	}
+
+	return webhooks, nil
+}
+
+func (p *PostgresStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	// Deliveries are removed by the foreign key cascade.
+	const query = `DELETE FROM webhooks WHERE id = $1`
+
+	result, err := p.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return storage.DatabaseError("delete webhook", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return storage.DatabaseError("get affected rows", err)
+	}
+	if rows == 0 {
+		return storage.WebhookNotFound(id)
+	}
+
+	return nil
+}
+
+func (p *PostgresStorage) AddWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	const insert = `
+    INSERT INTO webhook_deliveries (
+        webhook_id, delivery_id, event_type, attempt, status_code, error, success
+    ) VALUES (
+        :webhook_id, :delivery_id, :event_type, :attempt, :status_code, :error, :success
+    ) RETURNING id, created_at`
+
+	const prune = `
+    DELETE FROM webhook_deliveries
+    WHERE webhook_id = $1
+    AND id NOT IN (
+        SELECT id FROM webhook_deliveries
+        WHERE webhook_id = $1
+        ORDER BY id DESC
+        LIMIT $2
+    )`
+
+	return p.withTx(ctx, func(tx *sqlx.Tx) error {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, insert, delivery)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return storage.WebhookNotFound(delivery.WebhookID)
+			}
+			return storage.DatabaseError("add webhook delivery", err)
+		}
+		defer rows.Close()
+
+		if rows.Next() {
+			if err := rows.Scan(&delivery.ID, &delivery.CreatedAt); err != nil {
+				return storage.DatabaseError("scan id", err)
+			}
+		}
+		rows.Close()
+
+		_, err = tx.ExecContext(ctx, prune, delivery.WebhookID, storage.WebhookDeliveryLogSize)
+		if err != nil {
+			return storage.DatabaseError("prune webhook deliveries", err)
+		}
+		return nil
+	})
+}
+
+func (p *PostgresStorage) ListWebhookDeliveries(ctx context.Context,
+	webhookID int64,
+	limit int,
+) ([]*storage.WebhookDelivery, error) {
+	const query = `
+    SELECT id, webhook_id, delivery_id, event_type, attempt, status_code, error, success, created_at
+    FROM webhook_deliveries
+    WHERE webhook_id = $1
+    ORDER BY id DESC
+    LIMIT $2
+    `
+
+	if limit <= 0 {
+		limit = storage.WebhookDeliveryLogSize
+	}
+
+	var deliveries []*storage.WebhookDelivery
+	if err := p.db.SelectContext(ctx, &deliveries, query, webhookID, limit); err != nil {
+		return nil, storage.DatabaseError("list webhook deliveries", err)
+	}
+
+	return deliveries, nil
+}
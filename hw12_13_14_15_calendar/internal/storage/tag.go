@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Tag kinds. An event has any number of tags but at most one category.
+const (
+	TagKindTag      = "tag"
+	TagKindCategory = "category"
+)
+
+// Tag is a user-defined label of events.
+type Tag struct {
+	ID     int64  `json:"id" db:"id"`
+	UserID int64  `json:"user_id" db:"user_id"`
+	Name   string `json:"name" db:"name"`
+	Kind   string `json:"kind" db:"kind"`
+	// Color is a hex RGB colour such as "#1e90ff".
+	Color     string    `json:"color" db:"color"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// TagStore is implemented by storages that keep tags of events.
+type TagStore interface {
+	// CreateTag fails with ErrAlreadyExists if the user has a tag of the
+	// same kind and name.
+	CreateTag(ctx context.Context, tag *Tag) error
+	GetTag(ctx context.Context, id int64) (*Tag, error)
+	ListTags(ctx context.Context, userID int64) ([]*Tag, error)
+	// UpdateTag renames or recolours a tag of Tag.UserID.
+	UpdateTag(ctx context.Context, tag *Tag) error
+	// DeleteTag removes the tag from all events.
+	DeleteTag(ctx context.Context, id int64) error
+	// SetEventTags replaces the tags of an event.
+	SetEventTags(ctx context.Context, eventID int64, tagIDs []int64) error
+	GetEventTags(ctx context.Context, eventID int64) ([]*Tag, error)
+	// ListEventsByTags returns events of userID overlapping [from, to] that
+	// have all of tagIDs, ordered by start time.
+	ListEventsByTags(ctx context.Context, userID int64, tagIDs []int64, from, to time.Time) ([]*Event, error)
+}
+
+func TagNotFound(id int64) error {
+	return fmt.Errorf("tag %d: %w", id, ErrNotFound)
+}
+
+func TagAlreadyExists(kind, name string) error {
+	return fmt.Errorf("%s %q: %w", kind, name, ErrAlreadyExists)
+}
@@ -0,0 +1,107 @@
+package tracingstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GetNotificationPreferences and the other notification methods use the
+// decorated storage, which must be a storage.NotificationStore.
+func (s *TracedStorage) GetNotificationPreferences(ctx context.Context,
+	userID int64,
+) (_ *storage.NotificationPreferences, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationPreferences", attribute.Int64("user.id", userID))
+	defer func() { tracing.End(span, err) }()
+
+	inner, err := s.notificationStore()
+	if err != nil {
+		return nil, err
+	}
+	return inner.GetNotificationPreferences(ctx, userID)
+}
+
+func (s *TracedStorage) SetNotificationPreferences(ctx context.Context,
+	prefs *storage.NotificationPreferences,
+) (err error) {
+	ctx, span := startSpan(ctx, "SetNotificationPreferences", attribute.Int64("user.id", prefs.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	inner, err := s.notificationStore()
+	if err != nil {
+		return err
+	}
+	return inner.SetNotificationPreferences(ctx, prefs)
+}
+
+func (s *TracedStorage) AddNotificationDelivery(ctx context.Context,
+	delivery *storage.NotificationDelivery,
+) (err error) {
+	ctx, span := startSpan(ctx, "AddNotificationDelivery",
+		attribute.Int64("user.id", delivery.UserID), attribute.String("delivery.status", delivery.Status))
+	defer func() { tracing.End(span, err) }()
+
+	inner, err := s.notificationStore()
+	if err != nil {
+		return err
+	}
+	return inner.AddNotificationDelivery(ctx, delivery)
+}
+
+func (s *TracedStorage) ListNotificationDeliveries(ctx context.Context,
+	userID, eventID int64, limit int,
+) (deliveries []*storage.NotificationDelivery, err error) {
+	ctx, span := startSpan(ctx, "ListNotificationDeliveries", attribute.Int64("user.id", userID))
+	defer func() {
+		span.SetAttributes(attribute.Int("deliveries.count", len(deliveries)))
+		tracing.End(span, err)
+	}()
+
+	inner, err := s.notificationStore()
+	if err != nil {
+		return nil, err
+	}
+	return inner.ListNotificationDeliveries(ctx, userID, eventID, limit)
+}
+
+func (s *TracedStorage) NotificationHandled(ctx context.Context, userID int64, key string) (handled bool, err error) {
+	ctx, span := startSpan(ctx, "NotificationHandled", attribute.Int64("user.id", userID))
+	defer func() {
+		span.SetAttributes(attribute.Bool("notification.handled", handled))
+		tracing.End(span, err)
+	}()
+
+	inner, err := s.notificationStore()
+	if err != nil {
+		return false, err
+	}
+	return inner.NotificationHandled(ctx, userID, key)
+}
+
+func (s *TracedStorage) InDigest(ctx context.Context, userID, eventID int64) (found bool, err error) {
+	ctx, span := startSpan(ctx, "InDigest", attribute.Int64("user.id", userID), attribute.Int64("event.id", eventID))
+	defer func() {
+		span.SetAttributes(attribute.Bool("digest.found", found))
+		tracing.End(span, err)
+	}()
+
+	inner, err := s.notificationStore()
+	if err != nil {
+		return false, err
+	}
+	return inner.InDigest(ctx, userID, eventID)
+}
+
+func (s *TracedStorage) notificationStore() (storage.NotificationStore, error) {
+	inner, ok := storage.As[storage.NotificationStore](s.Storage)
+	if !ok {
+		return nil, fmt.Errorf("notifications: %w", errors.ErrUnsupported)
+	}
+	return inner, nil
+}
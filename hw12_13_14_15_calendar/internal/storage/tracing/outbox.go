@@ -0,0 +1,117 @@
+package tracingstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EnqueueDueNotifications and the other outbox methods use the decorated
+// storage, which must be a storage.Outbox.
+func (s *TracedStorage) EnqueueDueNotifications(ctx context.Context, before time.Time) (n int, err error) {
+	ctx, span := startSpan(ctx, "EnqueueDueNotifications")
+	defer func() {
+		span.SetAttributes(attribute.Int("notifications.count", n))
+		tracing.End(span, err)
+	}()
+
+	inner, err := s.outbox()
+	if err != nil {
+		return 0, err
+	}
+	return inner.EnqueueDueNotifications(ctx, before)
+}
+
+func (s *TracedStorage) FetchOutbox(ctx context.Context,
+	limit int, lease time.Duration,
+) (messages []*storage.OutboxMessage, err error) {
+	ctx, span := startSpan(ctx, "FetchOutbox", attribute.Int("outbox.limit", limit))
+	defer func() {
+		span.SetAttributes(attribute.Int("messages.count", len(messages)))
+		tracing.End(span, err)
+	}()
+
+	inner, err := s.outbox()
+	if err != nil {
+		return nil, err
+	}
+	return inner.FetchOutbox(ctx, limit, lease)
+}
+
+func (s *TracedStorage) DeleteOutbox(ctx context.Context, ids []int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteOutbox", attribute.Int("messages.count", len(ids)))
+	defer func() { tracing.End(span, err) }()
+
+	inner, err := s.outbox()
+	if err != nil {
+		return err
+	}
+	return inner.DeleteOutbox(ctx, ids)
+}
+
+func (s *TracedStorage) DeferOutbox(ctx context.Context, id int64, until time.Time) (err error) {
+	ctx, span := startSpan(ctx, "DeferOutbox", attribute.Int64("outbox.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	inner, err := s.outbox()
+	if err != nil {
+		return err
+	}
+	return inner.DeferOutbox(ctx, id, until)
+}
+
+// ListDigestPreferences and EnqueueDigest use the decorated storage,
+// which must be a storage.DigestOutbox.
+func (s *TracedStorage) ListDigestPreferences(ctx context.Context,
+) (prefs []*storage.NotificationPreferences, err error) {
+	ctx, span := startSpan(ctx, "ListDigestPreferences")
+	defer func() {
+		span.SetAttributes(attribute.Int("users.count", len(prefs)))
+		tracing.End(span, err)
+	}()
+
+	inner, err := s.digestOutbox()
+	if err != nil {
+		return nil, err
+	}
+	return inner.ListDigestPreferences(ctx)
+}
+
+func (s *TracedStorage) EnqueueDigest(ctx context.Context,
+	day string, digest storage.Notification,
+) (added bool, err error) {
+	ctx, span := startSpan(ctx, "EnqueueDigest", attribute.Int64("user.id", digest.UserID))
+	defer func() {
+		span.SetAttributes(attribute.Bool("digest.added", added))
+		tracing.End(span, err)
+	}()
+
+	inner, err := s.digestOutbox()
+	if err != nil {
+		return false, err
+	}
+	return inner.EnqueueDigest(ctx, day, digest)
+}
+
+func (s *TracedStorage) outbox() (storage.Outbox, error) {
+	inner, ok := storage.As[storage.Outbox](s.Storage)
+	if !ok {
+		return nil, fmt.Errorf("outbox: %w", errors.ErrUnsupported)
+	}
+	return inner, nil
+}
+
+func (s *TracedStorage) digestOutbox() (storage.DigestOutbox, error) {
+	inner, ok := storage.As[storage.DigestOutbox](s.Storage)
+	if !ok {
+		return nil, fmt.Errorf("digests: %w", errors.ErrUnsupported)
+	}
+	return inner, nil
+}
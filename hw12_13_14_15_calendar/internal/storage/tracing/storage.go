@@ -0,0 +1,113 @@
+package tracingstorage
+
+import (
+	"context"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("storage")
+
+// TracedStorage records a span for every event call of another storage
+// and for the calls of storage.Outbox, storage.DigestOutbox and
+// storage.NotificationStore, which fail with errors.ErrUnsupported if the
+// decorated storage lacks them. Ping has no context and is not traced.
+// Other optional interfaces found with storage.As reach the decorated
+// storage directly and are not traced.
+type TracedStorage struct {
+	storage.Storage
+}
+
+func New(inner storage.Storage) *TracedStorage {
+	return &TracedStorage{Storage: inner}
+}
+
+// Unwrap returns the decorated storage.
+func (s *TracedStorage) Unwrap() storage.Storage {
+	return s.Storage
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "storage."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+func (s *TracedStorage) CreateEvent(ctx context.Context, event *storage.Event) (err error) {
+	ctx, span := startSpan(ctx, "CreateEvent", attribute.Int64("user.id", event.UserID))
+	defer func() {
+		span.SetAttributes(attribute.Int64("event.id", event.ID))
+		tracing.End(span, err)
+	}()
+
+	return s.Storage.CreateEvent(ctx, event)
+}
+
+func (s *TracedStorage) UpdateEvent(ctx context.Context, event *storage.Event) (err error) {
+	ctx, span := startSpan(ctx, "UpdateEvent",
+		attribute.Int64("user.id", event.UserID), attribute.Int64("event.id", event.ID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Storage.UpdateEvent(ctx, event)
+}
+
+func (s *TracedStorage) DeleteEvent(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteEvent", attribute.Int64("event.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Storage.DeleteEvent(ctx, id)
+}
+
+func (s *TracedStorage) GetEvent(ctx context.Context, id int64) (_ *storage.Event, err error) {
+	ctx, span := startSpan(ctx, "GetEvent", attribute.Int64("event.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Storage.GetEvent(ctx, id)
+}
+
+func (s *TracedStorage) ListEvents(ctx context.Context,
+	userID int64, from, to time.Time,
+) (events []*storage.Event, err error) {
+	ctx, span := startSpan(ctx, "ListEvents", attribute.Int64("user.id", userID))
+	defer func() { endList(span, len(events), err) }()
+
+	return s.Storage.ListEvents(ctx, userID, from, to)
+}
+
+func (s *TracedStorage) ListUpcomingEvents(ctx context.Context,
+	userID int64, limit int,
+) (events []*storage.Event, err error) {
+	ctx, span := startSpan(ctx, "ListUpcomingEvents", attribute.Int64("user.id", userID))
+	defer func() { endList(span, len(events), err) }()
+
+	return s.Storage.ListUpcomingEvents(ctx, userID, limit)
+}
+
+func (s *TracedStorage) ListEventsNeedingNotification(ctx context.Context,
+	before time.Time,
+) (events []*storage.Event, err error) {
+	ctx, span := startSpan(ctx, "ListEventsNeedingNotification")
+	defer func() { endList(span, len(events), err) }()
+
+	return s.Storage.ListEventsNeedingNotification(ctx, before)
+}
+
+func (s *TracedStorage) GetEventsByTimeRange(ctx context.Context,
+	userID int64, start, end time.Time,
+) (events []*storage.Event, err error) {
+	ctx, span := startSpan(ctx, "GetEventsByTimeRange", attribute.Int64("user.id", userID))
+	defer func() { endList(span, len(events), err) }()
+
+	return s.Storage.GetEventsByTimeRange(ctx, userID, start, end)
+}
+
+func endList(span trace.Span, n int, err error) {
+	span.SetAttributes(attribute.Int("events.count", n))
+	tracing.End(span, err)
+}
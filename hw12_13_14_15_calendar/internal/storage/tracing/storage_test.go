@@ -0,0 +1,46 @@
+package tracingstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedStorage_Notifications(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx := context.Background()
+	store := New(memorystorage.New())
+
+	outbox, ok := storage.As[storage.Outbox](store)
+	require.True(t, ok)
+	require.Same(t, store, outbox)
+
+	_, err := store.EnqueueDueNotifications(ctx, time.Now())
+	require.NoError(t, err)
+	messages, err := store.FetchOutbox(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+	handled, err := store.NotificationHandled(ctx, 1, "key")
+	require.NoError(t, err)
+	require.False(t, handled)
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	require.Equal(t, []string{
+		"storage.EnqueueDueNotifications",
+		"storage.FetchOutbox",
+		"storage.NotificationHandled",
+	}, names)
+}
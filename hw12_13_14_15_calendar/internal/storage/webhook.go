@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WebhookDeliveryLogSize is the number of recent delivery attempts kept
+// per webhook.
+const WebhookDeliveryLogSize = 100
+
+// Webhook is a user's subscription to changes of their events.
+type Webhook struct {
+	ID     int64  `json:"id" db:"id"`
+	UserID int64  `json:"user_id" db:"user_id"`
+	URL    string `json:"url" db:"url"`
+	// Secret signs deliveries. It is only shown when the webhook is created.
+	Secret    string    `json:"-" db:"secret"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookDelivery is an attempt to deliver a message to a webhook.
+type WebhookDelivery struct {
+	ID        int64 `json:"id" db:"id"`
+	WebhookID int64 `json:"webhook_id" db:"webhook_id"`
+	// DeliveryID is shared by the retries of a message.
+	DeliveryID string    `json:"delivery_id" db:"delivery_id"`
+	EventType  string    `json:"event_type" db:"event_type"`
+	Attempt    int       `json:"attempt" db:"attempt"`
+	StatusCode int       `json:"status_code,omitempty" db:"status_code"`
+	Error      string    `json:"error,omitempty" db:"error"`
+	Success    bool      `json:"success" db:"success"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookStore is implemented by storages that keep webhook subscriptions.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	GetWebhook(ctx context.Context, id int64) (*Webhook, error)
+	ListWebhooks(ctx context.Context, userID int64) ([]*Webhook, error)
+	// DeleteWebhook removes the webhook with its delivery log.
+	DeleteWebhook(ctx context.Context, id int64) error
+	// AddWebhookDelivery logs a delivery attempt, dropping the oldest
+	// beyond WebhookDeliveryLogSize.
+	AddWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// ListWebhookDeliveries returns up to limit attempts, newest first.
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]*WebhookDelivery, error)
+}
+
+func WebhookNotFound(id int64) error {
+	return fmt.Errorf("webhook %d: %w", id, ErrNotFound)
+}
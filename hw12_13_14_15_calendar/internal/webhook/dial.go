@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// maxRedirects is the number of redirects a delivery follows.
+const maxRedirects = 3
+
+// ErrForbiddenAddress is returned for deliveries to an address webhooks
+// must not reach.
+var ErrForbiddenAddress = errors.New("address is not allowed for webhooks")
+
+// newClient returns the client of deliveries. Unless allowPrivate is set,
+// it refuses to connect to internal addresses. The check runs on the
+// resolved address of every connection, redirects included, so neither
+// DNS names nor redirects get around it.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = refuseInternal
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		// A proxy would be dialed in place of the target, so none is used.
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// refuseInternal is a net.Dialer Control function refusing loopback,
+// private, link-local, unspecified and multicast addresses. Link-local
+// covers the metadata services of cloud providers at 169.254.169.254.
+func refuseInternal(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isInternal(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+func isInternal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	//nolint:depguard
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	//nolint:depguard
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Payload is the body of a webhook delivery.
+type Payload struct {
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	CreatedAt time.Time     `json:"created_at"`
+	Event     storage.Event `json:"event"`
+	Digest    *app.Digest   `json:"digest,omitempty"`
+}
+
+type job struct {
+	webhook *storage.Webhook
+	payload Payload
+	body    []byte
+}
+
+// Dispatcher delivers event changes to the webhooks of their owners.
+// Failed deliveries are retried with exponential backoff and every
+// attempt is written to the delivery log, as are deliveries dropped
+// because the queue was full.
+type Dispatcher struct {
+	logger  *logger.Logger
+	store   storage.WebhookStore
+	client  *http.Client
+	changes chan app.Change
+	jobs    chan job
+
+	workers        int
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func NewDispatcher(logg *logger.Logger, store storage.WebhookStore, conf config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		logger:         logg,
+		store:          store,
+		client:         newClient(time.Duration(conf.Timeout)*time.Second, conf.AllowPrivateNetworks),
+		changes:        make(chan app.Change, conf.QueueSize),
+		jobs:           make(chan job, conf.QueueSize),
+		workers:        conf.Workers,
+		maxAttempts:    conf.MaxAttempts,
+		initialBackoff: time.Duration(conf.InitialBackoff) * time.Second,
+		maxBackoff:     time.Duration(conf.MaxBackoff) * time.Second,
+	}
+}
+
+// HandleChange queues change for delivery to the webhooks of the event
+// owner. It is an app.ChangeHandler and neither blocks nor reads the
+// storage; a change that finds the queue full is dropped.
+func (d *Dispatcher) HandleChange(_ context.Context, change app.Change) {
+	select {
+	case d.changes <- change:
+	default:
+		d.logger.Warn(fmt.Sprintf("webhook queue is full, dropped %s of event %d",
+			change.Type, change.Event.ID))
+	}
+}
+
+// fanOut queues a delivery of change to each webhook of the event owner.
+// A delivery that finds the queue full is dropped and logged as failed.
+func (d *Dispatcher) fanOut(ctx context.Context, change app.Change) {
+	webhooks, err := d.store.ListWebhooks(ctx, change.Event.UserID)
+	if err != nil {
+		d.logger.Error("failed to list webhooks: " + err.Error())
+		return
+	}
+
+	for _, webhook := range webhooks {
+		payload := Payload{
+			ID:        newDeliveryID(),
+			Type:      change.Type,
+			CreatedAt: change.At,
+			Event:     change.Event,
+			Digest:    change.Digest,
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			d.logger.Error("failed to encode webhook payload: " + err.Error())
+			return
+		}
+
+		select {
+		case d.jobs <- job{webhook: webhook, payload: payload, body: body}:
+		default:
+			d.logger.Warn(fmt.Sprintf("webhook queue is full, dropped %s delivery to webhook %d",
+				change.Type, webhook.ID))
+			d.log(ctx, &storage.WebhookDelivery{
+				WebhookID:  webhook.ID,
+				DeliveryID: payload.ID,
+				EventType:  payload.Type,
+				Error:      "dropped: delivery queue is full",
+			})
+		}
+	}
+}
+
+// Run delivers queued changes until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change := <-d.changes:
+				d.fanOut(ctx, change)
+			}
+		}
+	}()
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-d.jobs:
+					d.deliver(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, j job) {
+	backoff := d.initialBackoff
+	for attempt := 1; ; attempt++ {
+		statusCode, err := d.send(ctx, j)
+
+		delivery := &storage.WebhookDelivery{
+			WebhookID:  j.webhook.ID,
+			DeliveryID: j.payload.ID,
+			EventType:  j.payload.Type,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Success:    err == nil,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if !d.log(ctx, delivery) {
+			// The webhook was deleted meanwhile.
+			return
+		}
+
+		if err == nil {
+			return
+		}
+		if attempt >= d.maxAttempts {
+			d.logger.Warn(fmt.Sprintf("gave up delivering %s to webhook %d after %d attempts: %s",
+				j.payload.Type, j.webhook.ID, attempt, err.Error()))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > d.maxBackoff {
+			backoff = d.maxBackoff
+		}
+	}
+}
+
+// log writes delivery to the delivery log. It reports false if the
+// webhook no longer exists.
+func (d *Dispatcher) log(ctx context.Context, delivery *storage.WebhookDelivery) bool {
+	err := d.store.AddWebhookDelivery(ctx, delivery)
+	if storage.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		d.logger.Error("failed to log webhook delivery: " + err.Error())
+	}
+	return true
+}
+
+// send posts the delivery once and returns the response status code.
+func (d *Dispatcher) send(ctx context.Context, j job) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.webhook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, j.payload.Type)
+	req.Header.Set(DeliveryHeader, j.payload.ID)
+	// Every attempt is signed anew, so retries are not taken for replays.
+	timestamp := time.Now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(j.webhook.Secret, timestamp, j.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func newDeliveryID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/app"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/config"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/logger"
+	storage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"event.created"}`)
+	now := time.Now().Unix()
+	timestamp := strconv.FormatInt(now, 10)
+	signature := Sign("secret", now, body)
+
+	require.True(t, Verify("secret", body, timestamp, signature, DefaultTolerance))
+	require.False(t, Verify("other", body, timestamp, signature, DefaultTolerance))
+	require.False(t, Verify("secret", []byte(`{}`), timestamp, signature, DefaultTolerance))
+	require.False(t, Verify("secret", body, timestamp, signature[len(signaturePrefix):], DefaultTolerance))
+	require.False(t, Verify("secret", body, strconv.FormatInt(now+1, 10), signature, DefaultTolerance))
+
+	old := now - int64(DefaultTolerance/time.Second) - 1
+	require.False(t, Verify("secret", body, strconv.FormatInt(old, 10), Sign("secret", old, body), DefaultTolerance))
+}
+
+func TestDispatcher_RefusesInternalAddresses(t *testing.T) {
+	var calls atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		calls.Add(1)
+	}))
+	defer target.Close()
+
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	store, _ := storage.As[storage.WebhookStore](memorystorage.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &storage.Webhook{UserID: 1, URL: target.URL, Secret: "secret"}
+	require.NoError(t, store.CreateWebhook(ctx, hook))
+
+	conf := config.GetDefaultConfig().Components.Webhooks
+	conf.MaxAttempts = 1
+	d := NewDispatcher(logg, store, conf)
+	go d.Run(ctx)
+
+	d.HandleChange(ctx, app.Change{Type: app.ChangeCreated, Event: storage.Event{ID: 7, UserID: 1}})
+
+	require.Eventually(t, func() bool {
+		deliveries, err := store.ListWebhookDeliveries(ctx, hook.ID, 0)
+		return err == nil && len(deliveries) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	deliveries, err := store.ListWebhookDeliveries(ctx, hook.ID, 0)
+	require.NoError(t, err)
+	require.False(t, deliveries[0].Success)
+	require.Contains(t, deliveries[0].Error, ErrForbiddenAddress.Error())
+	require.Zero(t, calls.Load())
+
+	for _, addr := range []string{"127.0.0.1:80", "[::1]:443", "10.1.2.3:80", "192.168.0.1:80",
+		"169.254.169.254:80", "0.0.0.0:80", "[fe80::1]:80"} {
+		require.ErrorIs(t, refuseInternal("tcp", addr, nil), ErrForbiddenAddress, addr)
+	}
+	require.NoError(t, refuseInternal("tcp", "93.184.216.34:443", nil))
+}
+
+func TestDispatcher_LogsDroppedDeliveries(t *testing.T) {
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	store, _ := storage.As[storage.WebhookStore](memorystorage.New())
+	ctx := context.Background()
+
+	hook := &storage.Webhook{UserID: 1, URL: "https://example.com/hook", Secret: "secret"}
+	require.NoError(t, store.CreateWebhook(ctx, hook))
+
+	conf := config.GetDefaultConfig().Components.Webhooks
+	conf.QueueSize = 1
+	d := NewDispatcher(logg, store, conf)
+
+	// Without running workers the second delivery finds the queue full.
+	change := app.Change{Type: app.ChangeUpdated, Event: storage.Event{ID: 7, UserID: 1}}
+	d.HandleChange(ctx, change)
+	d.fanOut(ctx, <-d.changes)
+	d.HandleChange(ctx, change)
+	d.fanOut(ctx, <-d.changes)
+
+	deliveries, err := store.ListWebhookDeliveries(ctx, hook.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.False(t, deliveries[0].Success)
+	require.Zero(t, deliveries[0].Attempt)
+	require.Equal(t, app.ChangeUpdated, deliveries[0].EventType)
+	require.Contains(t, deliveries[0].Error, "queue is full")
+}
+
+func TestDispatcher_RetriesAndLogs(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan Payload, 1)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("secret", body, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), DefaultTolerance) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var payload Payload
+		json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer target.Close()
+
+	logg, err := logger.NewLogger(logger.StderrOutput, "error", logger.TextFormat)
+	require.NoError(t, err)
+
+	store, _ := storage.As[storage.WebhookStore](memorystorage.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hook := &storage.Webhook{UserID: 1, URL: target.URL, Secret: "secret"}
+	require.NoError(t, store.CreateWebhook(ctx, hook))
+	require.NoError(t, store.CreateWebhook(ctx, &storage.Webhook{UserID: 2, URL: target.URL, Secret: "secret"}))
+
+	conf := config.GetDefaultConfig().Components.Webhooks
+	conf.AllowPrivateNetworks = true
+	d := NewDispatcher(logg, store, conf)
+	d.initialBackoff = time.Millisecond
+	d.maxBackoff = time.Millisecond
+	go d.Run(ctx)
+
+	d.HandleChange(ctx, app.Change{
+		Type:  app.ChangeCreated,
+		Event: storage.Event{ID: 7, Title: "Meeting", UserID: 1},
+		At:    time.Now(),
+	})
+
+	select {
+	case payload := <-received:
+		require.Equal(t, app.ChangeCreated, payload.Type)
+		require.Equal(t, int64(7), payload.Event.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	require.Eventually(t, func() bool {
+		deliveries, err := store.ListWebhookDeliveries(ctx, hook.ID, 0)
+		return err == nil && len(deliveries) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	deliveries, err := store.ListWebhookDeliveries(ctx, hook.ID, 0)
+	require.NoError(t, err)
+	require.True(t, deliveries[0].Success)
+	require.Equal(t, 3, deliveries[0].Attempt)
+	require.False(t, deliveries[2].Success)
+	require.Equal(t, http.StatusServiceUnavailable, deliveries[2].StatusCode)
+	require.Equal(t, deliveries[0].DeliveryID, deliveries[2].DeliveryID)
+	require.Equal(t, int32(3), calls.Load())
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delivery headers.
+const (
+	SignatureHeader = "X-Calendar-Signature"
+	// TimestampHeader holds the Unix time in seconds the delivery was
+	// signed at.
+	TimestampHeader = "X-Calendar-Timestamp"
+	EventHeader     = "X-Calendar-Event"
+	DeliveryHeader  = "X-Calendar-Delivery"
+)
+
+// DefaultTolerance is the age after which receivers should reject a
+// signed delivery as a replay.
+const DefaultTolerance = 5 * time.Minute
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature header value of body sent at timestamp: the
+// hex encoded HMAC-SHA256 of "<timestamp>.<body>" keyed with secret,
+// prefixed with "sha256=".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body sent at
+// timestamp, the value of the timestamp header, and whether that time is
+// within tolerance of now.
+func Verify(secret string, body []byte, timestamp, signature string, tolerance time.Duration) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, sent, body)), []byte(signature))
+}
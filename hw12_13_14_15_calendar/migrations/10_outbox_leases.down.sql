@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notification_deliveries_key;
+ALTER TABLE outbox DROP COLUMN IF EXISTS locked_until;
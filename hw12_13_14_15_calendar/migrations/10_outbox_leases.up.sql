@@ -0,0 +1,6 @@
+-- Relayed messages stay in the outbox until their consumer acknowledges
+-- them; locked_until hides them from other relays meanwhile.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+
+-- The sender looks up earlier deliveries to drop redelivered notifications.
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_key ON notification_deliveries(user_id, key);
@@ -0,0 +1 @@
+ALTER TABLE notification_digests DROP COLUMN IF EXISTS event_ids;
@@ -0,0 +1,3 @@
+-- The events in the agenda of the last digest of each user. Reminders of
+-- other events are still sent to digest users.
+ALTER TABLE notification_digests ADD COLUMN IF NOT EXISTS event_ids BIGINT[] NOT NULL DEFAULT '{}';
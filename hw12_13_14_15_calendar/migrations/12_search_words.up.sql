@@ -0,0 +1,12 @@
+-- Punctuation is replaced with spaces before parsing, so emails, URLs and
+-- hyphenated words are indexed by their parts, as storage.Tokenize splits
+-- them, rather than as whole tokens.
+DROP INDEX IF EXISTS idx_events_search_vector;
+ALTER TABLE events DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE events ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', regexp_replace(coalesce(title, ''), '[[:punct:]]+', ' ', 'g')), 'A') ||
+        setweight(to_tsvector('simple', regexp_replace(coalesce(description, ''), '[[:punct:]]+', ' ', 'g')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_events_search_vector ON events USING GIN(search_vector);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS outbox;
+ALTER TABLE events DROP COLUMN IF EXISTS notified_at;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS tags (
+        id BIGSERIAL PRIMARY KEY,
+        user_id BIGINT NOT NULL,
+        name TEXT NOT NULL,
+        kind TEXT NOT NULL,
+        color TEXT NOT NULL,
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (user_id, kind, name)
+    );
+
+CREATE TABLE IF NOT EXISTS event_tags (
+        event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+        tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+        PRIMARY KEY (event_id, tag_id)
+    );
+CREATE INDEX IF NOT EXISTS idx_event_tags_tag_id ON event_tags(tag_id);
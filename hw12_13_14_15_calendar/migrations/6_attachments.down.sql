@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS attachments;
+ALTER TABLE events DROP COLUMN IF EXISTS conference;
@@ -0,0 +1,12 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS conference JSONB;
+
+CREATE TABLE IF NOT EXISTS attachments (
+        id BIGSERIAL PRIMARY KEY,
+        event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        mime_type TEXT NOT NULL,
+        size BIGINT NOT NULL,
+        storage_key TEXT NOT NULL UNIQUE,
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+CREATE INDEX IF NOT EXISTS idx_attachments_event_id ON attachments(event_id);
@@ -0,0 +1,2 @@
+ALTER TABLE events DROP COLUMN IF EXISTS calendar_id;
+DROP TABLE IF EXISTS calendars;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS calendars (
+        id BIGSERIAL PRIMARY KEY,
+        user_id BIGINT NOT NULL,
+        name TEXT NOT NULL,
+        color TEXT NOT NULL,
+        default_reminder INTEGER NOT NULL DEFAULT 0,
+        time_zone TEXT NOT NULL DEFAULT 'UTC',
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (user_id, name)
+    );
+
+-- Existing events stay outside of any calendar.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS calendar_id BIGINT REFERENCES calendars(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_events_calendar_id ON events(calendar_id);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+        user_id BIGINT PRIMARY KEY,
+        channel TEXT NOT NULL,
+        quiet_start TEXT NOT NULL DEFAULT '',
+        quiet_end TEXT NOT NULL DEFAULT '',
+        digest BOOLEAN NOT NULL DEFAULT FALSE,
+        digest_time TEXT NOT NULL,
+        time_zone TEXT NOT NULL DEFAULT 'UTC',
+        updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Deliveries outlive their events, so event_id is not a foreign key.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+        id BIGSERIAL PRIMARY KEY,
+        user_id BIGINT NOT NULL,
+        event_id BIGINT NOT NULL DEFAULT 0,
+        key TEXT NOT NULL,
+        channel TEXT NOT NULL,
+        status TEXT NOT NULL,
+        error TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_user_id ON notification_deliveries(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_event_id ON notification_deliveries(user_id, event_id, id);
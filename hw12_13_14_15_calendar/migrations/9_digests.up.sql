@@ -0,0 +1,6 @@
+-- The last day a digest was enqueued for each user.
+CREATE TABLE IF NOT EXISTS notification_digests (
+        user_id BIGINT PRIMARY KEY,
+        day DATE NOT NULL,
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
@@ -4,11 +4,13 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
 
 	//nolint:depguard
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
@@ -17,6 +19,7 @@ var migrationsFS embed.FS
 
 type Migrator struct {
 	migrate *migrate.Migrate
+	source  source.Driver
 }
 
 func New(dsn string) (*Migrator, error) {
@@ -29,7 +32,7 @@ func New(dsn string) (*Migrator, error) {
 		return nil, fmt.Errorf("failed to create migrator: %w", err)
 	}
 
-	return &Migrator{migrate: m}, nil
+	return &Migrator{migrate: m, source: d}, nil
 }
 
 func (m *Migrator) Up() error {
@@ -52,6 +55,69 @@ func (m *Migrator) Down() error {
 	return nil
 }
 
+// Steps applies n migrations up, or rolls back -n migrations if n is negative.
+func (m *Migrator) Steps(n int) error {
+	if err := m.migrate.Steps(n); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			return nil
+		}
+		return fmt.Errorf("failed to migrate %d steps: %w", n, err)
+	}
+	return nil
+}
+
+// Goto migrates up or down to the given version.
+func (m *Migrator) Goto(version uint) error {
+	if err := m.migrate.Migrate(version); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			return nil
+		}
+		return fmt.Errorf("failed to migrate to version %d: %w", version, err)
+	}
+	return nil
+}
+
+// Force sets the version without running migrations and clears the dirty
+// flag. Version -1 means no migration is applied.
+func (m *Migrator) Force(version int) error {
+	if err := m.migrate.Force(version); err != nil {
+		return fmt.Errorf("failed to force version %d: %w", version, err)
+	}
+	return nil
+}
+
+// Version returns the applied version, zero if none is applied.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Available lists the versions of the embedded migrations in order.
+func (m *Migrator) Available() ([]uint, error) {
+	version, err := m.source.First()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	versions := []uint{version}
+	for {
+		version, err = m.source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		versions = append(versions, version)
+	}
+}
+
 func (m *Migrator) Close() error {
 	srcErr, dbErr := m.migrate.Close()
 	if srcErr != nil {
@@ -0,0 +1,433 @@
+// Package calendarclient is a typed client of the calendar HTTP API
+// described by api/openapi.json.
+package calendarclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UserIDHeader carries the ID of the calling user.
+const UserIDHeader = "X-User-ID"
+
+// Error is a response with an error status.
+type Error struct {
+	StatusCode int
+	Message    string
+	// Index is the failed operation of an atomic batch, or -1.
+	Index int
+}
+
+func (e *Error) Error() string {
+	if e.Index >= 0 {
+		return fmt.Sprintf("calendar: %d: operation %d: %s", e.StatusCode, e.Index, e.Message)
+	}
+	return fmt.Sprintf("calendar: %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends requests with c instead of http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(client *Client) {
+		client.http = c
+	}
+}
+
+// Client calls the API as one user.
+type Client struct {
+	baseURL string
+	userID  int64
+	http    *http.Client
+}
+
+// New returns a client of the server at baseURL, e.g.
+// "http://localhost:8080", acting as userID.
+func New(baseURL string, userID int64, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		userID:  userID,
+		http:    http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ListEvents returns the events overlapping [from, to] having all of tagIDs.
+func (c *Client) ListEvents(ctx context.Context, from, to time.Time, tagIDs ...int64) ([]Event, error) {
+	return c.ListCalendarEvents(ctx, from, to, nil, tagIDs...)
+}
+
+// ListCalendarEvents returns the events overlapping [from, to] that are in
+// any of calendarIDs and have all of tagIDs. No calendarIDs means all
+// events.
+func (c *Client) ListCalendarEvents(ctx context.Context,
+	from, to time.Time,
+	calendarIDs []int64,
+	tagIDs ...int64,
+) ([]Event, error) {
+	query := url.Values{}
+	query.Set("from", from.Format(time.RFC3339))
+	query.Set("to", to.Format(time.RFC3339))
+	for _, id := range calendarIDs {
+		query.Add("calendar", strconv.FormatInt(id, 10))
+	}
+	for _, id := range tagIDs {
+		query.Add("tag", strconv.FormatInt(id, 10))
+	}
+
+	var events []Event
+	err := c.do(ctx, http.MethodGet, "/events?"+query.Encode(), nil, &events)
+	return events, err
+}
+
+func (c *Client) GetEvent(ctx context.Context, id int64) (*Event, error) {
+	var event Event
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/events/%d", id), nil, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Batch applies operations in bulk. A failed atomic batch returns an
+// *Error with the index of the failed operation.
+func (c *Client) Batch(ctx context.Context, req BatchRequest) (*BatchResponse, error) {
+	var resp BatchResponse
+	if err := c.do(ctx, http.MethodPost, "/events/batch", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateEvent creates an event and returns it with its ID.
+func (c *Client) CreateEvent(ctx context.Context, event Event) (*Event, error) {
+	return c.single(ctx, BatchOperation{Op: OpCreate, Event: &event})
+}
+
+// UpdateEvent replaces the event with event.ID.
+func (c *Client) UpdateEvent(ctx context.Context, event Event) (*Event, error) {
+	return c.single(ctx, BatchOperation{Op: OpUpdate, Event: &event})
+}
+
+func (c *Client) DeleteEvent(ctx context.Context, id int64) error {
+	_, err := c.single(ctx, BatchOperation{Op: OpDelete, ID: id})
+	return err
+}
+
+// single applies one operation as an atomic batch.
+func (c *Client) single(ctx context.Context, op BatchOperation) (*Event, error) {
+	resp, err := c.Batch(ctx, BatchRequest{Atomic: true, Operations: []BatchOperation{op}})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			e.Index = -1
+		}
+		return nil, err
+	}
+	if len(resp.Results) != 1 {
+		return nil, fmt.Errorf("calendar: expected 1 batch result, got %d", len(resp.Results))
+	}
+	return resp.Results[0].Event, nil
+}
+
+// SearchEvents returns events matching params.Query, best matches first.
+func (c *Client) SearchEvents(ctx context.Context, params SearchParams) ([]SearchResult, error) {
+	query := url.Values{}
+	query.Set("q", params.Query)
+	if !params.From.IsZero() {
+		query.Set("from", params.From.Format(time.RFC3339))
+	}
+	if !params.To.IsZero() {
+		query.Set("to", params.To.Format(time.RFC3339))
+	}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	var results []SearchResult
+	err := c.do(ctx, http.MethodGet, "/events/search?"+query.Encode(), nil, &results)
+	return results, err
+}
+
+func (c *Client) GetEventTags(ctx context.Context, eventID int64) ([]Tag, error) {
+	var tags []Tag
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/events/%d/tags", eventID), nil, &tags)
+	return tags, err
+}
+
+// SetEventTags replaces the tags of an event. At most one of them may be
+// a category.
+func (c *Client) SetEventTags(ctx context.Context, eventID int64, tagIDs []int64) ([]Tag, error) {
+	if tagIDs == nil {
+		tagIDs = []int64{}
+	}
+
+	var tags []Tag
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/events/%d/tags", eventID),
+		map[string][]int64{"tag_ids": tagIDs}, &tags)
+	return tags, err
+}
+
+// UploadAttachment attaches the bytes of r to an event as a file named
+// name. An empty mimeType is stored as application/octet-stream.
+func (c *Client) UploadAttachment(ctx context.Context,
+	eventID int64,
+	name, mimeType string,
+	r io.Reader,
+) (*Attachment, error) {
+	path := fmt.Sprintf("/events/%d/attachments?name=%s", eventID, url.QueryEscape(name))
+	req, err := c.newRequest(ctx, http.MethodPost, path, r)
+	if err != nil {
+		return nil, err
+	}
+	if mimeType != "" {
+		req.Header.Set("Content-Type", mimeType)
+	}
+
+	var attachment Attachment
+	if err := c.send(req, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (c *Client) ListAttachments(ctx context.Context, eventID int64) ([]Attachment, error) {
+	var attachments []Attachment
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/events/%d/attachments", eventID), nil, &attachments)
+	return attachments, err
+}
+
+// DownloadAttachment returns the bytes of an attachment. The caller
+// closes the reader.
+func (c *Client) DownloadAttachment(ctx context.Context, eventID, id int64) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, fmt.Sprintf("/events/%d/attachments/%d", eventID, id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) DeleteAttachment(ctx context.Context, eventID, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/events/%d/attachments/%d", eventID, id), nil, nil)
+}
+
+func (c *Client) CreateCalendar(ctx context.Context, req CalendarRequest) (*Calendar, error) {
+	var calendar Calendar
+	if err := c.do(ctx, http.MethodPost, "/calendars", req, &calendar); err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+func (c *Client) ListCalendars(ctx context.Context) ([]Calendar, error) {
+	var calendars []Calendar
+	err := c.do(ctx, http.MethodGet, "/calendars", nil, &calendars)
+	return calendars, err
+}
+
+// UpdateCalendar replaces the settings of a calendar.
+func (c *Client) UpdateCalendar(ctx context.Context, id int64, req CalendarRequest) (*Calendar, error) {
+	var calendar Calendar
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/calendars/%d", id), req, &calendar); err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// DeleteCalendar deletes a calendar with its events.
+func (c *Client) DeleteCalendar(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/calendars/%d", id), nil, nil)
+}
+
+func (c *Client) CreateTag(ctx context.Context, req TagRequest) (*Tag, error) {
+	var tag Tag
+	if err := c.do(ctx, http.MethodPost, "/tags", req, &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (c *Client) ListTags(ctx context.Context) ([]Tag, error) {
+	var tags []Tag
+	err := c.do(ctx, http.MethodGet, "/tags", nil, &tags)
+	return tags, err
+}
+
+// UpdateTag renames or recolours a tag. An empty field is left unchanged.
+func (c *Client) UpdateTag(ctx context.Context, id int64, req TagRequest) (*Tag, error) {
+	var tag Tag
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/tags/%d", id), req, &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (c *Client) DeleteTag(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/tags/%d", id), nil, nil)
+}
+
+func (c *Client) CreateWebhook(ctx context.Context, req WebhookRequest) (*CreatedWebhook, error) {
+	var webhook CreatedWebhook
+	if err := c.do(ctx, http.MethodPost, "/webhooks", req, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := c.do(ctx, http.MethodGet, "/webhooks", nil, &webhooks)
+	return webhooks, err
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/webhooks/%d", id), nil, nil)
+}
+
+// ListWebhookDeliveries returns delivery attempts of a webhook, newest
+// first. A zero limit is left to the server.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id int64, limit int) ([]WebhookDelivery, error) {
+	path := fmt.Sprintf("/webhooks/%d/deliveries", id)
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+
+	var deliveries []WebhookDelivery
+	err := c.do(ctx, http.MethodGet, path, nil, &deliveries)
+	return deliveries, err
+}
+
+func (c *Client) GetNotificationPreferences(ctx context.Context) (*NotificationPreferences, error) {
+	var prefs NotificationPreferences
+	if err := c.do(ctx, http.MethodGet, "/notifications/preferences", nil, &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (c *Client) SetNotificationPreferences(ctx context.Context,
+	req NotificationPreferencesRequest,
+) (*NotificationPreferences, error) {
+	var prefs NotificationPreferences
+	if err := c.do(ctx, http.MethodPut, "/notifications/preferences", req, &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// ListNotificationHistory returns notification deliveries of the user,
+// newest first. A non-zero eventID only lists those of the event and a
+// zero limit is left to the server.
+func (c *Client) ListNotificationHistory(ctx context.Context,
+	eventID int64,
+	limit int,
+) ([]NotificationDelivery, error) {
+	query := url.Values{}
+	if eventID != 0 {
+		query.Set("event", strconv.FormatInt(eventID, 10))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/notifications/history"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var deliveries []NotificationDelivery
+	err := c.do(ctx, http.MethodGet, path, nil, &deliveries)
+	return deliveries, err
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(UserIDHeader, strconv.FormatInt(c.userID, 10))
+	return req, nil
+}
+
+// do sends in as JSON and decodes the response into out. Either may be nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+// send sends req and decodes the response into out, which may be nil.
+func (c *Client) send(req *http.Request, out interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("calendar: decode response: %w", err)
+	}
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	body := struct {
+		Error string `json:"error"`
+		Index *int   `json:"index"`
+	}{}
+	e := &Error{StatusCode: resp.StatusCode, Index: -1}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+		e.Message = body.Error
+		if body.Index != nil {
+			e.Index = *body.Index
+		}
+	} else {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
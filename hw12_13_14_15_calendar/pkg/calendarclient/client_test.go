@@ -0,0 +1,236 @@
+package calendarclient_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/internal/integration"
+	"github.com/bimboterminator1/otus_hwgo/hw12_13_14_15_calendar/pkg/calendarclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	h := integration.Start(t)
+	client := calendarclient.New(h.URL, 1, calendarclient.WithHTTPClient(h.Client))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	t.Run("events", func(t *testing.T) {
+		stream, err := client.StreamEvents(ctx, "")
+		require.NoError(t, err)
+		defer stream.Close()
+
+		created, err := client.CreateEvent(ctx, calendarclient.Event{
+			Title:     "Planning",
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+		})
+		require.NoError(t, err)
+		require.NotZero(t, created.ID)
+		require.Equal(t, int64(1), created.UserID)
+
+		event, err := stream.Next()
+		require.NoError(t, err)
+		require.Equal(t, calendarclient.ChangeCreated, event.Type)
+		require.NotEmpty(t, event.ID)
+		require.Equal(t, created.ID, event.Change.Event.ID)
+
+		got, err := client.GetEvent(ctx, created.ID)
+		require.NoError(t, err)
+		require.Equal(t, created, got)
+
+		created.Title = "Sprint planning"
+		updated, err := client.UpdateEvent(ctx, *created)
+		require.NoError(t, err)
+		require.Equal(t, "Sprint planning", updated.Title)
+
+		events, err := client.ListEvents(ctx, start.Add(-time.Hour), start.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "Sprint planning", events[0].Title)
+
+		results, err := client.SearchEvents(ctx, calendarclient.SearchParams{Query: "sprint"})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Equal(t, "<mark>Sprint</mark> planning", results[0].TitleHighlight)
+
+		require.NoError(t, client.DeleteEvent(ctx, created.ID))
+		_, err = client.GetEvent(ctx, created.ID)
+		require.True(t, calendarclient.IsNotFound(err), err)
+		err = client.DeleteEvent(ctx, created.ID)
+		require.True(t, calendarclient.IsNotFound(err), err)
+
+		var e *calendarclient.Error
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, -1, e.Index)
+	})
+
+	t.Run("atomic batch error", func(t *testing.T) {
+		_, err := client.Batch(ctx, calendarclient.BatchRequest{
+			Atomic: true,
+			Operations: []calendarclient.BatchOperation{
+				{Op: calendarclient.OpCreate, Event: &calendarclient.Event{
+					Title: "ok", StartTime: start, EndTime: start,
+				}},
+				{Op: calendarclient.OpCreate, Event: &calendarclient.Event{StartTime: start, EndTime: start}},
+			},
+		})
+
+		var e *calendarclient.Error
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, http.StatusBadRequest, e.StatusCode)
+		require.Equal(t, 1, e.Index)
+	})
+
+	t.Run("calendars", func(t *testing.T) {
+		work, err := client.CreateCalendar(ctx, calendarclient.CalendarRequest{Name: "Work", DefaultReminder: 10})
+		require.NoError(t, err)
+		require.Equal(t, "UTC", work.TimeZone)
+
+		event, err := client.CreateEvent(ctx, calendarclient.Event{
+			Title: "Standup", StartTime: start, EndTime: start.Add(15 * time.Minute), CalendarID: work.ID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, start.Add(-10*time.Minute), event.NotifyAt.UTC())
+
+		events, err := client.ListCalendarEvents(ctx, start, start.Add(time.Hour), []int64{work.ID})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		work, err = client.UpdateCalendar(ctx, work.ID, calendarclient.CalendarRequest{Name: "Job", TimeZone: "Europe/Paris"})
+		require.NoError(t, err)
+		require.Equal(t, "Europe/Paris", work.TimeZone)
+
+		require.NoError(t, client.DeleteCalendar(ctx, work.ID))
+		_, err = client.GetEvent(ctx, event.ID)
+		require.True(t, calendarclient.IsNotFound(err), err)
+		calendars, err := client.ListCalendars(ctx)
+		require.NoError(t, err)
+		require.Empty(t, calendars)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		event, err := client.CreateEvent(ctx, calendarclient.Event{
+			Title: "Retro", StartTime: start, EndTime: start.Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		tag, err := client.CreateTag(ctx, calendarclient.TagRequest{Name: "Team", Color: "#1E90FF"})
+		require.NoError(t, err)
+		require.Equal(t, calendarclient.KindTag, tag.Kind)
+		require.Equal(t, "#1e90ff", tag.Color)
+
+		_, err = client.CreateTag(ctx, calendarclient.TagRequest{Name: "Team"})
+		var e *calendarclient.Error
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, http.StatusConflict, e.StatusCode)
+
+		tags, err := client.SetEventTags(ctx, event.ID, []int64{tag.ID})
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+
+		events, err := client.ListEvents(ctx, start, start.Add(time.Hour), tag.ID)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, event.ID, events[0].ID)
+
+		tags, err = client.SetEventTags(ctx, event.ID, nil)
+		require.NoError(t, err)
+		require.Empty(t, tags)
+
+		require.NoError(t, client.DeleteTag(ctx, tag.ID))
+		tags, err = client.ListTags(ctx)
+		require.NoError(t, err)
+		require.Empty(t, tags)
+	})
+
+	t.Run("attachments", func(t *testing.T) {
+		event, err := client.CreateEvent(ctx, calendarclient.Event{
+			Title: "Demo", StartTime: start, EndTime: start.Add(time.Hour),
+			Conference: &calendarclient.Conference{URL: "https://meet.example.com/demo", Provider: "meet"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "https://meet.example.com/demo", event.Conference.URL)
+
+		attachment, err := client.UploadAttachment(ctx, event.ID, "slides v2.pdf", "application/pdf",
+			strings.NewReader("%PDF-1.7"))
+		require.NoError(t, err)
+		require.Equal(t, "slides v2.pdf", attachment.Name)
+		require.Equal(t, int64(8), attachment.Size)
+
+		got, err := client.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, []calendarclient.Attachment{*attachment}, got.Attachments)
+
+		body, err := client.DownloadAttachment(ctx, event.ID, attachment.ID)
+		require.NoError(t, err)
+		data, err := io.ReadAll(body)
+		body.Close()
+		require.NoError(t, err)
+		require.Equal(t, "%PDF-1.7", string(data))
+
+		require.NoError(t, client.DeleteEvent(ctx, event.ID))
+		_, err = client.DownloadAttachment(ctx, event.ID, attachment.ID)
+		require.True(t, calendarclient.IsNotFound(err), err)
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		webhook, err := client.CreateWebhook(ctx, calendarclient.WebhookRequest{URL: "http://127.0.0.1:1/hook"})
+		require.NoError(t, err)
+		require.NotEmpty(t, webhook.Secret)
+
+		webhooks, err := client.ListWebhooks(ctx)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		require.Equal(t, webhook.Webhook, webhooks[0])
+
+		deliveries, err := client.ListWebhookDeliveries(ctx, webhook.ID, 10)
+		require.NoError(t, err)
+		require.Empty(t, deliveries)
+
+		require.NoError(t, client.DeleteWebhook(ctx, webhook.ID))
+		require.True(t, calendarclient.IsNotFound(client.DeleteWebhook(ctx, webhook.ID)))
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		prefs, err := client.GetNotificationPreferences(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "push", prefs.Channel)
+
+		prefs, err = client.SetNotificationPreferences(ctx, calendarclient.NotificationPreferencesRequest{
+			QuietStart: "22:00",
+			QuietEnd:   "07:00",
+			TimeZone:   "Europe/Berlin",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "08:00", prefs.DigestTime)
+
+		got, err := client.GetNotificationPreferences(ctx)
+		require.NoError(t, err)
+		require.Equal(t, prefs, got)
+
+		_, err = client.SetNotificationPreferences(ctx, calendarclient.NotificationPreferencesRequest{Channel: "sms"})
+		var e *calendarclient.Error
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, http.StatusBadRequest, e.StatusCode)
+
+		history, err := client.ListNotificationHistory(ctx, 1, 10)
+		require.NoError(t, err)
+		require.Empty(t, history)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := calendarclient.New(h.URL, 0, calendarclient.WithHTTPClient(h.Client)).ListTags(ctx)
+
+		var e *calendarclient.Error
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, http.StatusUnauthorized, e.StatusCode)
+	})
+}
@@ -0,0 +1,92 @@
+package calendarclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamReset is the type of a stream event telling that changes were
+// missed and events should be reloaded.
+const StreamReset = "reset"
+
+// StreamEvent is a change received from the stream. Change is nil for a
+// reset.
+type StreamEvent struct {
+	// ID resumes the stream after this event.
+	ID     string
+	Type   string
+	Change *Change
+}
+
+// Stream reads changes of the user's events as they happen.
+type Stream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// StreamEvents opens the change stream. A non-empty lastEventID resumes
+// after that event.
+func (c *Client) StreamEvents(ctx context.Context, lastEventID string) (*Stream, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/events/stream", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	return &Stream{body: resp.Body, scanner: bufio.NewScanner(resp.Body)}, nil
+}
+
+// Next blocks until the next event. It returns io.EOF when the server
+// closes the stream.
+func (s *Stream) Next() (StreamEvent, error) {
+	var event StreamEvent
+	var data string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if event.Type == "" {
+				continue
+			}
+			if event.Type != StreamReset {
+				event.Change = &Change{}
+				if err := json.Unmarshal([]byte(data), event.Change); err != nil {
+					return StreamEvent{}, err
+				}
+			}
+			return event, nil
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Type = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return StreamEvent{}, err
+	}
+	return StreamEvent{}, io.EOF
+}
+
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
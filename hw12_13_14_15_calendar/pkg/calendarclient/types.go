@@ -0,0 +1,231 @@
+package calendarclient
+
+import "time"
+
+// Change types of Change.Type and of stream events.
+const (
+	ChangeCreated  = "event.created"
+	ChangeUpdated  = "event.updated"
+	ChangeDeleted  = "event.deleted"
+	ChangeReminder = "event.reminder"
+	ChangeDigest   = "agenda.digest"
+)
+
+// Batch operation types.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Tag kinds.
+const (
+	KindTag      = "tag"
+	KindCategory = "category"
+)
+
+type Event struct {
+	ID          int64     `json:"id,omitempty"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	UserID      int64     `json:"user_id,omitempty"`
+	// NotifyAt is when to send a reminder. The zero time means none.
+	NotifyAt time.Time `json:"notify_at,omitempty"`
+	// CalendarID is the calendar of the event. Zero means none.
+	CalendarID int64       `json:"calendar_id,omitempty"`
+	Conference *Conference `json:"conference,omitempty"`
+	// Attachments are only returned by GetEvent.
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Calendar groups events of a user.
+type Calendar struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	Color  string `json:"color"`
+	// DefaultReminder is how many minutes before their start events
+	// created without a reminder are reminded of. Zero means none.
+	DefaultReminder int       `json:"default_reminder"`
+	TimeZone        string    `json:"time_zone"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// CalendarRequest creates a calendar or replaces its settings. Empty
+// fields take their defaults.
+type CalendarRequest struct {
+	Name            string `json:"name"`
+	Color           string `json:"color,omitempty"`
+	DefaultReminder int    `json:"default_reminder,omitempty"`
+	// TimeZone is an IANA time zone name. It defaults to UTC.
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// Conference is the online meeting of an event.
+type Conference struct {
+	// URL is an absolute http or https URL.
+	URL       string `json:"url"`
+	Provider  string `json:"provider,omitempty"`
+	MeetingID string `json:"meeting_id,omitempty"`
+	Passcode  string `json:"passcode,omitempty"`
+}
+
+// Attachment is a file attached to an event.
+type Attachment struct {
+	ID       int64  `json:"id"`
+	EventID  int64  `json:"event_id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	// Size is in bytes.
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SearchResult is an event matching a search. Matched words of the
+// highlights are wrapped in <mark> and </mark>.
+type SearchResult struct {
+	Event                *Event  `json:"event"`
+	Rank                 float64 `json:"rank"`
+	TitleHighlight       string  `json:"title_highlight"`
+	DescriptionHighlight string  `json:"description_highlight,omitempty"`
+}
+
+// SearchParams are the parameters of SearchEvents. Zero From, To and
+// Limit are left to the server.
+type SearchParams struct {
+	Query string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+type BatchOperation struct {
+	Op    string `json:"op"`
+	Event *Event `json:"event,omitempty"`
+	// ID is the event to delete.
+	ID int64 `json:"id,omitempty"`
+}
+
+type BatchRequest struct {
+	// Atomic applies all operations or none.
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+type BatchItemResult struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"`
+	// Status is the HTTP status of the operation.
+	Status int    `json:"status"`
+	Event  *Event `json:"event,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+	Failed  int               `json:"failed"`
+}
+
+// Change is a change of an event, or a reminder that it is about to start.
+// A digest change carries the daily agenda of Event.UserID, with the
+// subject as Event.Title and the start of the day as Event.StartTime.
+type Change struct {
+	Type   string    `json:"type"`
+	Event  Event     `json:"event"`
+	At     time.Time `json:"at"`
+	Digest *Digest   `json:"digest,omitempty"`
+}
+
+// Digest is a rendered daily agenda.
+type Digest struct {
+	Text string `json:"text"`
+	HTML string `json:"html"`
+}
+
+type Tag struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	// Color is a hex RGB colour such as "#1e90ff".
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagRequest creates or updates a tag. Kind is only used on create.
+type TagRequest struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+type Webhook struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatedWebhook is a new webhook with the secret that signs its
+// deliveries. The secret is not returned again.
+type CreatedWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookRequest creates a webhook. A secret is generated unless given.
+type WebhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID        int64 `json:"id"`
+	WebhookID int64 `json:"webhook_id"`
+	// DeliveryID is shared by the retries of a message.
+	DeliveryID string    `json:"delivery_id"`
+	EventType  string    `json:"event_type"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// NotificationPreferences are the notification settings of a user. Times
+// of day look like 22:00 in TimeZone.
+type NotificationPreferences struct {
+	UserID     int64     `json:"user_id"`
+	Channel    string    `json:"channel"`
+	QuietStart string    `json:"quiet_start,omitempty"`
+	QuietEnd   string    `json:"quiet_end,omitempty"`
+	Digest     bool      `json:"digest"`
+	DigestTime string    `json:"digest_time"`
+	TimeZone   string    `json:"time_zone"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type NotificationPreferencesRequest struct {
+	// Channel is push or log, push when empty.
+	Channel string `json:"channel,omitempty"`
+	// QuietStart and QuietEnd are set together.
+	QuietStart string `json:"quiet_start,omitempty"`
+	QuietEnd   string `json:"quiet_end,omitempty"`
+	Digest     bool   `json:"digest"`
+	DigestTime string `json:"digest_time,omitempty"`
+	TimeZone   string `json:"time_zone,omitempty"`
+}
+
+type NotificationDelivery struct {
+	ID      int64 `json:"id"`
+	UserID  int64 `json:"user_id"`
+	EventID int64 `json:"event_id,omitempty"`
+	// Key is shared by the attempts to deliver a notification.
+	Key       string    `json:"key"`
+	Channel   string    `json:"channel"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}